@@ -0,0 +1,68 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 21:58:30
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 21:58:30
+ * @FilePath: \go-core\pkg\casbin\abac.go
+ * @Description: ABAC 的主体属性，不同类型的 claims 统一为相同字段
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"strings"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+)
+
+// Attributes ABAC 策略中 r.sub 的属性，用户与机器身份字段一致，策略访问不存在的字段会导致校验出错
+//
+//	r.sub.TenantId == "M001" && r.sub.HasRole("manager")
+//	r.sub.UserType == "service" && r.sub.HasScope("orders:read")
+type Attributes struct {
+	UserId   string   // 用户id，机器身份为客户端id
+	UserType string   // 用户类型，机器身份为 service
+	TenantId string   // 租户，即商户号
+	Roles    []string // 角色，CustomClaims 的 AuthorityId 按逗号拆分
+	Scopes   []string // 授权范围，机器身份的 Scopes
+}
+
+// HasRole 是否拥有角色
+func (a Attributes) HasRole(role string) bool {
+	return containsValue(a.Roles, role)
+}
+
+// HasScope 是否拥有授权范围，* 表示全部
+func (a Attributes) HasScope(scope string) bool {
+	return containsValue(a.Scopes, jwt.ScopeAll) || containsValue(a.Scopes, scope)
+}
+
+// NewAttributes 从 claims 提取 ABAC 属性，claims 未提供的属性为零值
+func NewAttributes(claims jwt.Claims) Attributes {
+	attributes := Attributes{UserId: claims.GetUserId(), UserType: userType(claims)}
+	if tenant, ok := claims.(tenantClaims); ok {
+		attributes.TenantId = tenant.GetTenantId()
+	}
+	switch typed := claims.(type) {
+	case *jwt.CustomClaims:
+		for _, role := range strings.Split(typed.AuthorityId, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				attributes.Roles = append(attributes.Roles, role)
+			}
+		}
+	case *jwt.ServiceClaims:
+		attributes.Scopes = typed.Scopes
+	}
+	return attributes
+}
+
+// containsValue 切片中是否包含值
+func containsValue(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
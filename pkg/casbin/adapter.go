@@ -0,0 +1,443 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 00:25:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 00:25:36
+ * @FilePath: \go-core\pkg\casbin\adapter.go
+ * @Description: 文件、Redis、内存策略适配器，均支持按 DomainFilter 过滤加载
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	fileAdapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+)
+
+// 适配器相关错误
+var (
+	FilteredPolicySave  error = errors.New("按域过滤加载的策略不完整，不能整体保存")
+	PolicyFilterInvalid error = errors.New("不支持的策略过滤条件，需要使用 *DomainFilter")
+)
+
+// defaultRedisAdapterKey 默认保存策略的键，前缀为 global.GPerFix
+const defaultRedisAdapterKey = "casbin_rules"
+
+// modelRules 模型中的全部策略，每行以策略类型开头，如 p, admin, /users, GET
+func modelRules(m model.Model) [][]string {
+	var lines [][]string
+	for _, item := range policyTypes(m) {
+		for _, rule := range m[item.sec][item.ptype].Policy {
+			lines = append(lines, append([]string{item.ptype}, rule...))
+		}
+	}
+	return lines
+}
+
+// ruleLines 给策略加上策略类型
+func ruleLines(ptype string, rules [][]string) [][]string {
+	lines := make([][]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = append([]string{ptype}, rule...)
+	}
+	return lines
+}
+
+// loadRuleLines 将策略写入模型，filter 为空时全部写入
+func loadRuleLines(m model.Model, lines [][]string, filter *DomainFilter) error {
+	fields := domainFields(m)
+	for _, line := range lines {
+		if len(line) < 2 || (filter != nil && !filter.match(fields, line[0], line[1:])) {
+			continue
+		}
+		if err := persist.LoadPolicyArray(line, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// domainFilterOf 转换过滤条件，为空时表示全部加载
+func domainFilterOf(filter interface{}) (*DomainFilter, error) {
+	switch value := filter.(type) {
+	case nil:
+		return nil, nil
+	case *DomainFilter:
+		return value, nil
+	case DomainFilter:
+		return &value, nil
+	default:
+		return nil, fmt.Errorf("%w：%T", PolicyFilterInvalid, filter)
+	}
+}
+
+// ruleStore 策略的存储，每行以策略类型开头
+type ruleStore interface {
+	all() ([][]string, error)
+	add(lines [][]string) error
+	remove(lines [][]string) error
+	replace(lines [][]string) error
+}
+
+// storeAdapter 基于 ruleStore 实现 casbin 的适配器，支持批量、更新与过滤加载
+type storeAdapter struct {
+	store    ruleStore
+	mu       sync.RWMutex
+	filtered bool
+}
+
+// LoadPolicy 加载全部策略
+func (a *storeAdapter) LoadPolicy(m model.Model) error {
+	return a.LoadFilteredPolicy(m, nil)
+}
+
+// LoadFilteredPolicy 按 DomainFilter 加载策略
+func (a *storeAdapter) LoadFilteredPolicy(m model.Model, filter interface{}) error {
+	domainFilter, err := domainFilterOf(filter)
+	if err != nil {
+		return err
+	}
+	lines, err := a.store.all()
+	if err != nil {
+		return err
+	}
+	if err := loadRuleLines(m, lines, domainFilter); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.filtered = domainFilter != nil
+	a.mu.Unlock()
+	return nil
+}
+
+// IsFiltered 是否按过滤条件加载
+func (a *storeAdapter) IsFiltered() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.filtered
+}
+
+// SavePolicy 使用模型中的策略替换全部策略
+func (a *storeAdapter) SavePolicy(m model.Model) error {
+	if a.IsFiltered() {
+		return FilteredPolicySave
+	}
+	return a.store.replace(modelRules(m))
+}
+
+// AddPolicy 新增一条策略
+func (a *storeAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.store.add(ruleLines(ptype, [][]string{rule}))
+}
+
+// AddPolicies 批量新增策略
+func (a *storeAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return a.store.add(ruleLines(ptype, rules))
+}
+
+// RemovePolicy 删除一条策略
+func (a *storeAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.store.remove(ruleLines(ptype, [][]string{rule}))
+}
+
+// RemovePolicies 批量删除策略
+func (a *storeAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return a.store.remove(ruleLines(ptype, rules))
+}
+
+// RemoveFilteredPolicy 删除字段匹配的策略
+func (a *storeAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	_, err := a.removeFiltered(ptype, fieldIndex, fieldValues)
+	return err
+}
+
+// UpdatePolicy 更新一条策略
+func (a *storeAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return a.UpdatePolicies(sec, ptype, [][]string{oldRule}, [][]string{newRule})
+}
+
+// UpdatePolicies 批量更新策略
+func (a *storeAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	if err := a.store.remove(ruleLines(ptype, oldRules)); err != nil {
+		return err
+	}
+	return a.store.add(ruleLines(ptype, newRules))
+}
+
+// UpdateFilteredPolicies 用 newRules 替换字段匹配的策略，返回被替换的策略
+func (a *storeAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	oldRules, err := a.removeFiltered(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	return oldRules, a.store.add(ruleLines(ptype, newRules))
+}
+
+// removeFiltered 删除字段匹配的策略，字段值为空表示不限制
+func (a *storeAdapter) removeFiltered(ptype string, fieldIndex int, fieldValues []string) ([][]string, error) {
+	lines, err := a.store.all()
+	if err != nil {
+		return nil, err
+	}
+	var removed, rules [][]string
+	for _, line := range lines {
+		if line[0] != ptype || !matchFields(line[1:], fieldIndex, fieldValues) {
+			continue
+		}
+		removed = append(removed, line)
+		rules = append(rules, line[1:])
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return rules, a.store.remove(removed)
+}
+
+// matchFields 策略从 fieldIndex 开始的字段是否与 fieldValues 一致
+func matchFields(rule []string, fieldIndex int, fieldValues []string) bool {
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if fieldIndex+i >= len(rule) || rule[fieldIndex+i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryAdapter 内存策略适配器，进程重启后策略丢失，用于测试或由代码生成策略的场景
+type MemoryAdapter struct {
+	*storeAdapter
+}
+
+// NewMemoryAdapter 新建内存策略适配器，可传入初始策略
+func NewMemoryAdapter(rules ...PolicyRule) *MemoryAdapter {
+	store := &memoryStore{rules: map[string][]string{}}
+	for _, rule := range rules {
+		line := append([]string{rule.Ptype}, rule.Rule...)
+		store.rules[ruleKey(line)] = line
+	}
+	return &MemoryAdapter{storeAdapter: &storeAdapter{store: store}}
+}
+
+// memoryStore 内存中的策略，键为 ruleKey
+type memoryStore struct {
+	mu    sync.RWMutex
+	rules map[string][]string
+}
+
+func (s *memoryStore) all() ([][]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.rules))
+	for key := range s.rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([][]string, len(keys))
+	for i, key := range keys {
+		lines[i] = s.rules[key]
+	}
+	return lines, nil
+}
+
+func (s *memoryStore) add(lines [][]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, line := range lines {
+		s.rules[ruleKey(line)] = line
+	}
+	return nil
+}
+
+func (s *memoryStore) remove(lines [][]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, line := range lines {
+		delete(s.rules, ruleKey(line))
+	}
+	return nil
+}
+
+func (s *memoryStore) replace(lines [][]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = make(map[string][]string, len(lines))
+	for _, line := range lines {
+		s.rules[ruleKey(line)] = line
+	}
+	return nil
+}
+
+// RedisAdapter Redis 策略适配器，策略以 JSON 数组保存在同一个 Set 中，适合没有数据库的服务
+type RedisAdapter struct {
+	*storeAdapter
+}
+
+// NewRedisAdapter
+/**
+ *  @Description: 新建 Redis 策略适配器
+ *  @param client Redis 客户端，为空时使用 global.REDIS
+ *  @param key 保存策略的键，为空时使用 global.GPerFix + "casbin_rules"
+ *  @return adapter
+ *  @return err
+ */
+func NewRedisAdapter(client redis.UniversalClient, key string) (adapter *RedisAdapter, err error) {
+	if client == nil {
+		if global.REDIS == nil {
+			return nil, errors.New("未初始化 Redis 连接")
+		}
+		client = global.REDIS
+	}
+	if key == "" {
+		key = global.GPerFix + defaultRedisAdapterKey
+	}
+	return &RedisAdapter{storeAdapter: &storeAdapter{store: &redisStore{client: client, key: key}}}, nil
+}
+
+// redisStore Redis Set 中的策略
+type redisStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+func (s *redisStore) all() ([][]string, error) {
+	members, err := s.client.SMembers(context.Background(), s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(members)
+	lines := make([][]string, 0, len(members))
+	for _, member := range members {
+		var line []string
+		if err := json.Unmarshal([]byte(member), &line); err != nil {
+			return nil, fmt.Errorf("%w：%s", PolicyInvalid, err.Error())
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// members 编码为 Set 的成员
+func (s *redisStore) members(lines [][]string) ([]interface{}, error) {
+	members := make([]interface{}, len(lines))
+	for i, line := range lines {
+		member, err := json.Marshal(line)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = string(member)
+	}
+	return members, nil
+}
+
+func (s *redisStore) add(lines [][]string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	members, err := s.members(lines)
+	if err != nil {
+		return err
+	}
+	return s.client.SAdd(context.Background(), s.key, members...).Err()
+}
+
+func (s *redisStore) remove(lines [][]string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	members, err := s.members(lines)
+	if err != nil {
+		return err
+	}
+	return s.client.SRem(context.Background(), s.key, members...).Err()
+}
+
+func (s *redisStore) replace(lines [][]string) error {
+	members, err := s.members(lines)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Del(context.Background(), s.key)
+		if len(members) > 0 {
+			pipe.SAdd(context.Background(), s.key, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+// FileAdapter 策略文件适配器，格式与 casbin 策略文件一致，单条修改只作用于内存，需调用 SavePolicy 写回文件
+type FileAdapter struct {
+	*fileAdapter.Adapter
+	path     string
+	mu       sync.RWMutex
+	filtered bool
+}
+
+// NewFileAdapter 新建策略文件适配器
+func NewFileAdapter(path string) *FileAdapter {
+	return &FileAdapter{Adapter: fileAdapter.NewAdapter(path), path: path}
+}
+
+// LoadPolicy 加载全部策略
+func (a *FileAdapter) LoadPolicy(m model.Model) error {
+	return a.LoadFilteredPolicy(m, nil)
+}
+
+// LoadFilteredPolicy 按 DomainFilter 加载策略
+func (a *FileAdapter) LoadFilteredPolicy(m model.Model, filter interface{}) error {
+	domainFilter, err := domainFilterOf(filter)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rules, err := readPolicyRules(file, PolicyFormatCSV)
+	if err != nil {
+		return err
+	}
+	lines := make([][]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = append([]string{rule.Ptype}, rule.Rule...)
+	}
+	if err := loadRuleLines(m, lines, domainFilter); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.filtered = domainFilter != nil
+	a.mu.Unlock()
+	return nil
+}
+
+// IsFiltered 是否按过滤条件加载
+func (a *FileAdapter) IsFiltered() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.filtered
+}
+
+// SavePolicy 将模型中的策略写回文件
+func (a *FileAdapter) SavePolicy(m model.Model) error {
+	if a.IsFiltered() {
+		return FilteredPolicySave
+	}
+	return a.Adapter.SavePolicy(m)
+}
@@ -0,0 +1,214 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:48:05
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:48:05
+ * @FilePath: \go-core\pkg\casbin\cache.go
+ * @Description: 授权结果缓存，按请求参数缓存 Enforce 的结果，LRU 淘汰并带有效期，策略变更时整体失效
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamalyes/go-core/pkg/global"
+)
+
+// 授权结果缓存默认配置
+const (
+	DefaultDecisionCacheSize = 10000
+	DefaultDecisionCacheTTL  = time.Minute
+)
+
+// DecisionCacheConfig 授权结果缓存配置，零值使用默认值
+//
+//	casbin-decision-cache:
+//	  size: 10000
+//	  ttl: 1m
+type DecisionCacheConfig struct {
+	Size int           `mapstructure:"size" json:"size" yaml:"size"` // 最多缓存的结果数
+	TTL  time.Duration `mapstructure:"ttl"  json:"ttl"  yaml:"ttl"`  // 结果有效期，兜底未经 CasbinService 与 Watcher 的策略修改
+}
+
+// DecisionCacheStats 缓存统计
+type DecisionCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
+
+// decisionEntry 一条缓存的授权结果
+type decisionEntry struct {
+	key       string
+	allowed   bool
+	expiresAt time.Time
+}
+
+// DecisionCache 授权结果缓存，键为 (sub, dom, obj, act) 等请求参数
+type DecisionCache struct {
+	config     DecisionCacheConfig
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // 最近使用的在前
+	generation uint64     // 每次失效加一，失效前开始的 Enforce 结果不再写入
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+}
+
+// NewDecisionCache 新建授权结果缓存，未传配置时使用默认配置
+func NewDecisionCache(configs ...*DecisionCacheConfig) *DecisionCache {
+	config := DecisionCacheConfig{}
+	if len(configs) > 0 && configs[0] != nil {
+		config = *configs[0]
+	}
+	if config.Size <= 0 {
+		config.Size = DefaultDecisionCacheSize
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultDecisionCacheTTL
+	}
+	return &DecisionCache{config: config, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// decisionKey 请求参数拼接的缓存键
+func decisionKey(rvals []string) string {
+	return strings.Join(rvals, "\x00")
+}
+
+// Get 获取未过期的授权结果
+func (c *DecisionCache) Get(rvals ...string) (allowed bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[decisionKey(rvals)]
+	if !ok {
+		c.misses.Add(1)
+		return false, false
+	}
+	entry := element.Value.(*decisionEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, entry.key)
+		c.misses.Add(1)
+		return false, false
+	}
+	c.order.MoveToFront(element)
+	c.hits.Add(1)
+	return entry.allowed, true
+}
+
+// Generation 当前代数，配合 SetIfGeneration 使用
+func (c *DecisionCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Set 写入授权结果
+func (c *DecisionCache) Set(allowed bool, rvals ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(decisionKey(rvals), allowed)
+}
+
+// SetIfGeneration 代数未变化时写入，避免 Enforce 期间策略变更导致写入过期结果
+func (c *DecisionCache) SetIfGeneration(generation uint64, allowed bool, rvals ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.set(decisionKey(rvals), allowed)
+}
+
+// set 写入并淘汰最久未使用的结果，调用方持有锁
+func (c *DecisionCache) set(key string, allowed bool) {
+	expiresAt := time.Now().Add(c.config.TTL)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*decisionEntry)
+		entry.allowed, entry.expiresAt = allowed, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&decisionEntry{key: key, allowed: allowed, expiresAt: expiresAt})
+	for c.order.Len() > c.config.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*decisionEntry).key)
+	}
+}
+
+// Invalidate 清空全部结果
+func (c *DecisionCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
+
+// Stats 命中统计
+func (c *DecisionCache) Stats() DecisionCacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+	return DecisionCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Size: size}
+}
+
+// decisionCache 当前启用的缓存，为空表示不缓存
+var decisionCache atomic.Pointer[DecisionCache]
+
+// EnableDecisionCache 启用授权结果缓存，重复调用时替换原缓存
+func EnableDecisionCache(configs ...*DecisionCacheConfig) *DecisionCache {
+	cache := NewDecisionCache(configs...)
+	decisionCache.Store(cache)
+	return cache
+}
+
+// DisableDecisionCache 停用授权结果缓存
+func DisableDecisionCache() {
+	decisionCache.Store(nil)
+}
+
+// GetDecisionCache 获取当前启用的缓存，未启用时返回空
+func GetDecisionCache() *DecisionCache {
+	return decisionCache.Load()
+}
+
+// InvalidateDecisionCache 策略变更后清空缓存，CasbinService 的写方法与 Watcher 会自动调用，
+// 直接通过执行者修改策略且未挂载 Watcher 时需手动调用，否则旧结果在有效期内仍会生效
+func InvalidateDecisionCache() {
+	if cache := decisionCache.Load(); cache != nil {
+		cache.Invalidate()
+	}
+}
+
+// enforce 优先使用缓存的授权结果，未启用缓存时直接调用执行者
+func enforce(rvals ...string) (bool, error) {
+	if global.CSBEF == nil {
+		return false, EnforcerNotInitialized
+	}
+	args := make([]interface{}, len(rvals))
+	for i, rval := range rvals {
+		args[i] = rval
+	}
+	cache := decisionCache.Load()
+	if cache == nil {
+		return global.CSBEF.Enforce(args...)
+	}
+	if allowed, ok := cache.Get(rvals...); ok {
+		return allowed, nil
+	}
+	generation := cache.Generation()
+	allowed, err := global.CSBEF.Enforce(args...)
+	if err != nil {
+		return false, err
+	}
+	cache.SetIfGeneration(generation, allowed, rvals...)
+	return allowed, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:48:05
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:48:05
+ * @FilePath: \go-core\pkg\casbin\cache_test.go
+ * @Description: 授权结果缓存与授权解释测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecisionCache(t *testing.T) {
+	cache := NewDecisionCache(&DecisionCacheConfig{Size: 2, TTL: 50 * time.Millisecond})
+	cache.Set(true, "user-u1", "/a", "GET")
+	cache.Set(false, "user-u1", "/b", "GET")
+	allowed, ok := cache.Get("user-u1", "/a", "GET")
+	assert.True(t, ok)
+	assert.True(t, allowed)
+
+	// /b 最久未使用，被淘汰
+	cache.Set(true, "user-u1", "/c", "GET")
+	_, ok = cache.Get("user-u1", "/b", "GET")
+	assert.False(t, ok)
+	assert.Equal(t, DecisionCacheStats{Hits: 1, Misses: 1, Size: 2}, cache.Stats())
+
+	// 失效前开始的 Enforce 结果不再写入
+	generation := cache.Generation()
+	cache.Invalidate()
+	cache.SetIfGeneration(generation, true, "user-u1", "/a", "GET")
+	_, ok = cache.Get("user-u1", "/a", "GET")
+	assert.False(t, ok)
+
+	cache.Set(true, "user-u1", "/a", "GET")
+	time.Sleep(60 * time.Millisecond)
+	_, ok = cache.Get("user-u1", "/a", "GET")
+	assert.False(t, ok)
+}
+
+func TestPermissionVerifyWithCache(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	cache := EnableDecisionCache()
+	defer DisableDecisionCache()
+	svc := CasbinServiceApp
+
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Object: "/users", Action: "GET"}))
+	assert.False(t, svc.PermissionVerify("u1", "/users", "GET"))
+	assert.False(t, svc.PermissionVerify("u1", "/users", "GET"))
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+
+	// 通过 CasbinService 修改策略后缓存失效
+	require.NoError(t, svc.AddRolesForUser("u1", "", "admin"))
+	assert.True(t, svc.PermissionVerify("u1", "/users", "GET"))
+
+	// 直接修改执行者时需手动失效
+	_, err := global.CSBEF.DeleteRoleForUser(UserSubject("u1"), "admin")
+	require.NoError(t, err)
+	assert.True(t, svc.PermissionVerify("u1", "/users", "GET"))
+	InvalidateDecisionCache()
+	assert.False(t, svc.PermissionVerify("u1", "/users", "GET"))
+}
+
+func TestExplainPermission(t *testing.T) {
+	useEnforcer(t, PresetRBACWithDomains)
+	svc := CasbinServiceApp
+	require.NoError(t, svc.AddPermissions(RoleSubject("reader"), Permission{Domain: "*", Object: "/users", Action: "GET"}))
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Domain: "m1", Object: "/users", Action: "POST"}))
+	require.NoError(t, svc.AddRoleInheritance("admin", "reader", "m1"))
+	require.NoError(t, svc.AddRolesForUser("u1", "m1", "admin"))
+
+	decision, err := svc.ExplainPermission("u1", "m1", "/users", "GET")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []string{"reader", "*", "/users", "GET"}, decision.Policy)
+	assert.Equal(t, []string{"user-u1", "admin", "reader"}, decision.RoleChain)
+	assert.Equal(t, "命中策略：reader, *, /users, GET，角色链：user-u1 -> admin -> reader", decision.Reason)
+
+	decision, err = svc.ExplainPermission("u1", "m1", "/users", "DELETE")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Len(t, decision.Candidates, 2)
+	assert.Equal(t, "用户及其角色 user-u1, admin, reader 共有 2 条权限，没有与 DELETE /users 匹配的策略", decision.Reason)
+
+	decision, err = svc.ExplainPermission("u1", "m2", "/users", "GET")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "用户没有任何角色，也没有直接授予的权限", decision.Reason)
+
+	_, err = svc.ExplainPermission("u1", "", "/users", "GET")
+	assert.ErrorIs(t, err, DomainRequired)
+}
+
+func TestExplainPermissionDeny(t *testing.T) {
+	useEnforcer(t, PresetRBACDeny)
+	svc := CasbinServiceApp
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Object: "/users/*", Action: "*"}))
+	require.NoError(t, svc.AddPermissions(UserSubject("u1"), Permission{Object: "/users/:id/password", Action: "*", Effect: EffectDeny}))
+	require.NoError(t, svc.AddRolesForUser("u1", "", "admin"))
+
+	decision, err := svc.ExplainPermission("u1", "", "/users/1/password", "PUT")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, []string{"user-u1", "/users/:id/password", "*", "deny"}, decision.Policy)
+	assert.Equal(t, "被策略拒绝：user-u1, /users/:id/password, *, deny", decision.Reason)
+}
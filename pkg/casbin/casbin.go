@@ -14,57 +14,73 @@ import (
 	"log"
 
 	"github.com/casbin/casbin/v2"
-	"github.com/casbin/casbin/v2/model"
-	gormAdapter "github.com/casbin/gorm-adapter/v3"
-	"github.com/kamalyes/go-core/pkg/global"
 )
 
+// DefaultModel 默认 RBAC 模型，r = sub, obj, act
+const DefaultModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act`
+
+// DomainModel 带域的 RBAC 模型，r = sub, dom, obj, act，域默认为 JWT 中的商户号
+// 角色在域内分配，p.dom 为 * 的策略对全部域生效
+const DomainModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && (p.dom == r.dom || p.dom == "*") && r.obj == p.obj && r.act == p.act`
+
 // Casbin
 /**
- *  @Description: 初始化Casbin执行者（与gorm结合）
- *  @param DB Gorm连接池
- *  @param casbinConfPath casbin配置文件地址
+ *  @Description: 初始化Casbin执行者（与gorm结合），出错时退出进程，需要返回错误或其他适配器时使用 NewEnforcer
+ *  @param casbinModelPath casbin配置文件地址，为空时使用 DefaultModel
  *  @return Enforcer casbin执行者
- *  @return err 错误
  */
 func Casbin(casbinModelPath ...string) *casbin.SyncedEnforcer {
-	if global.DB == nil {
-		log.Fatalln("未初始化数据库连接")
-		return nil
+	return mustEnforcer(PresetRBAC, casbinModelPath...)
+}
+
+// CasbinWithDomains
+/**
+ *  @Description: 初始化带域的Casbin执行者（与gorm结合），配合 CasbinDomainHandler 使用
+ *  @param casbinModelPath casbin配置文件地址，为空时使用 DomainModel
+ *  @return Enforcer casbin执行者
+ */
+func CasbinWithDomains(casbinModelPath ...string) *casbin.SyncedEnforcer {
+	return mustEnforcer(PresetRBACWithDomains, casbinModelPath...)
+}
+
+// mustEnforcer 使用模型文件或内置模型创建 gorm 执行者，策略表为不加前缀的 PolicyTable，出错时退出进程
+func mustEnforcer(preset string, casbinModelPath ...string) *casbin.SyncedEnforcer {
+	config := &EnforcerConfig{Preset: preset, TableName: PolicyTable}
+	if len(casbinModelPath) > 0 {
+		config.ModelPath = casbinModelPath[0]
 	}
-	adapter, err := gormAdapter.NewAdapterByDB(global.DB)
+	syncedEnforcer, err := NewEnforcer(config)
 	if err != nil {
-		log.Fatalln("创建Casbin Gorm适配器错误：" + err.Error())
+		log.Fatalln(err.Error())
 		return nil
 	}
-	if len(casbinModelPath) > 0 {
-		syncedEnforcer, err := casbin.NewSyncedEnforcer(casbinModelPath[0], adapter)
-		if err != nil {
-			log.Fatalln("初始化Casbin执行者错误：" + err.Error())
-			return nil
-		}
-		return syncedEnforcer
-	} else {
-		m, _ := model.NewModelFromString(`
-			[request_definition]
-			r = sub, obj, act
-			
-			[policy_definition]
-			p = sub, obj, act
-			
-			[role_definition]
-			g = _, _
-			
-			[policy_effect]
-			e = some(where (p.eft == allow))
-			
-			[matchers]
-			m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act`)
-		syncedEnforcer, err := casbin.NewSyncedEnforcer(m, adapter)
-		if err != nil {
-			log.Fatalln("初始化Casbin执行者错误：" + err.Error())
-			return nil
-		}
-		return syncedEnforcer
-	}
+	return syncedEnforcer
 }
@@ -0,0 +1,37 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:59:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:59:10
+ * @FilePath: \go-core\pkg\casbin\echox.go
+ * @Description: Echo Casbin 权限认证中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// CasbinEchoHandler Echo Casbin权限认证，需放在 jwt.EchoJWTHandler 之后，校验函数默认为 VerifyPermission
+//
+//	e.Use(jwt.EchoJWTHandler(), casbin.CasbinEchoHandler(casbin.VerifyInDomain()))
+func CasbinEchoHandler(verifiers ...Verifier) echo.MiddlewareFunc {
+	verify := verifierOf(verifiers)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, err := jwt.GetEchoTypedClaims[jwt.Claims](c)
+			if err != nil {
+				claims, _ = jwt.ClaimsFrom(c.Request().Context())
+			}
+			req := &Request{Method: c.Request().Method, Path: c.Request().URL.Path, Claims: claims, Header: c.Request().Header.Get}
+			if err := Authorize(req, verify); err != nil {
+				return response.GenEchoResponse(c, response.NewErrorResponseOption(err))
+			}
+			return next(c)
+		}
+	}
+}
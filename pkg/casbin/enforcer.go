@@ -0,0 +1,293 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 00:25:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 00:25:36
+ * @FilePath: \go-core\pkg\casbin\enforcer.go
+ * @Description: 返回错误的执行者初始化，支持 gorm、文件、Redis、内存适配器以及按域过滤加载策略
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	gormAdapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 适配器类型
+const (
+	AdapterGorm   = "gorm"   // global.DB 或 EnforcerConfig.DB
+	AdapterFile   = "file"   // 策略文件，格式与 casbin 策略文件一致
+	AdapterRedis  = "redis"  // global.REDIS 或 EnforcerConfig.Redis
+	AdapterMemory = "memory" // 内存，进程重启后策略丢失
+)
+
+// PolicyTable gorm 的策略表名，NewEnforcer 默认加 global.GPerFix 前缀
+// Casbin、CasbinWithDomains、CasbinWithPreset 不加前缀，沿用已有数据
+const PolicyTable = "casbin_rule"
+
+// AdapterUnsupported 不支持的适配器类型
+var AdapterUnsupported error = errors.New("不支持的 casbin 适配器")
+
+// EnforcerConfig 执行者配置，零值使用 PresetRBAC 与 global.DB 上的 gorm 适配器
+//
+//	casbin:
+//	  preset: rbac-with-domains
+//	  adapter: gorm
+//	  table-name: goc_casbin_rule
+//	  domains: [M001, M002]
+type EnforcerConfig struct {
+	ModelPath  string   `mapstructure:"model-path"  json:"modelPath"  yaml:"model-path"`  // 模型文件，设置后忽略 Preset
+	Preset     string   `mapstructure:"preset"      json:"preset"     yaml:"preset"`      // 内置模型预设，默认 PresetRBAC
+	Adapter    string   `mapstructure:"adapter"     json:"adapter"    yaml:"adapter"`     // gorm、file、redis、memory，默认 gorm
+	TableName  string   `mapstructure:"table-name"  json:"tableName"  yaml:"table-name"`  // gorm 的策略表名，默认 global.GPerFix + PolicyTable
+	PolicyPath string   `mapstructure:"policy-path" json:"policyPath" yaml:"policy-path"` // file 的策略文件
+	RedisKey   string   `mapstructure:"redis-key"   json:"redisKey"   yaml:"redis-key"`   // redis 保存策略的键，默认 global.GPerFix + "casbin_rules"
+	Domains    []string `mapstructure:"domains"     json:"domains"    yaml:"domains"`     // 只加载这些域的策略，为空时全部加载
+
+	DB            *gorm.DB              `mapstructure:"-" json:"-" yaml:"-"` // gorm 的连接，默认 global.DB
+	Redis         redis.UniversalClient `mapstructure:"-" json:"-" yaml:"-"` // redis 的客户端，默认 global.REDIS
+	PolicyAdapter persist.Adapter       `mapstructure:"-" json:"-" yaml:"-"` // 自定义适配器，设置后忽略 Adapter
+}
+
+// NewEnforcer
+/**
+ *  @Description: 初始化Casbin执行者，与 Casbin 不同，任何错误都会返回而不是退出进程
+ *  @param configs 执行者配置，未传时使用零值
+ *  @return enforcer casbin执行者
+ *  @return err
+ */
+func NewEnforcer(configs ...*EnforcerConfig) (enforcer *casbin.SyncedEnforcer, err error) {
+	config := EnforcerConfig{}
+	if len(configs) > 0 && configs[0] != nil {
+		config = *configs[0]
+	}
+	if config.Preset == "" {
+		config.Preset = PresetRBAC
+	}
+	if config.TableName == "" {
+		config.TableName = global.GPerFix + PolicyTable
+	}
+
+	var m model.Model
+	if config.ModelPath != "" {
+		m, err = model.NewModelFromFile(config.ModelPath)
+	} else {
+		m, err = NewPresetModel(config.Preset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析Casbin模型错误：%w", err)
+	}
+	adapter, err := config.newAdapter()
+	if err != nil {
+		return nil, err
+	}
+	// 先不带适配器创建，避免按域过滤时加载全部策略
+	enforcer, err = casbin.NewSyncedEnforcer(m)
+	if err != nil {
+		return nil, fmt.Errorf("初始化Casbin执行者错误：%w", err)
+	}
+	enforcer.SetAdapter(adapter)
+	if len(config.Domains) > 0 {
+		err = LoadDomainPolicies(enforcer, config.Domains...)
+	} else {
+		err = enforcer.LoadPolicy()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("加载Casbin策略错误：%w", err)
+	}
+	return enforcer, nil
+}
+
+// newAdapter 按配置创建适配器
+func (c *EnforcerConfig) newAdapter() (persist.Adapter, error) {
+	if c.PolicyAdapter != nil {
+		return c.PolicyAdapter, nil
+	}
+	switch strings.ToLower(c.Adapter) {
+	case "", AdapterGorm:
+		db := c.DB
+		if db == nil {
+			db = global.DB
+		}
+		if db == nil {
+			return nil, errors.New("未初始化数据库连接")
+		}
+		adapter, err := gormAdapter.NewAdapterByDBUseTableName(db, "", c.TableName)
+		if err != nil {
+			return nil, fmt.Errorf("创建Casbin Gorm适配器错误：%w", err)
+		}
+		return adapter, nil
+	case AdapterFile:
+		if c.PolicyPath == "" {
+			return nil, errors.New("未指定Casbin策略文件")
+		}
+		return NewFileAdapter(c.PolicyPath), nil
+	case AdapterRedis:
+		return NewRedisAdapter(c.Redis, c.RedisKey)
+	case AdapterMemory:
+		return NewMemoryAdapter(), nil
+	default:
+		return nil, fmt.Errorf("%w：%s", AdapterUnsupported, c.Adapter)
+	}
+}
+
+// DomainFilter 按域过滤加载策略，域很多的多租户部署中每个实例只加载所需商户的策略
+// p 中域为 * 的策略总会加载，模型不带域时不过滤
+type DomainFilter struct {
+	Domains []string
+}
+
+// domainFields 各策略类型中域字段的位置，p 取 dom 字段，g 按域分配角色时取第三个字段
+func domainFields(m model.Model) map[string]int {
+	fields := map[string]int{}
+	if p, ok := m["p"]["p"]; ok {
+		for i, token := range p.Tokens {
+			if token == "p_dom" {
+				fields["p"] = i
+			}
+		}
+	}
+	if g, ok := m["g"]["g"]; ok && len(g.Tokens) >= 3 {
+		fields["g"] = 2
+	}
+	return fields
+}
+
+// match 策略是否属于过滤的域
+func (f *DomainFilter) match(fields map[string]int, ptype string, rule []string) bool {
+	index, ok := fields[ptype]
+	if !ok {
+		return true
+	}
+	if index >= len(rule) {
+		return false
+	}
+	return rule[index] == "*" || containsString(f.Domains, rule[index])
+}
+
+// gormFilters 转换为 gorm 适配器的过滤条件，不带域的策略类型不过滤
+func (f *DomainFilter) gormFilters(m model.Model) []gormAdapter.Filter {
+	fields := domainFields(m)
+	values := append([]string{"*"}, f.Domains...)
+	var filters []gormAdapter.Filter
+	var others []string
+	for _, item := range policyTypes(m) {
+		index, ok := fields[item.ptype]
+		if !ok {
+			others = append(others, item.ptype)
+			continue
+		}
+		filter := gormAdapter.Filter{Ptype: []string{item.ptype}}
+		switch index {
+		case 0:
+			filter.V0 = values
+		case 1:
+			filter.V1 = values
+		case 2:
+			filter.V2 = values
+		case 3:
+			filter.V3 = values
+		case 4:
+			filter.V4 = values
+		default:
+			filter.V5 = values
+		}
+		filters = append(filters, filter)
+	}
+	if len(others) > 0 {
+		filters = append(filters, gormAdapter.Filter{Ptype: others})
+	}
+	return filters
+}
+
+// containsString 切片中是否包含 value
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// enforcerFilters 各执行者当前加载的域，只记录按域加载的执行者，丢弃时由 CloseEnforcer 移除
+var enforcerFilters = struct {
+	sync.Mutex
+	filters map[*casbin.SyncedEnforcer]*DomainFilter
+}{filters: map[*casbin.SyncedEnforcer]*DomainFilter{}}
+
+// GetDomainFilter 获取执行者当前加载的域，全部加载时返回空
+func GetDomainFilter(e *casbin.SyncedEnforcer) *DomainFilter {
+	enforcerFilters.Lock()
+	defer enforcerFilters.Unlock()
+	return enforcerFilters.filters[e]
+}
+
+// CloseEnforcer 停止执行者的自动加载并移除按域加载的记录，替换或丢弃按域加载的执行者时调用，避免记录一直保留
+func CloseEnforcer(e *casbin.SyncedEnforcer) {
+	if e == nil {
+		return
+	}
+	if e.IsAutoLoadingRunning() {
+		e.StopAutoLoadPolicy()
+	}
+	enforcerFilters.Lock()
+	defer enforcerFilters.Unlock()
+	delete(enforcerFilters.filters, e)
+}
+
+// LoadDomainPolicies
+/**
+ *  @Description: 按域加载策略，已按域加载时追加这些域，如新商户接入；之后 ReloadPolicy 与 Watcher 的全量加载同样只加载这些域
+ *  @param e casbin执行者
+ *  @param domains 域
+ *  @return err
+ */
+func LoadDomainPolicies(e *casbin.SyncedEnforcer, domains ...string) (err error) {
+	defer InvalidateDecisionCache()
+	enforcerFilters.Lock()
+	defer enforcerFilters.Unlock()
+	filter := &DomainFilter{}
+	if previous, ok := enforcerFilters.filters[e]; ok {
+		filter.Domains = append(filter.Domains, previous.Domains...)
+	}
+	for _, domain := range domains {
+		if !containsString(filter.Domains, domain) {
+			filter.Domains = append(filter.Domains, domain)
+		}
+	}
+	if err := loadFilteredPolicy(e, filter); err != nil {
+		return err
+	}
+	enforcerFilters.filters[e] = filter
+	return nil
+}
+
+// ReloadPolicy 重新加载策略，按域加载的执行者只加载原有的域
+func ReloadPolicy(e *casbin.SyncedEnforcer) (err error) {
+	defer InvalidateDecisionCache()
+	if filter := GetDomainFilter(e); filter != nil {
+		return loadFilteredPolicy(e, filter)
+	}
+	return e.LoadPolicy()
+}
+
+// loadFilteredPolicy 按域加载策略，gorm 适配器需要转换为其 Filter
+func loadFilteredPolicy(e *casbin.SyncedEnforcer, filter *DomainFilter) error {
+	if _, ok := e.GetAdapter().(*gormAdapter.Adapter); ok {
+		return e.LoadFilteredPolicy(filter.gormFilters(e.GetModel()))
+	}
+	return e.LoadFilteredPolicy(filter)
+}
@@ -0,0 +1,169 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 00:25:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 00:25:36
+ * @FilePath: \go-core\pkg\casbin\enforcer_test.go
+ * @Description: 执行者初始化、适配器与按域加载测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// domainPolicies 多个商户的策略
+const domainPolicies = `p, admin, M001, /orders, GET
+p, admin, M002, /orders, GET
+p, auditor, *, /audit, GET
+g, user-u1, admin, M001
+g, user-u2, admin, M002
+g, user-u1, auditor, M001
+`
+
+// policyCount 执行者中的 p 与 g 策略数
+func policyCount(t *testing.T, e *casbin.SyncedEnforcer) (int, int) {
+	policies, err := e.GetPolicy()
+	require.NoError(t, err)
+	groupings, err := e.GetGroupingPolicy()
+	require.NoError(t, err)
+	return len(policies), len(groupings)
+}
+
+func TestNewEnforcerErrors(t *testing.T) {
+	_, err := NewEnforcer(&EnforcerConfig{Adapter: "mongo"})
+	assert.ErrorIs(t, err, AdapterUnsupported)
+	_, err = NewEnforcer(&EnforcerConfig{Adapter: AdapterFile})
+	assert.Error(t, err)
+	_, err = NewEnforcer(&EnforcerConfig{Adapter: AdapterMemory, Preset: "unknown"})
+	assert.Error(t, err)
+	_, err = NewEnforcer(&EnforcerConfig{Adapter: AdapterFile, PolicyPath: filepath.Join(t.TempDir(), "missing.csv")})
+	assert.Error(t, err)
+}
+
+func TestMemoryAdapter(t *testing.T) {
+	adapter := NewMemoryAdapter(PolicyRule{Ptype: "p", Rule: []string{"admin", "/users", "GET"}})
+	e, err := NewEnforcer(&EnforcerConfig{PolicyAdapter: adapter})
+	require.NoError(t, err)
+	_, err = e.AddGroupingPolicy("user-u1", "admin")
+	require.NoError(t, err)
+	_, err = e.AddPolicies([][]string{{"admin", "/users", "POST"}, {"reader", "/users", "GET"}})
+	require.NoError(t, err)
+	_, err = e.UpdatePolicy([]string{"admin", "/users", "POST"}, []string{"admin", "/users", "PUT"})
+	require.NoError(t, err)
+	_, err = e.RemoveFilteredPolicy(0, "reader")
+	require.NoError(t, err)
+
+	reloaded, err := NewEnforcer(&EnforcerConfig{PolicyAdapter: adapter})
+	require.NoError(t, err)
+	policies, err := reloaded.GetPolicy()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]string{{"admin", "/users", "GET"}, {"admin", "/users", "PUT"}}, policies)
+	allowed, err := reloaded.Enforce("user-u1", "/users", "PUT")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, reloaded.SavePolicy())
+	lines, err := adapter.store.all()
+	require.NoError(t, err)
+	assert.Len(t, lines, 3)
+}
+
+func TestFileAdapterFilteredByDomain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	require.NoError(t, os.WriteFile(path, []byte(domainPolicies), 0o644))
+
+	e, err := NewEnforcer(&EnforcerConfig{Preset: PresetRBACWithDomains, Adapter: AdapterFile, PolicyPath: path, Domains: []string{"M001"}})
+	require.NoError(t, err)
+	p, g := policyCount(t, e)
+	assert.Equal(t, 2, p)
+	assert.Equal(t, 2, g)
+	allowed, err := e.Enforce("user-u1", "M001", "/audit", "GET")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Error(t, e.SavePolicy())
+
+	require.NoError(t, LoadDomainPolicies(e, "M002"))
+	assert.Equal(t, []string{"M001", "M002"}, GetDomainFilter(e).Domains)
+	require.NoError(t, ReloadPolicy(e))
+	allowed, err = e.Enforce("user-u2", "M002", "/orders", "GET")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// 丢弃执行者时移除记录
+	CloseEnforcer(e)
+	assert.Nil(t, GetDomainFilter(e))
+	enforcerFilters.Lock()
+	assert.NotContains(t, enforcerFilters.filters, e)
+	enforcerFilters.Unlock()
+
+	full, err := NewEnforcer(&EnforcerConfig{Preset: PresetRBACWithDomains, Adapter: AdapterFile, PolicyPath: path})
+	require.NoError(t, err)
+	assert.Nil(t, GetDomainFilter(full))
+	_, err = full.AddPolicy("admin", "M003", "/orders", "GET")
+	require.NoError(t, err)
+	require.NoError(t, full.SavePolicy())
+	p, _ = policyCount(t, full)
+	require.NoError(t, full.LoadPolicy())
+	reloaded, _ := policyCount(t, full)
+	assert.Equal(t, 4, p)
+	assert.Equal(t, p, reloaded)
+}
+
+func TestGormAdapterFilteredByDomain(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:casbin_enforcer?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	writer, err := NewEnforcer(&EnforcerConfig{Preset: PresetRBACWithDomains, DB: db})
+	require.NoError(t, err)
+	assert.True(t, db.Migrator().HasTable(global.GPerFix+PolicyTable))
+	assert.False(t, db.Migrator().HasTable(PolicyTable))
+	_, err = writer.AddPolicies([][]string{
+		{"admin", "M001", "/orders", "GET"},
+		{"admin", "M002", "/orders", "GET"},
+		{"auditor", "*", "/audit", "GET"},
+	})
+	require.NoError(t, err)
+	_, err = writer.AddGroupingPolicies([][]string{{"user-u1", "admin", "M001"}, {"user-u2", "admin", "M002"}})
+	require.NoError(t, err)
+
+	e, err := NewEnforcer(&EnforcerConfig{Preset: PresetRBACWithDomains, DB: db, Domains: []string{"M002"}})
+	require.NoError(t, err)
+	p, g := policyCount(t, e)
+	assert.Equal(t, 2, p)
+	assert.Equal(t, 1, g)
+	allowed, err := e.Enforce("user-u2", "M002", "/orders", "GET")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = e.Enforce("user-u1", "M001", "/orders", "GET")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestLegacyPolicyTable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:casbin_legacy?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	previous := global.DB
+	global.DB = db
+	t.Cleanup(func() {
+		global.DB = previous
+		sqlDB.Close()
+	})
+
+	// 旧方法沿用不加前缀的表
+	Casbin()
+	assert.True(t, db.Migrator().HasTable(PolicyTable))
+	assert.False(t, db.Migrator().HasTable(global.GPerFix+PolicyTable))
+}
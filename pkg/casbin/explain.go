@@ -0,0 +1,152 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:48:05
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:48:05
+ * @FilePath: \go-core\pkg\casbin\explain.go
+ * @Description: 授权判断的解释，给出命中的策略与角色链，或未命中时用户及其角色拥有的策略，便于管理员排查无权限的请求
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// Decision 授权判断的解释
+type Decision struct {
+	Allowed    bool       `json:"allowed"`              // 是否通过
+	Request    []string   `json:"request"`              // 请求参数，如 user-1001, /users, GET
+	Policy     []string   `json:"policy,omitempty"`     // 决定结果的策略
+	RoleChain  []string   `json:"roleChain,omitempty"`  // 请求主体到策略主体的角色链，如 user-1001、admin、reader
+	Candidates [][]string `json:"candidates,omitempty"` // 未命中时，用户及其角色拥有的策略
+	Reason     string     `json:"reason"`               // 说明
+}
+
+// ExplainPermission
+/**
+ *  @Description: 解释用户的一次权限认证，不使用授权结果缓存，供管理员排查
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @param permission url
+ *  @param method 方法
+ *  @return decision 授权判断的解释
+ *  @return err
+ */
+func (s CasbinService) ExplainPermission(userId, domain, permission, method string) (decision *Decision, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkDomain(domain); err != nil {
+		return nil, err
+	}
+	subject := UserSubject(userId)
+	rvals := []string{subject, permission, method}
+	if layout.domain {
+		rvals = []string{subject, domain, permission, method}
+	}
+	args := make([]interface{}, len(rvals))
+	for i, rval := range rvals {
+		args[i] = rval
+	}
+	allowed, explain, err := e.EnforceEx(args...)
+	if err != nil {
+		return nil, err
+	}
+	decision = &Decision{Allowed: allowed, Request: rvals}
+
+	if len(explain) > 0 {
+		decision.Policy = explain
+		policySubject, _ := layout.permission(explain)
+		decision.RoleChain = roleChain(e, layout, subject, policySubject, domain)
+		verb := "命中策略"
+		if !allowed {
+			verb = "被策略拒绝"
+		}
+		decision.Reason = fmt.Sprintf("%s：%s", verb, strings.Join(explain, ", "))
+		if len(decision.RoleChain) > 1 {
+			decision.Reason += "，角色链：" + strings.Join(decision.RoleChain, " -> ")
+		}
+		return decision, nil
+	}
+
+	subjects := []string{subject}
+	if layout.grouping > 0 {
+		roles, err := e.GetImplicitRolesForUser(subject, layout.domainArgs(domain)...)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, roles...)
+	}
+	for _, item := range subjects {
+		policies, err := e.GetFilteredNamedPolicy("p", 0, layout.policyFilter(item, "")...)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			if _, candidate := layout.permission(policy); layout.domain && candidate.Domain != domain && candidate.Domain != "*" {
+				continue
+			}
+			decision.Candidates = append(decision.Candidates, policy)
+		}
+	}
+	switch {
+	case len(subjects) == 1 && len(decision.Candidates) == 0:
+		decision.Reason = "用户没有任何角色，也没有直接授予的权限"
+	case len(decision.Candidates) == 0:
+		decision.Reason = fmt.Sprintf("用户及其角色 %s 没有任何权限", strings.Join(subjects, ", "))
+	default:
+		decision.Reason = fmt.Sprintf("用户及其角色 %s 共有 %d 条权限，没有与 %s %s 匹配的策略",
+			strings.Join(subjects, ", "), len(decision.Candidates), method, permission)
+	}
+	return decision, nil
+}
+
+// roleChain 通过 g 从请求主体找到策略主体的最短角色链，找不到时返回空
+func roleChain(e casbin.IEnforcer, layout *policyLayout, from, to, domain string) []string {
+	if from == to {
+		return []string{from}
+	}
+	if layout.grouping == 0 {
+		return nil
+	}
+	if layout.grouping < 3 {
+		domain = ""
+	}
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		values, err := layout.groupingFilter(0, current, domain)
+		if err != nil {
+			return nil
+		}
+		groupings, err := e.GetFilteredNamedGroupingPolicy("g", 0, values...)
+		if err != nil {
+			return nil
+		}
+		for _, grouping := range groupings {
+			next := grouping[1]
+			if _, ok := previous[next]; ok {
+				continue
+			}
+			previous[next] = current
+			if next == to {
+				chain := []string{next}
+				for node := current; node != ""; node = previous[node] {
+					chain = append([]string{node}, chain...)
+				}
+				return chain
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:59:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:59:10
+ * @FilePath: \go-core\pkg\casbin\fiberx.go
+ * @Description: Fiber Casbin 权限认证中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// CasbinFiberHandler Fiber Casbin权限认证，需放在 jwt.FiberJWTHandler 之后，校验函数默认为 VerifyPermission
+//
+//	app.Use(jwt.FiberJWTHandler(), casbin.CasbinFiberHandler())
+func CasbinFiberHandler(verifiers ...Verifier) fiber.Handler {
+	verify := verifierOf(verifiers)
+	return func(c *fiber.Ctx) error {
+		claims, err := jwt.GetFiberTypedClaims[jwt.Claims](c)
+		if err != nil {
+			claims, _ = jwt.ClaimsFrom(c.UserContext())
+		}
+		req := &Request{Method: c.Method(), Path: c.Path(), Claims: claims, Header: func(name string) string { return c.Get(name) }}
+		if err := Authorize(req, verify); err != nil {
+			return response.GenFiberResponse(c, response.NewErrorResponseOption(err))
+		}
+		return c.Next()
+	}
+}
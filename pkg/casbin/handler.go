@@ -11,12 +11,10 @@
 package casbin
 
 import (
-	"net/http"
-	"strings"
-
 	"github.com/gin-gonic/gin"
 	"github.com/kamalyes/go-core/pkg/global"
 	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
 )
 
 var casbinAdmi = global.GPerFix + "casbin_admi"
@@ -31,36 +29,66 @@ func GetCasbinAdmi() string {
 	return casbinAdmi
 }
 
-// CasbinHandler Casbin权限认证
+// DomainResolver 获取请求所属的域
+type DomainResolver func(ctx *gin.Context, claims jwt.Claims) string
+
+// tenantClaims 携带租户的 claims，jwt.CustomClaims 以商户号作为租户
+type tenantClaims interface {
+	GetTenantId() string
+}
+
+// ClaimsDomain 默认的域解析，使用 claims 中的商户号
+func ClaimsDomain(ctx *gin.Context, claims jwt.Claims) string {
+	if tenant, ok := claims.(tenantClaims); ok {
+		return tenant.GetTenantId()
+	}
+	return ""
+}
+
+// CasbinHandler Casbin权限认证，无权限时响应 403，Echo、Fiber、net/http 见 CasbinEchoHandler 等
 func CasbinHandler() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		path := ctx.Request.URL.Path
-		if strings.Contains(path, "swagger") {
-			ctx.Next()
-			return
-		}
-		if strings.Contains(path, "login") || strings.Contains(path, "health") || strings.Contains(path, "captcha") {
-			ctx.Next()
-			return
-		}
-		// 从ctx中获取claims
-		claims, _ := jwt.GetClaims(ctx)
-		user := claims.UserId
-		permission := ctx.Request.URL.Path
-		method := ctx.Request.Method
+		authorize(ctx, VerifyPermission)
+	}
+}
 
-		if claims.UserType != GetCasbinAdmi() {
-			ok := CasbinServiceApp.PermissionVerify(user, permission, method)
-			if !ok {
-				ctx.JSON(http.StatusMethodNotAllowed, gin.H{
-					"code":    -1,
-					"message": "用户已经通过身份验证，但请求的接口:(" + permission + ")不在您的权限之内！",
-				})
-				ctx.Abort()
-				return
-			}
-		}
-		ctx.Next()
+// CasbinDomainHandler 带域的Casbin权限认证，域默认取 JWT 中的商户号，需要使用 CasbinWithDomains 创建的执行者
+func CasbinDomainHandler(resolvers ...DomainResolver) gin.HandlerFunc {
+	resolver := DomainResolver(ClaimsDomain)
+	if len(resolvers) > 0 && resolvers[0] != nil {
+		resolver = resolvers[0]
+	}
+	return func(ctx *gin.Context) {
+		authorize(ctx, VerifyInDomain(func(req *Request) string {
+			return resolver(ctx, req.Claims)
+		}))
+	}
+}
+
+// CasbinABACHandler 基于 claims 属性的Casbin权限认证，需要使用 PresetABAC 创建的执行者
+func CasbinABACHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorize(ctx, VerifyWithClaims)
+	}
+}
+
+// authorize 组装 Gin 请求交给 Authorize，未通过时写出响应并中止
+func authorize(ctx *gin.Context, verify Verifier) {
+	// 从ctx中获取claims，获取失败时由 Authorize 返回 jwt.TokenMissing
+	claims, _ := jwt.GetTypedClaims[jwt.Claims](ctx)
+	req := &Request{Method: ctx.Request.Method, Path: ctx.Request.URL.Path, Claims: claims, Header: ctx.GetHeader}
+	if err := Authorize(req, verify); err != nil {
+		response.GenGinResponse(ctx, response.NewErrorResponseOption(err))
+		ctx.Abort()
 		return
 	}
+	ctx.Next()
+}
+
+// userType 获取用户类型，claims 未提供用户类型时返回空
+func userType(claims jwt.Claims) string {
+	if typed, ok := claims.(interface{ GetUserType() string }); ok {
+		return typed.GetUserType()
+	}
+	return ""
 }
@@ -0,0 +1,34 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:59:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:59:10
+ * @FilePath: \go-core\pkg\casbin\httpx.go
+ * @Description: net/http Casbin 权限认证中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"net/http"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// CasbinHttpHandler net/http Casbin权限认证，需包在 jwt.HttpJWTHandler 之内，校验函数默认为 VerifyPermission
+//
+//	handler := jwt.HttpJWTHandler(casbin.CasbinHttpHandler(mux))
+func CasbinHttpHandler(next http.Handler, verifiers ...Verifier) http.Handler {
+	verify := verifierOf(verifiers)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := jwt.ClaimsFrom(r.Context())
+		req := &Request{Method: r.Method, Path: r.URL.Path, Claims: claims, Header: r.Header.Get}
+		if err := Authorize(req, verify); err != nil {
+			response.GenNetHttpResponse(w, response.NewErrorResponseOption(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,172 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:59:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:59:10
+ * @FilePath: \go-core\pkg\casbin\middleware.go
+ * @Description: 与框架无关的权限认证，Gin、Echo、Fiber、net/http 中间件共用，无权限时按请求语言返回 403
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// PermissionDenied 已通过身份验证但没有访问权限，响应 403 与 AuthError，实际返回的错误信息见 SetDeniedMessage
+var PermissionDenied error = errors.New("没有访问该接口的权限")
+
+func init() {
+	response.RegisterErrorCode(PermissionDenied, response.StatusForbidden, response.AuthError)
+}
+
+// Request 与框架无关的授权请求
+type Request struct {
+	Method string
+	Path   string
+	Claims jwt.Claims               // 未通过 JWT 中间件时为空
+	Header func(name string) string // 读取请求头
+}
+
+// header 读取请求头，未提供 Header 时返回空
+func (r *Request) header(name string) string {
+	if r.Header == nil {
+		return ""
+	}
+	return r.Header(name)
+}
+
+// Verifier 判断请求是否有访问权限，调用时 Claims 不为空
+type Verifier func(req *Request) bool
+
+// RequestDomainResolver 获取请求所属的域，用于 Echo、Fiber、net/http 中间件
+type RequestDomainResolver func(req *Request) string
+
+// RequestClaimsDomain 默认的域解析，使用 claims 中的商户号
+func RequestClaimsDomain(req *Request) string {
+	return ClaimsDomain(nil, req.Claims)
+}
+
+// VerifyPermission 按当前身份的主体（见 ClaimsSubject）、请求路径与方法校验
+func VerifyPermission(req *Request) bool {
+	return CasbinServiceApp.PermissionVerifySubject(ClaimsSubject(req.Claims), req.Path, req.Method)
+}
+
+// VerifyInDomain 带域校验，域默认取 claims 中的商户号，解析不到域时拒绝
+func VerifyInDomain(resolvers ...RequestDomainResolver) Verifier {
+	resolver := RequestDomainResolver(RequestClaimsDomain)
+	if len(resolvers) > 0 && resolvers[0] != nil {
+		resolver = resolvers[0]
+	}
+	return func(req *Request) bool {
+		domain := resolver(req)
+		if domain == "" {
+			return false
+		}
+		return CasbinServiceApp.PermissionVerifySubjectInDomain(ClaimsSubject(req.Claims), domain, req.Path, req.Method)
+	}
+}
+
+// VerifyWithClaims 基于 claims 属性校验，需要使用 PresetABAC 创建的执行者
+func VerifyWithClaims(req *Request) bool {
+	return CasbinServiceApp.PermissionVerifyWithClaims(req.Claims, req.Path, req.Method)
+}
+
+// verifierOf 取第一个校验函数，未传时使用 VerifyPermission
+func verifierOf(verifiers []Verifier) Verifier {
+	if len(verifiers) > 0 && verifiers[0] != nil {
+		return verifiers[0]
+	}
+	return VerifyPermission
+}
+
+// Authorize
+/**
+ *  @Description: 权限认证的公共逻辑，各框架中间件只负责组装请求与写出响应
+ *  @param req 授权请求
+ *  @param verify 校验函数
+ *  @return err 放行时为空，没有 claims 时为 jwt.TokenMissing，无权限时可通过 errors.Is 判断为 PermissionDenied
+ */
+func Authorize(req *Request, verify Verifier) (err error) {
+	// 命中跳过规则的请求不做权限校验，规则见 SetSkipRules
+	if ShouldSkip(req.Method, req.Path) {
+		return nil
+	}
+	// 用户按 UserSubject 授权，机器身份（API Key、服务 Token）按 ServiceSubject 授权
+	if req.Claims == nil {
+		return jwt.TokenMissing
+	}
+	if userType(req.Claims) == GetCasbinAdmi() || verify(req) {
+		return nil
+	}
+	return &deniedError{message: GetDeniedMessage(req.header("Accept-Language"), req.Path)}
+}
+
+// deniedError 带本地化信息的 PermissionDenied
+type deniedError struct {
+	message string
+}
+
+func (e *deniedError) Error() string { return e.message }
+func (e *deniedError) Unwrap() error { return PermissionDenied }
+
+// DefaultDeniedLanguage 默认的无权限提示语言
+const DefaultDeniedLanguage = "zh"
+
+// deniedMessages 各语言的无权限提示，%s 为请求路径
+var deniedMessages = struct {
+	sync.RWMutex
+	language string
+	formats  map[string]string
+}{
+	language: DefaultDeniedLanguage,
+	formats: map[string]string{
+		"zh": "用户已经通过身份验证，但请求的接口:(%s)不在您的权限之内！",
+		"en": "You are authenticated, but the requested API (%s) is not within your permissions",
+	},
+}
+
+// SetDeniedMessage 设置某种语言的无权限提示，language 如 zh、en、zh-TW，format 中的 %s 为请求路径
+func SetDeniedMessage(language, format string) {
+	deniedMessages.Lock()
+	defer deniedMessages.Unlock()
+	deniedMessages.formats[strings.ToLower(language)] = format
+}
+
+// SetDefaultDeniedLanguage 设置请求未指定或不支持其语言时使用的语言
+func SetDefaultDeniedLanguage(language string) {
+	deniedMessages.Lock()
+	defer deniedMessages.Unlock()
+	deniedMessages.language = strings.ToLower(language)
+}
+
+// GetDeniedMessage 按 Accept-Language 获取无权限提示，依次匹配完整语言标签与主语言，如 zh-CN 会匹配 zh
+func GetDeniedMessage(acceptLanguage, path string) string {
+	deniedMessages.RLock()
+	defer deniedMessages.RUnlock()
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(item, ";", 2)[0]))
+		if tag == "" {
+			continue
+		}
+		if format, ok := deniedMessages.formats[tag]; ok {
+			return fmt.Sprintf(format, path)
+		}
+		if primary, _, found := strings.Cut(tag, "-"); found {
+			if format, ok := deniedMessages.formats[primary]; ok {
+				return fmt.Sprintf(format, path)
+			}
+		}
+	}
+	if format, ok := deniedMessages.formats[deniedMessages.language]; ok {
+		return fmt.Sprintf(format, path)
+	}
+	return PermissionDenied.Error()
+}
@@ -0,0 +1,195 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:59:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:59:10
+ * @FilePath: \go-core\pkg\casbin\middleware_test.go
+ * @Description: Gin、Echo、Fiber、net/http 权限认证中间件测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/response"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// middlewareResult 中间件的响应
+type middlewareResult struct {
+	status  int
+	code    response.SceneCode
+	message string
+}
+
+// serveFunc 以 claims 身份发起请求，claims 为空表示未登录
+type serveFunc func(t *testing.T, claims jwt.Claims, method, path, language string) middlewareResult
+
+// decodeResult 解析响应体
+func decodeResult(t *testing.T, status int, body []byte) middlewareResult {
+	result := middlewareResult{status: status}
+	if status == http.StatusOK {
+		return result
+	}
+	var payload struct {
+		Code    response.SceneCode `json:"code"`
+		Message string             `json:"message"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	result.code, result.message = payload.Code, payload.Message
+	return result
+}
+
+// newRequest 新建请求
+func newRequest(method, path, language string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+	return req
+}
+
+func serveGin(t *testing.T, claims jwt.Claims, method, path, language string) middlewareResult {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if claims != nil {
+			ctx.Set(jwt.DefaultClaimsKey, claims)
+		}
+	}, CasbinHandler())
+	router.Any("/*path", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest(method, path, language))
+	return decodeResult(t, rec.Code, rec.Body.Bytes())
+}
+
+func serveEcho(t *testing.T, claims jwt.Claims, method, path, language string) middlewareResult {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if claims != nil {
+				c.Set(jwt.DefaultClaimsKey, claims)
+			}
+			return next(c)
+		}
+	}, CasbinEchoHandler())
+	e.Any("/*", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newRequest(method, path, language))
+	return decodeResult(t, rec.Code, rec.Body.Bytes())
+}
+
+func serveFiber(t *testing.T, claims jwt.Claims, method, path, language string) middlewareResult {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if claims != nil {
+			c.SetUserContext(jwt.WithClaims(c.UserContext(), claims))
+		}
+		return c.Next()
+	}, CasbinFiberHandler())
+	app.All("/*", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+	resp, err := app.Test(newRequest(method, path, language))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body json.RawMessage
+	if resp.StatusCode != http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return decodeResult(t, resp.StatusCode, body)
+}
+
+func serveHttp(t *testing.T, claims jwt.Claims, method, path, language string) middlewareResult {
+	handler := CasbinHttpHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := newRequest(method, path, language)
+	if claims != nil {
+		req = req.WithContext(jwt.WithClaims(req.Context(), claims))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return decodeResult(t, rec.Code, rec.Body.Bytes())
+}
+
+func TestCasbinMiddlewares(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	require.NoError(t, CasbinServiceApp.AddPermissions(UserSubject("u1"), Permission{Object: "/users", Action: "GET"}))
+	user := &jwt.CustomClaims{UserId: "u1"}
+	admin := &jwt.CustomClaims{UserId: "u2", UserType: GetCasbinAdmi()}
+
+	for name, serve := range map[string]serveFunc{"gin": serveGin, "echo": serveEcho, "fiber": serveFiber, "http": serveHttp} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, http.StatusOK, serve(t, user, http.MethodGet, "/users", "").status)
+			assert.Equal(t, http.StatusOK, serve(t, admin, http.MethodDelete, "/users", "").status)
+
+			denied := serve(t, user, http.MethodDelete, "/users", "")
+			assert.Equal(t, middlewareResult{
+				status:  http.StatusForbidden,
+				code:    response.AuthError,
+				message: "用户已经通过身份验证，但请求的接口:(/users)不在您的权限之内！",
+			}, denied)
+			denied = serve(t, user, http.MethodDelete, "/users", "en-US,en;q=0.9")
+			assert.Equal(t, "You are authenticated, but the requested API (/users) is not within your permissions", denied.message)
+
+			missing := serve(t, nil, http.MethodGet, "/users", "")
+			assert.Equal(t, http.StatusUnauthorized, missing.status)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	useEnforcer(t, PresetRBACWithDomains)
+	require.NoError(t, CasbinServiceApp.AddPermissions(UserSubject("u1"), Permission{Domain: "m1", Object: "/orders", Action: "GET"}))
+	claims := &jwt.CustomClaims{UserId: "u1"}
+	header := func(name string) string { return "m1" }
+	verify := VerifyInDomain(func(req *Request) string { return req.header("X-Merchant-No") })
+
+	assert.NoError(t, Authorize(&Request{Method: http.MethodGet, Path: "/orders", Claims: claims, Header: header}, verify))
+	err := Authorize(&Request{Method: http.MethodGet, Path: "/orders", Claims: claims}, verify)
+	assert.True(t, errors.Is(err, PermissionDenied))
+	errorCode, ok := response.GetErrorCode(err)
+	require.True(t, ok)
+	assert.Equal(t, response.StatusForbidden, errorCode.HttpCode)
+
+	SetDeniedMessage("ja", "%s へのアクセス権限がありません")
+	SetDefaultDeniedLanguage("ja")
+	defer SetDefaultDeniedLanguage(DefaultDeniedLanguage)
+	assert.Equal(t, "/orders へのアクセス権限がありません", GetDeniedMessage("fr", "/orders"))
+	assert.Equal(t, "用户已经通过身份验证，但请求的接口:(/orders)不在您的权限之内！", GetDeniedMessage("fr, zh-CN;q=0.8", "/orders"))
+}
+
+func TestAuthorizeServiceSubject(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	require.NoError(t, CasbinServiceApp.AddPermissions(UserSubject("billing"), Permission{Object: "/users", Action: "GET"}))
+	require.NoError(t, CasbinServiceApp.AddPermissions(RoleSubject("reader"), Permission{Object: "/orders", Action: "GET"}))
+	require.NoError(t, CasbinServiceApp.AddRolesForService("billing", "", "reader"))
+	service := &jwt.ServiceClaims{ClientId: "billing", TokenUse: jwt.TokenUseService}
+	user := &jwt.CustomClaims{UserId: "billing"}
+
+	assert.Equal(t, "svc-billing", ClaimsSubject(service))
+	assert.Equal(t, "user-billing", ClaimsSubject(user))
+
+	// 客户端id 与用户id 相同时不共享权限
+	get := func(claims jwt.Claims, path string) error {
+		return Authorize(&Request{Method: http.MethodGet, Path: path, Claims: claims}, VerifyPermission)
+	}
+	assert.NoError(t, get(service, "/orders"))
+	assert.ErrorIs(t, get(service, "/users"), PermissionDenied)
+	assert.NoError(t, get(user, "/users"))
+	assert.ErrorIs(t, get(user, "/orders"), PermissionDenied)
+
+	users, err := CasbinServiceApp.GetUsersForRole("reader", "")
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
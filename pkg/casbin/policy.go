@@ -0,0 +1,884 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 22:52:14
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 22:52:14
+ * @FilePath: \go-core\pkg\casbin\policy.go
+ * @Description: 角色、用户角色分配、权限增删改查、角色继承与用户有效权限，按当前模型的字段布局读写策略，供管理接口使用
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// 策略管理相关错误
+var (
+	EnforcerNotInitialized  error = errors.New("未初始化 casbin 执行者")
+	DomainRequired          error = errors.New("当前模型带域，需要指定域")
+	DomainNotSupported      error = errors.New("当前模型不带域，不能指定域")
+	RoleNotSupported        error = errors.New("当前模型没有角色定义")
+	PolicyFormatUnsupported error = errors.New("不支持的策略格式")
+	PolicyInvalid           error = errors.New("策略无效")
+)
+
+// 策略效果，rbac-deny 预设使用
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+func init() {
+	response.RegisterErrorCode(DomainRequired, response.StatusBadRequest, response.ValidateError)
+	response.RegisterErrorCode(DomainNotSupported, response.StatusBadRequest, response.ValidateError)
+	response.RegisterErrorCode(RoleNotSupported, response.StatusBadRequest, response.ValidateError)
+	response.RegisterErrorCode(PolicyFormatUnsupported, response.StatusBadRequest, response.ValidateError)
+	response.RegisterErrorCode(PolicyInvalid, response.StatusBadRequest, response.ValidateError)
+}
+
+// Permission 一条权限，不含主体
+type Permission struct {
+	Domain string `json:"domain,omitempty"` // 域，带域的模型必填，* 表示全部域
+	Object string `json:"object"`           // 资源，一般为接口路径
+	Action string `json:"action"`           // 操作，一般为请求方法
+	Effect string `json:"effect,omitempty"` // allow 或 deny，模型带 eft 字段时使用，默认 allow
+}
+
+// EffectivePermission 用户的有效权限及其来源
+type EffectivePermission struct {
+	Permission
+	Subject string `json:"subject"` // 授予权限的主体，用户本身或其直接、间接拥有的角色
+}
+
+// policyLayout 当前模型中 p 与 g 的字段布局
+type policyLayout struct {
+	fields   []string // p 的字段名，如 sub、dom、obj、act、eft
+	domain   bool     // p 是否带 dom 字段
+	grouping int      // g 的字段数，0 表示没有角色，3 表示角色按域分配
+}
+
+// loadPolicyLayout 获取执行者与当前模型的字段布局
+func loadPolicyLayout() (casbin.IEnforcer, *policyLayout, error) {
+	if global.CSBEF == nil {
+		return nil, nil, EnforcerNotInitialized
+	}
+	layout, err := newPolicyLayout(global.CSBEF.GetModel())
+	if err != nil {
+		return nil, nil, err
+	}
+	return global.CSBEF, layout, nil
+}
+
+// newPolicyLayout 解析模型中 p 与 g 的字段布局
+func newPolicyLayout(m model.Model) (*policyLayout, error) {
+	p, ok := m["p"]["p"]
+	if !ok {
+		return nil, fmt.Errorf("%w：模型缺少 p 定义", PolicyInvalid)
+	}
+	layout := &policyLayout{}
+	for _, token := range p.Tokens {
+		field := strings.TrimPrefix(token, "p_")
+		layout.fields = append(layout.fields, field)
+		layout.domain = layout.domain || field == "dom"
+	}
+	if g, ok := m["g"]["g"]; ok {
+		layout.grouping = len(g.Tokens)
+	}
+	if layout.index("sub") < 0 {
+		return nil, fmt.Errorf("%w：模型的 p 定义缺少 sub 字段", PolicyInvalid)
+	}
+	return layout, nil
+}
+
+// index 字段在 p 中的位置，不存在时返回 -1
+func (l *policyLayout) index(field string) int {
+	for i, item := range l.fields {
+		if item == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkDomain 写入策略时校验域
+func (l *policyLayout) checkDomain(domain string) error {
+	if l.domain && domain == "" {
+		return DomainRequired
+	}
+	if !l.domain && domain != "" {
+		return DomainNotSupported
+	}
+	return nil
+}
+
+// checkFilterDomain 查询策略时校验域，带域的模型中为空表示全部域
+func (l *policyLayout) checkFilterDomain(domain string) error {
+	if !l.domain && domain != "" {
+		return DomainNotSupported
+	}
+	return nil
+}
+
+// rule 将主体与权限转换为 p 规则
+func (l *policyLayout) rule(subject string, permission Permission) ([]string, error) {
+	if err := l.checkDomain(permission.Domain); err != nil {
+		return nil, err
+	}
+	if subject == "" || permission.Object == "" || permission.Action == "" {
+		return nil, fmt.Errorf("%w：主体、资源与操作不能为空", PolicyInvalid)
+	}
+	rule := make([]string, len(l.fields))
+	for i, field := range l.fields {
+		switch field {
+		case "sub":
+			rule[i] = subject
+		case "dom":
+			rule[i] = permission.Domain
+		case "obj":
+			rule[i] = permission.Object
+		case "act":
+			rule[i] = permission.Action
+		case "eft":
+			rule[i] = permission.Effect
+			if rule[i] == "" {
+				rule[i] = EffectAllow
+			}
+		default:
+			return nil, fmt.Errorf("%w：不支持的策略字段 %s", PolicyInvalid, field)
+		}
+	}
+	return rule, nil
+}
+
+// permission 将 p 规则转换为主体与权限
+func (l *policyLayout) permission(rule []string) (subject string, permission Permission) {
+	for i, field := range l.fields {
+		if i >= len(rule) {
+			break
+		}
+		switch field {
+		case "sub":
+			subject = rule[i]
+		case "dom":
+			permission.Domain = rule[i]
+		case "obj":
+			permission.Object = rule[i]
+		case "act":
+			permission.Action = rule[i]
+		case "eft":
+			permission.Effect = rule[i]
+		}
+	}
+	return subject, permission
+}
+
+// policyFilter 按主体与域过滤 p 的参数，从第 0 个字段开始，域为空时不过滤域
+func (l *policyLayout) policyFilter(subject, domain string) []string {
+	values := make([]string, len(l.fields))
+	values[l.index("sub")] = subject
+	if domain != "" {
+		values[l.index("dom")] = domain
+	}
+	last := 0
+	for i, value := range values {
+		if value != "" {
+			last = i
+		}
+	}
+	return values[:last+1]
+}
+
+// checkGroupingDomain 校验角色分配的域
+func (l *policyLayout) checkGroupingDomain(domain string) error {
+	switch {
+	case l.grouping == 0:
+		return RoleNotSupported
+	case l.grouping < 3 && domain != "":
+		return DomainNotSupported
+	case l.grouping >= 3 && domain == "":
+		return DomainRequired
+	}
+	return nil
+}
+
+// groupingRule 生成 g 规则
+func (l *policyLayout) groupingRule(subject, role, domain string) ([]string, error) {
+	if err := l.checkGroupingDomain(domain); err != nil {
+		return nil, err
+	}
+	if subject == "" || role == "" {
+		return nil, fmt.Errorf("%w：主体与角色不能为空", PolicyInvalid)
+	}
+	if l.grouping < 3 {
+		return []string{subject, role}, nil
+	}
+	return []string{subject, role, domain}, nil
+}
+
+// groupingFilter 按主体或角色与域过滤 g 的参数，field 为 0 时按主体，为 1 时按角色，域为空时不过滤域
+func (l *policyLayout) groupingFilter(field int, value, domain string) ([]string, error) {
+	if l.grouping == 0 {
+		return nil, RoleNotSupported
+	}
+	if l.grouping < 3 && domain != "" {
+		return nil, DomainNotSupported
+	}
+	values := make([]string, field+1, 3)
+	values[field] = value
+	if domain != "" {
+		values = append(values[:2], domain)
+	}
+	return values, nil
+}
+
+// domainArgs 传给 casbin 角色接口的域参数
+func (l *policyLayout) domainArgs(domain string) []string {
+	if l.grouping >= 3 {
+		return []string{domain}
+	}
+	return nil
+}
+
+// ruleKey 用于比较规则
+func ruleKey(rule []string) string {
+	return strings.Join(rule, "\x00")
+}
+
+// existingRules 过滤出已存在的规则
+func existingRules(e casbin.IEnforcer, ptype string, grouping bool, rules [][]string) ([][]string, error) {
+	var existing [][]string
+	for _, rule := range rules {
+		var ok bool
+		var err error
+		if grouping {
+			ok, err = e.HasNamedGroupingPolicy(ptype, rule)
+		} else {
+			ok, err = e.HasNamedPolicy(ptype, rule)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			existing = append(existing, rule)
+		}
+	}
+	return existing, nil
+}
+
+// sortedKeys 去重排序后的角色名或用户id
+func sortedKeys(set map[string]struct{}) []string {
+	roles := make([]string, 0, len(set))
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// GetRoles
+/**
+ *  @Description: 获取角色列表，包括已分配给用户、有权限或参与继承的角色
+ *  @receiver casbinApi
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return roles 角色名
+ *  @return err
+ */
+func (s CasbinService) GetRoles(domain string) (roles []string, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkFilterDomain(domain); err != nil {
+		return nil, err
+	}
+	set := map[string]struct{}{}
+	add := func(subject string) {
+		if role, ok := RoleFromSubject(subject); ok && role != "" {
+			set[role] = struct{}{}
+		}
+	}
+	if layout.grouping > 0 {
+		groupings, err := e.GetNamedGroupingPolicy("g")
+		if err != nil {
+			return nil, err
+		}
+		for _, grouping := range groupings {
+			if domain != "" && len(grouping) > 2 && grouping[2] != domain {
+				continue
+			}
+			add(grouping[0])
+			add(grouping[1])
+		}
+	}
+	policies, err := e.GetNamedPolicy("p")
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		subject, permission := layout.permission(policy)
+		if domain != "" && permission.Domain != domain && permission.Domain != "*" {
+			continue
+		}
+		add(subject)
+	}
+	return sortedKeys(set), nil
+}
+
+// DeleteRole
+/**
+ *  @Description: 删除角色的权限、分配给用户与子角色的记录以及继承关系
+ *  @receiver casbinApi
+ *  @param role 角色名
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return err
+ */
+func (s CasbinService) DeleteRole(role, domain string) (err error) {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	if err := layout.checkFilterDomain(domain); err != nil {
+		return err
+	}
+	subject := RoleSubject(role)
+	if _, err := e.RemoveFilteredNamedPolicy("p", 0, layout.policyFilter(subject, domain)...); err != nil {
+		return err
+	}
+	if layout.grouping == 0 {
+		return nil
+	}
+	for field := 0; field < 2; field++ {
+		values, err := layout.groupingFilter(field, subject, domain)
+		if err != nil {
+			return err
+		}
+		if _, err := e.RemoveFilteredNamedGroupingPolicy("g", 0, values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addGroupings 为主体添加角色，已存在的忽略，角色名需通过 checkRoleName
+func addGroupings(subject, domain string, roles []string) error {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	rules := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		if err := checkRoleName(role); err != nil {
+			return err
+		}
+		rule, err := layout.groupingRule(subject, RoleSubject(role), domain)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err = e.AddNamedGroupingPoliciesEx("g", rules)
+	return err
+}
+
+// removeGroupings 取消主体的角色，roles 为空时取消域内全部角色
+func removeGroupings(subject, domain string, roles []string) error {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		if err := layout.checkGroupingDomain(domain); err != nil {
+			return err
+		}
+		values, err := layout.groupingFilter(0, subject, domain)
+		if err != nil {
+			return err
+		}
+		_, err = e.RemoveFilteredNamedGroupingPolicy("g", 0, values...)
+		return err
+	}
+	rules := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		rule, err := layout.groupingRule(subject, RoleSubject(role), domain)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if rules, err = existingRules(e, "g", true, rules); err != nil || len(rules) == 0 {
+		return err
+	}
+	_, err = e.RemoveNamedGroupingPolicies("g", rules)
+	return err
+}
+
+// directRoles 主体直接拥有的角色
+func directRoles(subject, domain string) ([]string, error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	values, err := layout.groupingFilter(0, subject, domain)
+	if err != nil {
+		return nil, err
+	}
+	groupings, err := e.GetFilteredNamedGroupingPolicy("g", 0, values...)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]struct{}{}
+	for _, grouping := range groupings {
+		if role, ok := RoleFromSubject(grouping[1]); ok {
+			set[role] = struct{}{}
+		}
+	}
+	return sortedKeys(set), nil
+}
+
+// AddRolesForUser
+/**
+ *  @Description: 为用户分配角色，已分配的忽略
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @param roles 角色名
+ *  @return err
+ */
+func (s CasbinService) AddRolesForUser(userId, domain string, roles ...string) (err error) {
+	return addGroupings(UserSubject(userId), domain, roles)
+}
+
+// AddRolesForService
+/**
+ *  @Description: 为机器身份分配角色，已分配的忽略
+ *  @receiver casbinApi
+ *  @param clientId 客户端id
+ *  @param domain 域，模型不带域时为空
+ *  @param roles 角色名
+ *  @return err
+ */
+func (s CasbinService) AddRolesForService(clientId, domain string, roles ...string) (err error) {
+	return addGroupings(ServiceSubject(clientId), domain, roles)
+}
+
+// RemoveRolesForService
+/**
+ *  @Description: 取消机器身份的角色
+ *  @receiver casbinApi
+ *  @param clientId 客户端id
+ *  @param domain 域，模型不带域时为空
+ *  @param roles 角色名，为空时取消域内全部角色
+ *  @return err
+ */
+func (s CasbinService) RemoveRolesForService(clientId, domain string, roles ...string) (err error) {
+	return removeGroupings(ServiceSubject(clientId), domain, roles)
+}
+
+// RemoveRolesForUser
+/**
+ *  @Description: 取消用户的角色
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @param roles 角色名，为空时取消域内全部角色
+ *  @return err
+ */
+func (s CasbinService) RemoveRolesForUser(userId, domain string, roles ...string) (err error) {
+	return removeGroupings(UserSubject(userId), domain, roles)
+}
+
+// SetRolesForUser
+/**
+ *  @Description: 将用户在域内的角色替换为 roles
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @param roles 角色名
+ *  @return err
+ */
+func (s CasbinService) SetRolesForUser(userId, domain string, roles []string) (err error) {
+	current, err := s.GetRolesForUser(userId, domain)
+	if err != nil {
+		return err
+	}
+	wanted := map[string]struct{}{}
+	for _, role := range roles {
+		wanted[role] = struct{}{}
+	}
+	var removed []string
+	for _, role := range current {
+		if _, ok := wanted[role]; !ok {
+			removed = append(removed, role)
+		}
+	}
+	if len(removed) > 0 {
+		if err := s.RemoveRolesForUser(userId, domain, removed...); err != nil {
+			return err
+		}
+	}
+	return s.AddRolesForUser(userId, domain, roles...)
+}
+
+// GetRolesForUser
+/**
+ *  @Description: 获取用户直接拥有的角色
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return roles 角色名
+ *  @return err
+ */
+func (s CasbinService) GetRolesForUser(userId, domain string) (roles []string, err error) {
+	return directRoles(UserSubject(userId), domain)
+}
+
+// GetImplicitRolesForUser
+/**
+ *  @Description: 获取用户直接与通过角色继承间接拥有的角色
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @return roles 角色名
+ *  @return err
+ */
+func (s CasbinService) GetImplicitRolesForUser(userId, domain string) (roles []string, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkGroupingDomain(domain); err != nil {
+		return nil, err
+	}
+	subjects, err := e.GetImplicitRolesForUser(UserSubject(userId), layout.domainArgs(domain)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		if role, ok := RoleFromSubject(subject); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// GetUsersForRole
+/**
+ *  @Description: 获取直接拥有角色的用户
+ *  @receiver casbinApi
+ *  @param role 角色名
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return users 用户id
+ *  @return err
+ */
+func (s CasbinService) GetUsersForRole(role, domain string) (users []string, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	values, err := layout.groupingFilter(1, RoleSubject(role), domain)
+	if err != nil {
+		return nil, err
+	}
+	groupings, err := e.GetFilteredNamedGroupingPolicy("g", 0, values...)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]struct{}{}
+	for _, grouping := range groupings {
+		if user, ok := UserIdFromSubject(grouping[0]); ok {
+			set[user] = struct{}{}
+		}
+	}
+	return sortedKeys(set), nil
+}
+
+// AddRoleInheritance
+/**
+ *  @Description: 角色继承上级角色的全部权限，不允许形成环，角色名不能以用户或机器身份主体前缀开头
+ *  @receiver casbinApi
+ *  @param role 角色名
+ *  @param parent 上级角色名
+ *  @param domain 域，模型不带域时为空
+ *  @return err
+ */
+func (s CasbinService) AddRoleInheritance(role, parent, domain string) (err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	if err := checkRoleName(role); err != nil {
+		return err
+	}
+	if _, err := layout.groupingRule(RoleSubject(role), RoleSubject(parent), domain); err != nil {
+		return err
+	}
+	ancestors, err := e.GetImplicitRolesForUser(RoleSubject(parent), layout.domainArgs(domain)...)
+	if err != nil {
+		return err
+	}
+	if role == parent || containsValue(ancestors, RoleSubject(role)) {
+		return fmt.Errorf("%w：角色 %s 继承 %s 会形成环", PolicyInvalid, role, parent)
+	}
+	return addGroupings(RoleSubject(role), domain, []string{parent})
+}
+
+// RemoveRoleInheritance
+/**
+ *  @Description: 取消角色对上级角色的继承
+ *  @receiver casbinApi
+ *  @param role 角色名
+ *  @param parent 上级角色名
+ *  @param domain 域，模型不带域时为空
+ *  @return err
+ */
+func (s CasbinService) RemoveRoleInheritance(role, parent, domain string) (err error) {
+	return removeGroupings(RoleSubject(role), domain, []string{parent})
+}
+
+// GetParentRoles
+/**
+ *  @Description: 获取角色直接继承的上级角色
+ *  @receiver casbinApi
+ *  @param role 角色名
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return parents 上级角色名
+ *  @return err
+ */
+func (s CasbinService) GetParentRoles(role, domain string) (parents []string, err error) {
+	return directRoles(RoleSubject(role), domain)
+}
+
+// AddPermissions
+/**
+ *  @Description: 为主体添加权限，已存在的忽略，角色主体的角色名不能以用户或机器身份主体前缀开头
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 RoleSubject 生成
+ *  @param permissions 权限
+ *  @return err
+ */
+func (s CasbinService) AddPermissions(subject string, permissions ...Permission) (err error) {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	if err := checkSubject(subject); err != nil {
+		return err
+	}
+	rules := make([][]string, 0, len(permissions))
+	for _, permission := range permissions {
+		rule, err := layout.rule(subject, permission)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	_, err = e.AddNamedPoliciesEx("p", rules)
+	return err
+}
+
+// RemovePermissions
+/**
+ *  @Description: 删除主体的权限，不存在的忽略
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 RoleSubject 生成
+ *  @param permissions 权限
+ *  @return err
+ */
+func (s CasbinService) RemovePermissions(subject string, permissions ...Permission) (err error) {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	rules := make([][]string, 0, len(permissions))
+	for _, permission := range permissions {
+		rule, err := layout.rule(subject, permission)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if rules, err = existingRules(e, "p", false, rules); err != nil || len(rules) == 0 {
+		return err
+	}
+	_, err = e.RemoveNamedPolicies("p", rules)
+	return err
+}
+
+// UpdatePermission
+/**
+ *  @Description: 修改主体的一条权限
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 RoleSubject 生成
+ *  @param oldPermission 原权限
+ *  @param newPermission 新权限
+ *  @return err
+ */
+func (s CasbinService) UpdatePermission(subject string, oldPermission, newPermission Permission) (err error) {
+	defer InvalidateDecisionCache()
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	oldRule, err := layout.rule(subject, oldPermission)
+	if err != nil {
+		return err
+	}
+	newRule, err := layout.rule(subject, newPermission)
+	if err != nil {
+		return err
+	}
+	ok, err := e.UpdatePolicy(oldRule, newRule)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w：原权限不存在", PolicyInvalid)
+	}
+	return nil
+}
+
+// SetPermissions
+/**
+ *  @Description: 将主体在域内的权限替换为 permissions
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 RoleSubject 生成
+ *  @param domain 域，模型不带域时为空，权限未指定域时使用该域
+ *  @param permissions 权限
+ *  @return err
+ */
+func (s CasbinService) SetPermissions(subject, domain string, permissions []Permission) (err error) {
+	_, layout, err := loadPolicyLayout()
+	if err != nil {
+		return err
+	}
+	if err := layout.checkDomain(domain); err != nil {
+		return err
+	}
+	current, err := s.GetPermissions(subject, domain)
+	if err != nil {
+		return err
+	}
+	wanted := map[string]struct{}{}
+	for i := range permissions {
+		if permissions[i].Domain == "" {
+			permissions[i].Domain = domain
+		}
+		if permissions[i].Domain != domain {
+			return fmt.Errorf("%w：权限的域 %s 与 %s 不一致", PolicyInvalid, permissions[i].Domain, domain)
+		}
+		rule, err := layout.rule(subject, permissions[i])
+		if err != nil {
+			return err
+		}
+		wanted[ruleKey(rule)] = struct{}{}
+	}
+	var removed []Permission
+	for _, permission := range current {
+		rule, err := layout.rule(subject, permission)
+		if err != nil {
+			return err
+		}
+		if _, ok := wanted[ruleKey(rule)]; !ok {
+			removed = append(removed, permission)
+		}
+	}
+	if err := s.RemovePermissions(subject, removed...); err != nil {
+		return err
+	}
+	return s.AddPermissions(subject, permissions...)
+}
+
+// GetPermissions
+/**
+ *  @Description: 获取主体直接拥有的权限，不含角色继承得到的权限
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 RoleSubject 生成
+ *  @param domain 域，带域的模型中为空表示全部域
+ *  @return permissions 权限
+ *  @return err
+ */
+func (s CasbinService) GetPermissions(subject, domain string) (permissions []Permission, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkFilterDomain(domain); err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredNamedPolicy("p", 0, layout.policyFilter(subject, domain)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		_, permission := layout.permission(policy)
+		permissions = append(permissions, permission)
+	}
+	return permissions, nil
+}
+
+// GetEffectivePermissions
+/**
+ *  @Description: 获取用户的有效权限，包括直接授予、角色授予与角色继承得到的权限，带域的模型中包含 * 域的权限
+ *  @receiver casbinApi
+ *  @param userId 用户id
+ *  @param domain 域，模型不带域时为空
+ *  @return permissions 有效权限及其来源，模型带 eft 字段时 deny 权限同样列出
+ *  @return err
+ */
+func (s CasbinService) GetEffectivePermissions(userId, domain string) (permissions []EffectivePermission, err error) {
+	e, layout, err := loadPolicyLayout()
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.checkDomain(domain); err != nil {
+		return nil, err
+	}
+	subjects := []string{UserSubject(userId)}
+	if layout.grouping > 0 {
+		roles, err := e.GetImplicitRolesForUser(UserSubject(userId), layout.domainArgs(domain)...)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, roles...)
+	}
+	seen := map[string]struct{}{}
+	for _, subject := range subjects {
+		policies, err := e.GetFilteredNamedPolicy("p", 0, layout.policyFilter(subject, "")...)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			_, permission := layout.permission(policy)
+			if layout.domain && permission.Domain != domain && permission.Domain != "*" {
+				continue
+			}
+			if _, ok := seen[ruleKey(policy)]; ok {
+				continue
+			}
+			seen[ruleKey(policy)] = struct{}{}
+			permissions = append(permissions, EffectivePermission{Permission: permission, Subject: subject})
+		}
+	}
+	return permissions, nil
+}
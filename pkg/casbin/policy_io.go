@@ -0,0 +1,251 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:16:40
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:16:40
+ * @FilePath: \go-core\pkg\casbin\policy_io.go
+ * @Description: 策略批量导入导出，支持与 casbin 策略文件一致的 CSV 以及 JSON
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/kamalyes/go-core/pkg/global"
+)
+
+// 策略导入导出格式
+const (
+	PolicyFormatCSV  = "csv"  // 每行一条策略，如 p, admin, /users, GET
+	PolicyFormatJSON = "json" // PolicyRule 数组
+)
+
+// PolicyRule 一条原始策略，与策略文件中的一行对应
+type PolicyRule struct {
+	Ptype string   `json:"ptype"` // p、g、g2 等
+	Rule  []string `json:"rule"`
+}
+
+// PolicyImportResult 导入结果
+type PolicyImportResult struct {
+	Added   int `json:"added"`   // 新增的策略数
+	Removed int `json:"removed"` // 替换模式下删除的策略数
+}
+
+// policyType 模型中的一种策略
+type policyType struct {
+	sec    string
+	ptype  string
+	fields int
+}
+
+// policyTypes 模型中的全部策略类型，p 在前 g 在后
+func policyTypes(m model.Model) []policyType {
+	var types []policyType
+	for _, sec := range []string{"p", "g"} {
+		ptypes := make([]string, 0, len(m[sec]))
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			types = append(types, policyType{sec: sec, ptype: ptype, fields: len(m[sec][ptype].Tokens)})
+		}
+	}
+	return types
+}
+
+// ExportPolicies
+/**
+ *  @Description: 导出全部策略
+ *  @receiver casbinApi
+ *  @param w 输出
+ *  @param format PolicyFormatCSV 或 PolicyFormatJSON
+ *  @return err
+ */
+func (s CasbinService) ExportPolicies(w io.Writer, format string) (err error) {
+	e := global.CSBEF
+	if e == nil {
+		return EnforcerNotInitialized
+	}
+	var rules []PolicyRule
+	for _, item := range policyTypes(e.GetModel()) {
+		var policies [][]string
+		if item.sec == "p" {
+			policies, err = e.GetNamedPolicy(item.ptype)
+		} else {
+			policies, err = e.GetNamedGroupingPolicy(item.ptype)
+		}
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			rules = append(rules, PolicyRule{Ptype: item.ptype, Rule: policy})
+		}
+	}
+	return writePolicyRules(w, format, rules)
+}
+
+// ImportPolicies
+/**
+ *  @Description: 导入策略，全部校验通过后才写入，已存在的策略忽略
+ *  @receiver casbinApi
+ *  @param r 输入
+ *  @param format PolicyFormatCSV 或 PolicyFormatJSON
+ *  @param replace 是否替换，为 true 时删除导入内容中没有的策略，包括导入内容未出现的策略类型
+ *  @return result 新增与删除的策略数
+ *  @return err
+ */
+func (s CasbinService) ImportPolicies(r io.Reader, format string, replace bool) (result PolicyImportResult, err error) {
+	defer InvalidateDecisionCache()
+	e := global.CSBEF
+	if e == nil {
+		return result, EnforcerNotInitialized
+	}
+	rules, err := readPolicyRules(r, format)
+	if err != nil {
+		return result, err
+	}
+	types := policyTypes(e.GetModel())
+	imported := map[string][][]string{}
+	seen := map[string]struct{}{}
+	for i, rule := range rules {
+		var matched *policyType
+		for j := range types {
+			if types[j].ptype == rule.Ptype {
+				matched = &types[j]
+				break
+			}
+		}
+		if matched == nil {
+			return result, fmt.Errorf("%w：第 %d 条策略的类型 %s 不在模型中", PolicyInvalid, i+1, rule.Ptype)
+		}
+		if len(rule.Rule) != matched.fields {
+			return result, fmt.Errorf("%w：第 %d 条策略应有 %d 个字段，实际为 %d 个", PolicyInvalid, i+1, matched.fields, len(rule.Rule))
+		}
+		key := rule.Ptype + "\x00" + ruleKey(rule.Rule)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		imported[rule.Ptype] = append(imported[rule.Ptype], rule.Rule)
+	}
+
+	for _, item := range types {
+		var existing [][]string
+		if item.sec == "p" {
+			existing, err = e.GetNamedPolicy(item.ptype)
+		} else {
+			existing, err = e.GetNamedGroupingPolicy(item.ptype)
+		}
+		if err != nil {
+			return result, err
+		}
+		existingSet := map[string]struct{}{}
+		for _, rule := range existing {
+			existingSet[ruleKey(rule)] = struct{}{}
+		}
+		importedSet := map[string]struct{}{}
+		var added, removed [][]string
+		for _, rule := range imported[item.ptype] {
+			importedSet[ruleKey(rule)] = struct{}{}
+			if _, ok := existingSet[ruleKey(rule)]; !ok {
+				added = append(added, rule)
+			}
+		}
+		if replace {
+			for _, rule := range existing {
+				if _, ok := importedSet[ruleKey(rule)]; !ok {
+					removed = append(removed, rule)
+				}
+			}
+		}
+		if len(removed) > 0 {
+			if item.sec == "p" {
+				_, err = e.RemoveNamedPolicies(item.ptype, removed)
+			} else {
+				_, err = e.RemoveNamedGroupingPolicies(item.ptype, removed)
+			}
+			if err != nil {
+				return result, err
+			}
+			result.Removed += len(removed)
+		}
+		if len(added) > 0 {
+			if item.sec == "p" {
+				_, err = e.AddNamedPolicies(item.ptype, added)
+			} else {
+				_, err = e.AddNamedGroupingPolicies(item.ptype, added)
+			}
+			if err != nil {
+				return result, err
+			}
+			result.Added += len(added)
+		}
+	}
+	return result, nil
+}
+
+// readPolicyRules 读取策略，CSV 中 # 开头的行与空行忽略
+func readPolicyRules(r io.Reader, format string) ([]PolicyRule, error) {
+	switch strings.ToLower(format) {
+	case PolicyFormatJSON:
+		var rules []PolicyRule
+		if err := json.NewDecoder(r).Decode(&rules); err != nil {
+			return nil, fmt.Errorf("%w：%s", PolicyInvalid, err.Error())
+		}
+		return rules, nil
+	case PolicyFormatCSV:
+		reader := csv.NewReader(r)
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%w：%s", PolicyInvalid, err.Error())
+		}
+		rules := make([]PolicyRule, 0, len(records))
+		for _, record := range records {
+			for i := range record {
+				record[i] = strings.TrimSpace(record[i])
+			}
+			if len(record) == 0 || record[0] == "" {
+				continue
+			}
+			rules = append(rules, PolicyRule{Ptype: record[0], Rule: record[1:]})
+		}
+		return rules, nil
+	default:
+		return nil, fmt.Errorf("%w：%s", PolicyFormatUnsupported, format)
+	}
+}
+
+// writePolicyRules 写出策略
+func writePolicyRules(w io.Writer, format string, rules []PolicyRule) error {
+	switch strings.ToLower(format) {
+	case PolicyFormatJSON:
+		if rules == nil {
+			rules = []PolicyRule{}
+		}
+		return json.NewEncoder(w).Encode(rules)
+	case PolicyFormatCSV:
+		writer := csv.NewWriter(w)
+		for _, rule := range rules {
+			if err := writer.Write(append([]string{rule.Ptype}, rule.Rule...)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("%w：%s", PolicyFormatUnsupported, format)
+	}
+}
@@ -0,0 +1,265 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 23:16:40
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 23:16:40
+ * @FilePath: \go-core\pkg\casbin\policy_test.go
+ * @Description: 策略管理与导入导出测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useEnforcer 使用预设创建内存中的执行者作为 global.CSBEF
+func useEnforcer(t *testing.T, preset string) {
+	m, err := NewPresetModel(preset)
+	require.NoError(t, err)
+	enforcer, err := casbin.NewSyncedEnforcer(m)
+	require.NoError(t, err)
+	previous := global.CSBEF
+	global.CSBEF = enforcer
+	t.Cleanup(func() { global.CSBEF = previous })
+}
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "user-1001", UserSubject("1001"))
+	assert.Equal(t, "admin", RoleSubject("admin"))
+	userId, ok := UserIdFromSubject("user-1001")
+	assert.True(t, ok)
+	assert.Equal(t, "1001", userId)
+	_, ok = RoleFromSubject("user-1001")
+	assert.False(t, ok)
+
+	SetRoleSubjectPrefix("role-")
+	defer SetRoleSubjectPrefix("")
+	assert.Equal(t, "role-admin", RoleSubject("admin"))
+	role, ok := RoleFromSubject("role-admin")
+	assert.True(t, ok)
+	assert.Equal(t, "admin", role)
+	_, ok = RoleFromSubject("admin")
+	assert.False(t, ok)
+}
+
+func TestServiceRBAC(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	svc := CasbinServiceApp
+
+	require.NoError(t, svc.AddPermissions(RoleSubject("reader"), Permission{Object: "/users", Action: "GET"}))
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"),
+		Permission{Object: "/users", Action: "POST"}, Permission{Object: "/users", Action: "DELETE"}))
+	require.NoError(t, svc.AddPermissions(UserSubject("u1"), Permission{Object: "/profile", Action: "GET"}))
+	require.NoError(t, svc.AddRoleInheritance("admin", "reader", ""))
+	require.NoError(t, svc.AddRolesForUser("u1", "", "admin"))
+	require.NoError(t, svc.AddRolesForUser("u2", "", "reader"))
+
+	assert.ErrorIs(t, svc.AddRoleInheritance("reader", "admin", ""), PolicyInvalid)
+	assert.ErrorIs(t, svc.AddPermissions(RoleSubject("admin"), Permission{Domain: "m1", Object: "/a", Action: "GET"}), DomainNotSupported)
+	assert.True(t, svc.PermissionVerify("u1", "/users", "GET"))
+
+	roles, err := svc.GetRoles("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "reader"}, roles)
+	roles, err = svc.GetRolesForUser("u1", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+	roles, err = svc.GetImplicitRolesForUser("u1", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"admin", "reader"}, roles)
+	parents, err := svc.GetParentRoles("admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reader"}, parents)
+	users, err := svc.GetUsersForRole("reader", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u2"}, users)
+
+	effective, err := svc.GetEffectivePermissions("u1", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []EffectivePermission{
+		{Permission: Permission{Object: "/profile", Action: "GET"}, Subject: "user-u1"},
+		{Permission: Permission{Object: "/users", Action: "POST"}, Subject: "admin"},
+		{Permission: Permission{Object: "/users", Action: "DELETE"}, Subject: "admin"},
+		{Permission: Permission{Object: "/users", Action: "GET"}, Subject: "reader"},
+	}, effective)
+
+	require.NoError(t, svc.UpdatePermission(RoleSubject("admin"),
+		Permission{Object: "/users", Action: "DELETE"}, Permission{Object: "/users", Action: "PUT"}))
+	assert.ErrorIs(t, svc.UpdatePermission(RoleSubject("admin"),
+		Permission{Object: "/missing", Action: "GET"}, Permission{Object: "/users", Action: "PUT"}), PolicyInvalid)
+	require.NoError(t, svc.SetPermissions(RoleSubject("admin"), "", []Permission{
+		{Object: "/users", Action: "PUT"}, {Object: "/roles", Action: "POST"},
+	}))
+	permissions, err := svc.GetPermissions(RoleSubject("admin"), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Permission{{Object: "/users", Action: "PUT"}, {Object: "/roles", Action: "POST"}}, permissions)
+
+	require.NoError(t, svc.SetRolesForUser("u1", "", []string{"reader"}))
+	roles, err = svc.GetRolesForUser("u1", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reader"}, roles)
+
+	require.NoError(t, svc.DeleteRole("reader", ""))
+	roles, err = svc.GetRoles("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+	assert.False(t, svc.PermissionVerify("u2", "/users", "GET"))
+	parents, err = svc.GetParentRoles("admin", "")
+	require.NoError(t, err)
+	assert.Empty(t, parents)
+}
+
+func TestServiceRBACWithDomains(t *testing.T) {
+	useEnforcer(t, PresetRBACWithDomains)
+	svc := CasbinServiceApp
+
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Domain: "m1", Object: "/orders", Action: "GET"}))
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Domain: "*", Object: "/audit", Action: "GET"}))
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Domain: "m2", Object: "/invoices", Action: "GET"}))
+	require.NoError(t, svc.AddRolesForUser("u1", "m1", "admin"))
+	require.NoError(t, svc.AddRolesForUser("u2", "m2", "admin"))
+
+	assert.ErrorIs(t, svc.AddRolesForUser("u1", "", "admin"), DomainRequired)
+	assert.ErrorIs(t, svc.AddPermissions(RoleSubject("admin"), Permission{Object: "/a", Action: "GET"}), DomainRequired)
+
+	effective, err := svc.GetEffectivePermissions("u1", "m1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []EffectivePermission{
+		{Permission: Permission{Domain: "m1", Object: "/orders", Action: "GET"}, Subject: "admin"},
+		{Permission: Permission{Domain: "*", Object: "/audit", Action: "GET"}, Subject: "admin"},
+	}, effective)
+	effective, err = svc.GetEffectivePermissions("u1", "m2")
+	require.NoError(t, err)
+	assert.Empty(t, effective)
+
+	users, err := svc.GetUsersForRole("admin", "m2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u2"}, users)
+	users, err = svc.GetUsersForRole("admin", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u2"}, users)
+
+	require.NoError(t, svc.DeleteRole("admin", "m1"))
+	permissions, err := svc.GetPermissions(RoleSubject("admin"), "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Permission{
+		{Domain: "*", Object: "/audit", Action: "GET"},
+		{Domain: "m2", Object: "/invoices", Action: "GET"},
+	}, permissions)
+	roles, err := svc.GetRolesForUser("u1", "m1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+	roles, err = svc.GetRolesForUser("u2", "m2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+}
+
+func TestServiceRBACDenyEffect(t *testing.T) {
+	useEnforcer(t, PresetRBACDeny)
+	svc := CasbinServiceApp
+	require.NoError(t, svc.AddPermissions(RoleSubject("admin"), Permission{Object: "/users/*", Action: "*"}))
+	require.NoError(t, svc.AddPermissions(UserSubject("u1"), Permission{Object: "/users/:id/password", Action: "*", Effect: EffectDeny}))
+	require.NoError(t, svc.AddRolesForUser("u1", "", "admin"))
+
+	effective, err := svc.GetEffectivePermissions("u1", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []EffectivePermission{
+		{Permission: Permission{Object: "/users/:id/password", Action: "*", Effect: EffectDeny}, Subject: "user-u1"},
+		{Permission: Permission{Object: "/users/*", Action: "*", Effect: EffectAllow}, Subject: "admin"},
+	}, effective)
+	assert.False(t, svc.PermissionVerify("u1", "/users/1/password", "PUT"))
+}
+
+func TestImportExportPolicies(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	svc := CasbinServiceApp
+
+	input := `# 角色权限
+p, admin, /users, GET
+p, admin, /users, GET
+p, reader, "/search?q=a,b", GET
+
+g, user-u1, admin
+`
+	result, err := svc.ImportPolicies(strings.NewReader(input), PolicyFormatCSV, false)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyImportResult{Added: 3}, result)
+	assert.True(t, svc.PermissionVerify("u1", "/users", "GET"))
+
+	var csvOut bytes.Buffer
+	require.NoError(t, svc.ExportPolicies(&csvOut, PolicyFormatCSV))
+	assert.Equal(t, "p,admin,/users,GET\np,reader,\"/search?q=a,b\",GET\ng,user-u1,admin\n", csvOut.String())
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, svc.ExportPolicies(&jsonOut, PolicyFormatJSON))
+	result, err = svc.ImportPolicies(strings.NewReader(`[{"ptype":"p","rule":["admin","/users","GET"]}]`), PolicyFormatJSON, true)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyImportResult{Removed: 2}, result)
+	assert.False(t, svc.PermissionVerify("u1", "/users", "GET"))
+
+	result, err = svc.ImportPolicies(&jsonOut, PolicyFormatJSON, true)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyImportResult{Added: 2}, result)
+	assert.True(t, svc.PermissionVerify("u1", "/users", "GET"))
+
+	_, err = svc.ImportPolicies(strings.NewReader("p, admin, /users\n"), PolicyFormatCSV, false)
+	assert.ErrorIs(t, err, PolicyInvalid)
+	_, err = svc.ImportPolicies(strings.NewReader("g2, a, b\n"), PolicyFormatCSV, false)
+	assert.ErrorIs(t, err, PolicyInvalid)
+	_, err = svc.ImportPolicies(strings.NewReader(""), "yaml", false)
+	assert.ErrorIs(t, err, PolicyFormatUnsupported)
+}
+
+func TestPermissionForSubjectInDomain(t *testing.T) {
+	useEnforcer(t, PresetRBACWithDomains)
+	svc := CasbinServiceApp
+	require.NoError(t, svc.AddPermissionForUserInDomain(UserSubject("u1"), "m1", "/orders", "GET"))
+	require.NoError(t, svc.AddPermissionForUserInDomain(RoleSubject("admin"), "m1", "/users", "GET"))
+	require.NoError(t, svc.AddRoleForUserInDomain("u2", "admin", "m1"))
+	assert.ErrorIs(t, svc.AddRoleForUserInDomain("u2", "user-u1", "m1"), PolicyInvalid)
+	assert.ErrorIs(t, svc.AddPermissionForUserInDomain("", "m1", "/users", "GET"), PolicyInvalid)
+
+	assert.True(t, svc.PermissionVerifyInDomain("u1", "m1", "/orders", "GET"))
+	assert.False(t, svc.PermissionVerifyInDomain("u1", "m1", "/users", "GET"))
+	assert.True(t, svc.PermissionVerifyInDomain("u2", "m1", "/users", "GET"))
+
+	require.NoError(t, svc.DeletePermissionForUserInDomain(UserSubject("u1"), "m1", "/orders", "GET"))
+	assert.False(t, svc.PermissionVerifyInDomain("u1", "m1", "/orders", "GET"))
+}
+
+func TestRoleNamePrefix(t *testing.T) {
+	useEnforcer(t, PresetRBAC)
+	svc := CasbinServiceApp
+
+	// 角色名以用户或机器身份主体前缀开头时会被当作用户或机器身份
+	for _, role := range []string{"user-admin", "svc-admin", ""} {
+		assert.ErrorIs(t, svc.AddRolesForUser("u1", "", role), PolicyInvalid, role)
+		assert.ErrorIs(t, svc.AddRolesForService("billing", "", role), PolicyInvalid, role)
+		assert.ErrorIs(t, svc.AddRoleInheritance(role, "reader", ""), PolicyInvalid, role)
+		assert.ErrorIs(t, svc.AddRoleInheritance("reader", role, ""), PolicyInvalid, role)
+	}
+	assert.ErrorIs(t, svc.AddPermissions("", Permission{Object: "/a", Action: "GET"}), PolicyInvalid)
+	require.NoError(t, svc.AddPermissions(UserSubject("admin"), Permission{Object: "/a", Action: "GET"}))
+	require.NoError(t, svc.AddPermissions(ServiceSubject("billing"), Permission{Object: "/a", Action: "GET"}))
+	roles, err := svc.GetRoles("")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// 设置角色前缀后，不带前缀的主体既不是用户也不是角色
+	SetRoleSubjectPrefix("role-")
+	defer SetRoleSubjectPrefix("")
+	require.NoError(t, svc.AddPermissions(RoleSubject("user-admin"), Permission{Object: "/b", Action: "GET"}))
+	assert.ErrorIs(t, svc.AddPermissions("admin", Permission{Object: "/b", Action: "GET"}), PolicyInvalid)
+	roles, err = svc.GetRoles("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-admin"}, roles)
+}
@@ -0,0 +1,126 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 21:22:15
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 21:22:15
+ * @FilePath: \go-core\pkg\casbin\presets.go
+ * @Description: 内置模型预设，支持路径参数、正则、请求方法通配、基于 claims 的 ABAC 与拒绝优先
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// 内置模型预设名称
+const (
+	PresetRBAC            = "rbac"              // DefaultModel，路径与方法完全相等
+	PresetRBACWithDomains = "rbac-with-domains" // DomainModel，带域的 RBAC
+	PresetRBACKeyMatch2   = "rbac-keymatch2"    // 路径参数 /users/:id 与通配 /users/*
+	PresetRBACKeyMatch5   = "rbac-keymatch5"    // 路径参数 /users/{id}，忽略查询参数
+	PresetRBACRegex       = "rbac-regex"        // 路径为完整匹配的正则表达式
+	PresetABAC            = "abac"              // 按 Attributes 授权，策略的主体为表达式
+	PresetRBACDeny        = "rbac-deny"         // 路径参数同 keymatch2，deny 策略优先于 allow
+)
+
+// actMatcher 请求方法匹配：* 匹配全部方法，GET|POST 匹配其中之一
+const actMatcher = `(p.act == "*" || regexMatch(r.act, "^(" + p.act + ")$"))`
+
+// rbacPreset 按路径匹配函数生成 RBAC 模型
+func rbacPreset(objMatcher string) string {
+	return `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && ` + objMatcher + ` && ` + actMatcher
+}
+
+// presetModels 预设名称与模型
+var presetModels = map[string]string{
+	PresetRBAC:            DefaultModel,
+	PresetRBACWithDomains: DomainModel,
+	PresetRBACKeyMatch2:   rbacPreset(`keyMatch2(r.obj, p.obj)`),
+	PresetRBACKeyMatch5:   rbacPreset(`keyMatch5(r.obj, p.obj)`),
+	PresetRBACRegex:       rbacPreset(`regexMatch(r.obj, "^(?:" + p.obj + ")$")`),
+	PresetABAC: `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub_rule, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = eval(p.sub_rule) && keyMatch2(r.obj, p.obj) && ` + actMatcher,
+	PresetRBACDeny: `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
+
+[matchers]
+m = g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && ` + actMatcher,
+}
+
+// Presets 全部内置模型预设名称
+func Presets() []string {
+	names := make([]string, 0, len(presetModels))
+	for name := range presetModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetModelText 获取预设的模型文本
+func PresetModelText(preset string) (string, error) {
+	text, ok := presetModels[preset]
+	if !ok {
+		return "", fmt.Errorf("未知的Casbin模型预设：%s", preset)
+	}
+	return text, nil
+}
+
+// NewPresetModel 解析预设模型
+func NewPresetModel(preset string) (model.Model, error) {
+	text, err := PresetModelText(preset)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewModelFromString(text)
+}
+
+// CasbinWithPreset
+/**
+ *  @Description: 使用内置模型预设初始化Casbin执行者（与gorm结合）
+ *  @param preset 预设名称，见 Presets
+ *  @return Enforcer casbin执行者
+ */
+func CasbinWithPreset(preset string) *casbin.SyncedEnforcer {
+	return mustEnforcer(preset)
+}
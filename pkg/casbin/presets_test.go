@@ -0,0 +1,169 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 21:40:52
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 21:40:52
+ * @FilePath: \go-core\pkg\casbin\presets_test.go
+ * @Description: 内置模型预设测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enforceCase 一次授权判断
+type enforceCase struct {
+	name  string
+	rvals []interface{}
+	want  bool
+}
+
+// newPresetEnforcer 使用预设创建内存中的执行者并写入策略
+func newPresetEnforcer(t *testing.T, preset string, policies [][]string, groupings [][]string) *casbin.Enforcer {
+	m, err := NewPresetModel(preset)
+	require.NoError(t, err)
+	enforcer, err := casbin.NewEnforcer(m)
+	require.NoError(t, err)
+	for _, policy := range policies {
+		_, err := enforcer.AddPolicy(policy)
+		require.NoError(t, err)
+	}
+	for _, grouping := range groupings {
+		_, err := enforcer.AddGroupingPolicy(grouping)
+		require.NoError(t, err)
+	}
+	return enforcer
+}
+
+// assertCases 逐个校验授权结果
+func assertCases(t *testing.T, enforcer *casbin.Enforcer, cases []enforceCase) {
+	for _, c := range cases {
+		ok, err := enforcer.Enforce(c.rvals...)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.want, ok, c.name)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	assert.Equal(t, []string{
+		PresetABAC, PresetRBAC, PresetRBACDeny, PresetRBACKeyMatch2,
+		PresetRBACKeyMatch5, PresetRBACRegex, PresetRBACWithDomains,
+	}, Presets())
+	for _, preset := range Presets() {
+		_, err := NewPresetModel(preset)
+		assert.NoError(t, err, preset)
+	}
+	_, err := NewPresetModel("unknown")
+	assert.Error(t, err)
+}
+
+func TestPresetRBAC(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBAC,
+		[][]string{{"admin", "/users", "GET"}},
+		[][]string{{"user-u1", "admin"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"role permission", []interface{}{"user-u1", "/users", "GET"}, true},
+		{"other method", []interface{}{"user-u1", "/users", "POST"}, false},
+		{"path must be equal", []interface{}{"user-u1", "/users/1", "GET"}, false},
+		{"without role", []interface{}{"user-u2", "/users", "GET"}, false},
+	})
+}
+
+func TestPresetRBACWithDomains(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBACWithDomains,
+		[][]string{{"admin", "m1", "/orders", "GET"}, {"auditor", "*", "/audit", "GET"}},
+		[][]string{{"user-u1", "admin", "m1"}, {"user-u1", "auditor", "m2"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"role in domain", []interface{}{"user-u1", "m1", "/orders", "GET"}, true},
+		{"role not in domain", []interface{}{"user-u1", "m2", "/orders", "GET"}, false},
+		{"wildcard domain policy", []interface{}{"user-u1", "m2", "/audit", "GET"}, true},
+		{"wildcard domain needs role", []interface{}{"user-u1", "m1", "/audit", "GET"}, false},
+	})
+}
+
+func TestPresetRBACKeyMatch2(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBACKeyMatch2,
+		[][]string{
+			{"admin", "/users/:id", "*"},
+			{"reader", "/users/:id", "GET"},
+			{"reader", "/files/*", "GET|HEAD"},
+		},
+		[][]string{{"user-a", "admin"}, {"user-r", "reader"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"path param", []interface{}{"user-r", "/users/123", "GET"}, true},
+		{"path param is single segment", []interface{}{"user-r", "/users/123/roles", "GET"}, false},
+		{"method not allowed", []interface{}{"user-r", "/users/123", "DELETE"}, false},
+		{"method wildcard", []interface{}{"user-a", "/users/123", "DELETE"}, true},
+		{"method alternatives", []interface{}{"user-r", "/files/a/b.txt", "HEAD"}, true},
+		{"method alternatives are anchored", []interface{}{"user-r", "/files/a", "GETX"}, false},
+	})
+}
+
+func TestPresetRBACKeyMatch5(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBACKeyMatch5,
+		[][]string{{"reader", "/users/{id}/orders/{orderId}", "GET"}},
+		[][]string{{"user-r", "reader"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"path params", []interface{}{"user-r", "/users/1/orders/2", "GET"}, true},
+		{"query ignored", []interface{}{"user-r", "/users/1/orders/2?expand=items", "GET"}, true},
+		{"missing segment", []interface{}{"user-r", "/users/1/orders", "GET"}, false},
+	})
+}
+
+func TestPresetRBACRegex(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBACRegex,
+		[][]string{{"reader", `/users/\d+|/users`, "GET"}},
+		[][]string{{"user-r", "reader"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"numeric id", []interface{}{"user-r", "/users/42", "GET"}, true},
+		{"alternative", []interface{}{"user-r", "/users", "GET"}, true},
+		{"regex is anchored", []interface{}{"user-r", "/users/42/roles", "GET"}, false},
+		{"non numeric id", []interface{}{"user-r", "/users/abc", "GET"}, false},
+	})
+}
+
+func TestPresetABAC(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetABAC,
+		[][]string{
+			{`r.sub.TenantId == "m1" && r.sub.HasRole("manager")`, "/orders/:id", "GET|PUT"},
+			{`r.sub.UserType == "service" && r.sub.HasScope("invoices:write")`, "/invoices/*", "*"},
+		}, nil)
+	manager := NewAttributes(&jwt.CustomClaims{UserId: "u1", MerchantNo: "m1", AuthorityId: "staff, manager"})
+	otherMerchant := NewAttributes(&jwt.CustomClaims{UserId: "u2", MerchantNo: "m2", AuthorityId: "manager"})
+	service := NewAttributes(&jwt.ServiceClaims{ClientId: "billing", Scopes: []string{"invoices:write"}})
+	readOnlyService := NewAttributes(&jwt.ServiceClaims{ClientId: "report", Scopes: []string{"invoices:read"}})
+	assert.Equal(t, Attributes{UserId: "u1", TenantId: "m1", Roles: []string{"staff", "manager"}}, manager)
+	assert.Equal(t, jwt.ServiceUserType, service.UserType)
+	assertCases(t, enforcer, []enforceCase{
+		{"attributes match", []interface{}{manager, "/orders/1", "PUT"}, true},
+		{"method not allowed", []interface{}{manager, "/orders/1", "DELETE"}, false},
+		{"other merchant", []interface{}{otherMerchant, "/orders/1", "GET"}, false},
+		{"service scope", []interface{}{service, "/invoices/2026/01", "POST"}, true},
+		{"service without scope", []interface{}{readOnlyService, "/invoices/2026/01", "POST"}, false},
+		{"user on service rule", []interface{}{manager, "/invoices/2026/01", "GET"}, false},
+	})
+}
+
+func TestPresetRBACDeny(t *testing.T) {
+	enforcer := newPresetEnforcer(t, PresetRBACDeny,
+		[][]string{
+			{"admin", "/users/*", "*", "allow"},
+			{"user-a", "/users/:id/password", "*", "deny"},
+			{"intern", "/users/:id", "GET", "allow"},
+		},
+		[][]string{{"user-a", "admin"}, {"user-b", "admin"}, {"user-b", "intern"}})
+	assertCases(t, enforcer, []enforceCase{
+		{"allowed by role", []interface{}{"user-a", "/users/1", "DELETE"}, true},
+		{"deny overrides role allow", []interface{}{"user-a", "/users/1/password", "PUT"}, false},
+		{"deny is per subject", []interface{}{"user-b", "/users/1/password", "PUT"}, true},
+		{"no matching policy", []interface{}{"user-c", "/users/1", "GET"}, false},
+	})
+}
@@ -10,42 +10,193 @@
  */
 package casbin
 
-import "github.com/kamalyes/go-core/pkg/global"
+import (
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/kamalyes/go-core/pkg/jwt"
+)
 
+// CasbinService 策略管理与权限认证，主体命名见 UserSubject 与 RoleSubject
 type CasbinService struct{}
 
 var CasbinServiceApp = new(CasbinService)
 
 // AddPermissionForUserInDomain
 /**
- *  @Description: 为用户或角色在域内添加权限
+ *  @Description: 为用户或角色在域内添加权限，subject 按原样写入策略，不做前缀转换
  *  @receiver casbinApi
- *  @param user 用户或角色
+ *  @param subject 主体，用户使用 UserSubject(userId)，角色使用 RoleSubject(role)
  *  @param domain 域
  *  @param permission url
  *  @param method 方法
  *  @return err
  */
-func (s CasbinService) AddPermissionForUserInDomain(user, domain, permission, method string) (err error) {
-	_, err = global.CSBEF.AddPermissionForUser(user, domain, permission, method)
+func (s CasbinService) AddPermissionForUserInDomain(subject, domain, permission, method string) (err error) {
+	if err = checkSubject(subject); err != nil {
+		return
+	}
+	defer InvalidateDecisionCache()
+	_, err = global.CSBEF.AddPolicy(subject, domain, permission, method)
+	return
+}
+
+// DeletePermissionForUserInDomain
+/**
+ *  @Description: 删除用户或角色在域内的权限，subject 按原样匹配策略，不做前缀转换
+ *  @receiver casbinApi
+ *  @param subject 主体，用户使用 UserSubject(userId)，角色使用 RoleSubject(role)
+ *  @param domain 域
+ *  @param permission url
+ *  @param method 方法
+ *  @return err
+ */
+func (s CasbinService) DeletePermissionForUserInDomain(subject, domain, permission, method string) (err error) {
+	defer InvalidateDecisionCache()
+	_, err = global.CSBEF.RemovePolicy(subject, domain, permission, method)
+	return
+}
+
+// AddRoleForUserInDomain
+/**
+ *  @Description: 在域内为用户分配角色，角色名不能以用户或机器身份主体前缀开头
+ *  @receiver casbinApi
+ *  @param user 用户id
+ *  @param role 角色
+ *  @param domain 域
+ *  @return err
+ */
+func (s CasbinService) AddRoleForUserInDomain(user, role, domain string) (err error) {
+	if err = checkRoleName(role); err != nil {
+		return
+	}
+	defer InvalidateDecisionCache()
+	_, err = global.CSBEF.AddRoleForUserInDomain(UserSubject(user), role, domain)
+	return
+}
+
+// DeleteRoleForUserInDomain
+/**
+ *  @Description: 在域内取消用户的角色
+ *  @receiver casbinApi
+ *  @param user 用户id
+ *  @param role 角色
+ *  @param domain 域
+ *  @return err
+ */
+func (s CasbinService) DeleteRoleForUserInDomain(user, role, domain string) (err error) {
+	defer InvalidateDecisionCache()
+	_, err = global.CSBEF.DeleteRoleForUserInDomain(UserSubject(user), role, domain)
 	return
 }
 
+// DeleteRolesForUserInDomain
+/**
+ *  @Description: 取消用户在域内的全部角色
+ *  @receiver casbinApi
+ *  @param user 用户id
+ *  @param domain 域
+ *  @return err
+ */
+func (s CasbinService) DeleteRolesForUserInDomain(user, domain string) (err error) {
+	defer InvalidateDecisionCache()
+	_, err = global.CSBEF.DeleteRolesForUserInDomain(UserSubject(user), domain)
+	return
+}
+
+// GetRolesForUserInDomain
+/**
+ *  @Description: 获取用户在域内的角色
+ *  @receiver casbinApi
+ *  @param user 用户id
+ *  @param domain 域
+ *  @return roles 角色
+ */
+func (s CasbinService) GetRolesForUserInDomain(user, domain string) (roles []string) {
+	return global.CSBEF.GetRolesForUserInDomain(UserSubject(user), domain)
+}
+
+// GetUsersForRoleInDomain
+/**
+ *  @Description: 获取域内拥有角色的用户
+ *  @receiver casbinApi
+ *  @param role 角色
+ *  @param domain 域
+ *  @return users 用户id
+ */
+func (s CasbinService) GetUsersForRoleInDomain(role, domain string) (users []string) {
+	for _, subject := range global.CSBEF.GetUsersForRoleInDomain(role, domain) {
+		if user, ok := UserIdFromSubject(subject); ok {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
 // PermissionVerify
 /**
- *  @Description: 权限认证
+ *  @Description: 权限认证，user 按 UserSubject 转换为用户主体，启用 EnableDecisionCache 后使用缓存的结果
  *  @receiver casbinApi
- *  @param user 用户或角色
+ *  @param user 用户id
  *  @param permission url
  *  @param method 方法
  *  @return ok 是否通过
  */
 func (s CasbinService) PermissionVerify(user, permission, method string) (ok bool) {
-	user = "user-" + user
-	success, _ := global.CSBEF.Enforce(user, permission, method)
-	if success {
-		return true
-	} else {
-		return false
-	}
+	return s.PermissionVerifySubject(UserSubject(user), permission, method)
+}
+
+// PermissionVerifySubject
+/**
+ *  @Description: 按主体权限认证，机器身份使用 ServiceSubject，启用 EnableDecisionCache 后使用缓存的结果
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 ServiceSubject 生成
+ *  @param permission url
+ *  @param method 方法
+ *  @return ok 是否通过
+ */
+func (s CasbinService) PermissionVerifySubject(subject, permission, method string) (ok bool) {
+	success, _ := enforce(subject, permission, method)
+	return success
+}
+
+// PermissionVerifyInDomain
+/**
+ *  @Description: 域内权限认证，需要使用 CasbinWithDomains 创建的执行者，启用 EnableDecisionCache 后使用缓存的结果
+ *  @receiver casbinApi
+ *  @param user 用户id
+ *  @param domain 域
+ *  @param permission url
+ *  @param method 方法
+ *  @return ok 是否通过
+ */
+func (s CasbinService) PermissionVerifyInDomain(user, domain, permission, method string) (ok bool) {
+	return s.PermissionVerifySubjectInDomain(UserSubject(user), domain, permission, method)
+}
+
+// PermissionVerifySubjectInDomain
+/**
+ *  @Description: 按主体的域内权限认证，需要使用 CasbinWithDomains 创建的执行者，启用 EnableDecisionCache 后使用缓存的结果
+ *  @receiver casbinApi
+ *  @param subject 主体，使用 UserSubject 或 ServiceSubject 生成
+ *  @param domain 域
+ *  @param permission url
+ *  @param method 方法
+ *  @return ok 是否通过
+ */
+func (s CasbinService) PermissionVerifySubjectInDomain(subject, domain, permission, method string) (ok bool) {
+	success, _ := enforce(subject, domain, permission, method)
+	return success
+}
+
+// PermissionVerifyWithClaims
+/**
+ *  @Description: 基于 claims 属性的权限认证，需要使用 PresetABAC 创建的执行者
+ *  @receiver casbinApi
+ *  @param claims 当前用户，转换为 Attributes 后作为 r.sub
+ *  @param permission url
+ *  @param method 方法
+ *  @return ok 是否通过
+ */
+func (s CasbinService) PermissionVerifyWithClaims(claims jwt.Claims, permission, method string) (ok bool) {
+	success, _ := global.CSBEF.Enforce(NewAttributes(claims), permission, method)
+	return success
 }
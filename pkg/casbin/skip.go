@@ -0,0 +1,316 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 20:41:07
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 20:41:07
+ * @FilePath: \go-core\pkg\casbin\skip.go
+ * @Description: 跳过权限校验的规则，支持精确、前缀、glob、正则匹配与按请求方法限定，可在运行时修改
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kamalyes/go-core/pkg/global"
+)
+
+// 跳过规则的匹配方式
+const (
+	SkipMatchExact  = "exact"  // 路径完全相等
+	SkipMatchPrefix = "prefix" // 路径以 Pattern 开头
+	SkipMatchGlob   = "glob"   // * 匹配单级路径，** 匹配任意多级路径，? 匹配单个字符
+	SkipMatchRegex  = "regex"  // 正则表达式，需自行添加 ^ 与 $
+)
+
+// SkipRule 跳过权限校验的规则
+//
+//	casbin-skip-rules:
+//	  - pattern: /swagger/
+//	    match: prefix
+//	  - pattern: /api/*/public/**
+//	    match: glob
+//	    methods: [GET]
+type SkipRule struct {
+	Pattern string   `mapstructure:"pattern" json:"pattern" yaml:"pattern"` // 匹配的路径
+	Match   string   `mapstructure:"match"   json:"match"   yaml:"match"`   // 匹配方式，默认 exact
+	Methods []string `mapstructure:"methods" json:"methods" yaml:"methods"` // 限定的请求方法，为空时匹配全部方法
+
+	regexp *regexp.Regexp // glob 与 regex 编译后的表达式
+}
+
+// String 用于日志输出
+func (r SkipRule) String() string {
+	if len(r.Methods) == 0 {
+		return r.Match + ":" + r.Pattern
+	}
+	return r.Match + ":" + strings.Join(r.Methods, ",") + " " + r.Pattern
+}
+
+// compile 校验规则并编译 glob 与正则
+func (r *SkipRule) compile() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("跳过规则的 pattern 不能为空")
+	}
+	if r.Match == "" {
+		r.Match = SkipMatchExact
+	}
+	r.Match = strings.ToLower(r.Match)
+	for i, method := range r.Methods {
+		r.Methods[i] = strings.ToUpper(method)
+	}
+	var err error
+	switch r.Match {
+	case SkipMatchExact, SkipMatchPrefix:
+		r.regexp = nil
+	case SkipMatchGlob:
+		r.regexp, err = regexp.Compile(globToRegexp(r.Pattern))
+	case SkipMatchRegex:
+		r.regexp, err = regexp.Compile(r.Pattern)
+	default:
+		return fmt.Errorf("不支持的跳过规则匹配方式：%s", r.Match)
+	}
+	if err != nil {
+		return fmt.Errorf("跳过规则 %s 编译失败：%w", r.Pattern, err)
+	}
+	return nil
+}
+
+// matches 请求是否命中规则
+func (r *SkipRule) matches(method, path string) bool {
+	if len(r.Methods) > 0 && !containsMethod(r.Methods, method) {
+		return false
+	}
+	switch r.Match {
+	case SkipMatchExact:
+		return path == r.Pattern
+	case SkipMatchPrefix:
+		return strings.HasPrefix(path, r.Pattern)
+	default:
+		return r.regexp != nil && r.regexp.MatchString(path)
+	}
+}
+
+// containsMethod 请求方法是否在列表中，* 匹配全部方法
+func containsMethod(methods []string, method string) bool {
+	for _, item := range methods {
+		if item == "*" || strings.EqualFold(item, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp 将 glob 转换为正则，**/ 可匹配零级或多级路径
+func globToRegexp(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					builder.WriteString("(.*/)?")
+				} else {
+					builder.WriteString(".*")
+				}
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// SkipRulesConfigKey 跳过规则在配置文件中的键
+const SkipRulesConfigKey = "casbin-skip-rules"
+
+// DefaultSkipRules 默认跳过规则：swagger 文档与根路径下的 login、captcha、health 接口
+// 只做锚定匹配，其他层级的登录、健康检查等接口需通过配置 casbin-skip-rules 或 AddSkipRules 显式放行
+func DefaultSkipRules() []SkipRule {
+	return []SkipRule{
+		{Pattern: "/swagger/", Match: SkipMatchPrefix},
+		{Pattern: "/login", Match: SkipMatchExact},
+		{Pattern: "/captcha", Match: SkipMatchExact},
+		{Pattern: "/health", Match: SkipMatchExact},
+	}
+}
+
+// skipRules 当前生效的跳过规则，首次使用时从配置 casbin-skip-rules 加载
+var skipRules = struct {
+	sync.RWMutex
+	loaded bool
+	rules  []SkipRule
+}{
+	rules: mustCompileSkipRules(DefaultSkipRules()),
+}
+
+// loadSkipRulesConfig 读取配置 casbin-skip-rules，未配置时返回 false
+func loadSkipRulesConfig() ([]SkipRule, bool, error) {
+	if global.VP == nil || !global.VP.IsSet(SkipRulesConfigKey) {
+		return nil, false, nil
+	}
+	var rules []SkipRule
+	if err := global.VP.UnmarshalKey(SkipRulesConfigKey, &rules); err != nil {
+		return nil, false, err
+	}
+	compiled, err := compileSkipRules(rules)
+	if err != nil {
+		return nil, false, err
+	}
+	return compiled, true, nil
+}
+
+// LoadSkipRules 从配置 casbin-skip-rules 加载跳过规则并替换当前规则，未配置时恢复默认规则
+// 配置无效时返回错误且不做修改，配置文件变更后可在 global.VP.OnConfigChange 中调用
+func LoadSkipRules() error {
+	rules, found, err := loadSkipRulesConfig()
+	if err != nil {
+		return err
+	}
+	if !found {
+		rules = mustCompileSkipRules(DefaultSkipRules())
+	}
+	skipRules.Lock()
+	defer skipRules.Unlock()
+	skipRules.rules = rules
+	skipRules.loaded = true
+	return nil
+}
+
+// ensureSkipRulesLoaded 首次使用时加载配置，需持有写锁，配置无效时记录错误并保留默认规则
+func ensureSkipRulesLoaded() {
+	if skipRules.loaded {
+		return
+	}
+	skipRules.loaded = true
+	rules, found, err := loadSkipRulesConfig()
+	if err != nil {
+		message := "加载 casbin 跳过规则（" + SkipRulesConfigKey + "）失败，使用默认规则：" + err.Error()
+		if global.LOGGER != nil {
+			global.LOGGER.Error(message)
+		} else {
+			log.Println(message)
+		}
+		return
+	}
+	if found {
+		skipRules.rules = rules
+	}
+}
+
+// currentSkipRules 获取当前生效的规则，首次使用时加载配置
+func currentSkipRules() []SkipRule {
+	skipRules.RLock()
+	if skipRules.loaded {
+		defer skipRules.RUnlock()
+		return skipRules.rules
+	}
+	skipRules.RUnlock()
+
+	skipRules.Lock()
+	defer skipRules.Unlock()
+	ensureSkipRulesLoaded()
+	return skipRules.rules
+}
+
+// compileSkipRules 复制并编译规则，任一规则无效时返回错误
+func compileSkipRules(rules []SkipRule) ([]SkipRule, error) {
+	compiled := make([]SkipRule, len(rules))
+	for i, rule := range rules {
+		rule.Methods = append([]string(nil), rule.Methods...)
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		compiled[i] = rule
+	}
+	return compiled, nil
+}
+
+// mustCompileSkipRules 编译内置规则
+func mustCompileSkipRules(rules []SkipRule) []SkipRule {
+	compiled, err := compileSkipRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// SetSkipRules 替换全部跳过规则，任一规则无效时返回错误且不做修改，传入空切片表示不跳过任何路径
+// 设置后不再从配置加载，需要时调用 LoadSkipRules
+func SetSkipRules(rules []SkipRule) error {
+	compiled, err := compileSkipRules(rules)
+	if err != nil {
+		return err
+	}
+	skipRules.Lock()
+	defer skipRules.Unlock()
+	skipRules.rules = compiled
+	skipRules.loaded = true
+	return nil
+}
+
+// AddSkipRules 在当前规则（含配置中的规则）之后追加跳过规则
+func AddSkipRules(rules ...SkipRule) error {
+	compiled, err := compileSkipRules(rules)
+	if err != nil {
+		return err
+	}
+	skipRules.Lock()
+	defer skipRules.Unlock()
+	ensureSkipRulesLoaded()
+	skipRules.rules = append(append([]SkipRule(nil), skipRules.rules...), compiled...)
+	return nil
+}
+
+// RemoveSkipRule 移除匹配方式与 pattern 相同的规则，返回是否有规则被移除
+func RemoveSkipRule(match, pattern string) bool {
+	if match == "" {
+		match = SkipMatchExact
+	}
+	skipRules.Lock()
+	defer skipRules.Unlock()
+	ensureSkipRulesLoaded()
+	rules := make([]SkipRule, 0, len(skipRules.rules))
+	for _, rule := range skipRules.rules {
+		if rule.Match == strings.ToLower(match) && rule.Pattern == pattern {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	removed := len(rules) != len(skipRules.rules)
+	skipRules.rules = rules
+	return removed
+}
+
+// GetSkipRules 获取当前生效的跳过规则
+func GetSkipRules() []SkipRule {
+	return append([]SkipRule(nil), currentSkipRules()...)
+}
+
+// ShouldSkip 请求是否跳过权限校验，命中时以 debug 级别记录命中的规则
+func ShouldSkip(method, path string) bool {
+	rules := currentSkipRules()
+	for i := range rules {
+		rule := &rules[i]
+		if rule.matches(method, path) {
+			if global.LOGGER != nil {
+				global.LOGGER.DebugKV("casbin 跳过权限校验", "method", method, "path", path, "rule", rule.String())
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 12:05:19
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 12:05:19
+ * @FilePath: \go-core\pkg\casbin\skip_test.go
+ * @Description: 跳过规则的默认值与配置加载测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useSkipRules 使用独立的配置与默认规则，测试结束后恢复
+func useSkipRules(t *testing.T) *viper.Viper {
+	originalVP := global.VP
+	skipRules.Lock()
+	originalLoaded, originalRules := skipRules.loaded, skipRules.rules
+	skipRules.loaded, skipRules.rules = false, mustCompileSkipRules(DefaultSkipRules())
+	skipRules.Unlock()
+
+	global.VP = viper.New()
+	t.Cleanup(func() {
+		global.VP = originalVP
+		skipRules.Lock()
+		skipRules.loaded, skipRules.rules = originalLoaded, originalRules
+		skipRules.Unlock()
+	})
+	return global.VP
+}
+
+func TestDefaultSkipRules(t *testing.T) {
+	useSkipRules(t)
+
+	for _, path := range []string{"/login", "/captcha", "/health", "/swagger/index.html"} {
+		assert.True(t, ShouldSkip(http.MethodGet, path), path)
+	}
+	// 默认规则只匹配根路径，其他层级需要显式放行
+	for _, path := range []string{"/api/admin/login", "/api/users/1/health", "/login/../admin", "/api/admin/delete-login-history"} {
+		assert.False(t, ShouldSkip(http.MethodPost, path), path)
+	}
+}
+
+func TestLoadSkipRules(t *testing.T) {
+	vp := useSkipRules(t)
+	vp.Set(SkipRulesConfigKey, []map[string]interface{}{
+		{"pattern": "/api/v1/login", "methods": []string{"post"}},
+		{"pattern": "/api/*/public/**", "match": "glob"},
+	})
+
+	// 首次使用时从配置加载
+	assert.True(t, ShouldSkip(http.MethodPost, "/api/v1/login"))
+	assert.False(t, ShouldSkip(http.MethodGet, "/api/v1/login"))
+	assert.True(t, ShouldSkip(http.MethodGet, "/api/v2/public/docs/a"))
+	assert.False(t, ShouldSkip(http.MethodGet, "/login"))
+
+	require.NoError(t, AddSkipRules(SkipRule{Pattern: "/metrics"}))
+	assert.Len(t, GetSkipRules(), 3)
+
+	// 配置无效时返回错误且不做修改
+	vp.Set(SkipRulesConfigKey, []map[string]interface{}{{"pattern": "/a", "match": "fuzzy"}})
+	assert.Error(t, LoadSkipRules())
+	assert.Len(t, GetSkipRules(), 3)
+
+	// 配置为空列表时不跳过任何路径，删除配置后恢复默认规则
+	vp.Set(SkipRulesConfigKey, []map[string]interface{}{})
+	require.NoError(t, LoadSkipRules())
+	assert.Empty(t, GetSkipRules())
+	assert.False(t, ShouldSkip(http.MethodGet, "/login"))
+
+	global.VP = viper.New()
+	require.NoError(t, LoadSkipRules())
+	assert.True(t, ShouldSkip(http.MethodGet, "/login"))
+}
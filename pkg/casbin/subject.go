@@ -0,0 +1,124 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 22:52:14
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 22:52:14
+ * @FilePath: \go-core\pkg\casbin\subject.go
+ * @Description: 策略主体的命名约定，用户与角色通过前缀区分
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+)
+
+var (
+	userSubjectPrefix    = "user-" // 用户主体前缀，与 CasbinHandler 一致
+	serviceSubjectPrefix = "svc-"  // 机器身份主体前缀，客户端id 与用户id 相同时互不影响
+	roleSubjectPrefix    = ""      // 角色主体前缀，默认不加前缀以兼容已有策略
+)
+
+// SetUserSubjectPrefix 设置用户主体前缀，需在写入策略前设置
+func SetUserSubjectPrefix(prefix string) {
+	userSubjectPrefix = prefix
+}
+
+// GetUserSubjectPrefix 获取用户主体前缀
+func GetUserSubjectPrefix() string {
+	return userSubjectPrefix
+}
+
+// SetServiceSubjectPrefix 设置机器身份主体前缀，需在写入策略前设置
+func SetServiceSubjectPrefix(prefix string) {
+	serviceSubjectPrefix = prefix
+}
+
+// GetServiceSubjectPrefix 获取机器身份主体前缀
+func GetServiceSubjectPrefix() string {
+	return serviceSubjectPrefix
+}
+
+// SetRoleSubjectPrefix 设置角色主体前缀，如 role-，需在写入策略前设置
+func SetRoleSubjectPrefix(prefix string) {
+	roleSubjectPrefix = prefix
+}
+
+// GetRoleSubjectPrefix 获取角色主体前缀
+func GetRoleSubjectPrefix() string {
+	return roleSubjectPrefix
+}
+
+// UserSubject 用户在策略中的主体，如 user-1001
+func UserSubject(userId string) string {
+	return userSubjectPrefix + userId
+}
+
+// ServiceSubject 机器身份（API Key、服务 Token）在策略中的主体，如 svc-billing
+func ServiceSubject(clientId string) string {
+	return serviceSubjectPrefix + clientId
+}
+
+// ClaimsSubject 当前身份在策略中的主体，机器身份为 ServiceSubject，其余为 UserSubject
+func ClaimsSubject(claims jwt.Claims) string {
+	if _, ok := claims.(*jwt.ServiceClaims); ok {
+		return ServiceSubject(claims.GetUserId())
+	}
+	return UserSubject(claims.GetUserId())
+}
+
+// RoleSubject 角色在策略中的主体，默认与角色名相同
+func RoleSubject(role string) string {
+	return roleSubjectPrefix + role
+}
+
+// IsUserSubject 主体是否为用户
+func IsUserSubject(subject string) bool {
+	return strings.HasPrefix(subject, userSubjectPrefix)
+}
+
+// UserIdFromSubject 从用户主体中取出用户id，不是用户主体时返回 false
+func UserIdFromSubject(subject string) (string, bool) {
+	return strings.CutPrefix(subject, userSubjectPrefix)
+}
+
+// IsServiceSubject 主体是否为机器身份
+func IsServiceSubject(subject string) bool {
+	return strings.HasPrefix(subject, serviceSubjectPrefix)
+}
+
+// RoleFromSubject 从角色主体中取出角色名，用户主体、机器身份主体或前缀不符时返回 false
+func RoleFromSubject(subject string) (string, bool) {
+	if IsUserSubject(subject) || IsServiceSubject(subject) {
+		return "", false
+	}
+	return strings.CutPrefix(subject, roleSubjectPrefix)
+}
+
+// checkRoleName 角色名加上角色前缀后不能被识别为用户或机器身份主体
+// 否则角色不出现在 GetRoles、GetImplicitRolesForUser 中，并与同名的用户或机器身份主体冲突
+func checkRoleName(role string) error {
+	if role == "" {
+		return fmt.Errorf("%w：角色名不能为空", PolicyInvalid)
+	}
+	if _, ok := RoleFromSubject(RoleSubject(role)); !ok {
+		return fmt.Errorf("%w：角色名 %s 与用户主体前缀 %s 或机器身份主体前缀 %s 冲突", PolicyInvalid, role, userSubjectPrefix, serviceSubjectPrefix)
+	}
+	return nil
+}
+
+// checkSubject 主体需为用户、机器身份或角色主体，角色主体的角色名需通过 checkRoleName
+func checkSubject(subject string) error {
+	if IsUserSubject(subject) || IsServiceSubject(subject) {
+		return nil
+	}
+	role, ok := RoleFromSubject(subject)
+	if !ok {
+		return fmt.Errorf("%w：主体 %s 不是用户、机器身份或角色主体", PolicyInvalid, subject)
+	}
+	return checkRoleName(role)
+}
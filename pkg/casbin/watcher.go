@@ -0,0 +1,470 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 22:18:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 22:18:36
+ * @FilePath: \go-core\pkg\casbin\watcher.go
+ * @Description: 策略变更监听，通过 Redis 发布订阅或 MQTT 广播策略变更，其他实例增量应用并定期全量加载兜底
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+)
+
+// 策略变更类型
+const (
+	PolicyUpdate         = "update"          // 未知变更，全量加载
+	PolicySave           = "save"            // SavePolicy，全量加载
+	PolicyAdd            = "add"             // AddPolicy / AddPolicies
+	PolicyRemove         = "remove"          // RemovePolicy / RemovePolicies
+	PolicyRemoveFiltered = "remove_filtered" // RemoveFilteredPolicy
+	PolicyUpdateRules    = "update_rules"    // UpdatePolicy / UpdatePolicies
+)
+
+const (
+	DefaultWatcherReload      = 5 * time.Minute         // 默认全量加载间隔
+	DefaultWatcherMQTTQos     = byte(1)                 // MQTT 默认 QoS
+	defaultWatcherChannelName = "casbin_policy_watcher" // 频道名，前缀为 global.GPerFix
+	watcherPublishTimeout     = 5 * time.Second         // 发布超时时间
+)
+
+// PolicyMessage 广播的策略变更
+type PolicyMessage struct {
+	InstanceId  string     `json:"instanceId"`            // 发送方实例id，用于忽略自己发出的消息
+	Method      string     `json:"method"`                // 变更类型
+	Sec         string     `json:"sec,omitempty"`         // p 或 g
+	Ptype       string     `json:"ptype,omitempty"`       // p、g、g2 等
+	Rules       [][]string `json:"rules,omitempty"`       // 新增、删除或更新后的规则
+	OldRules    [][]string `json:"oldRules,omitempty"`    // 更新前的规则
+	FieldIndex  int        `json:"fieldIndex,omitempty"`  // RemoveFilteredPolicy 的起始字段
+	FieldValues []string   `json:"fieldValues,omitempty"` // RemoveFilteredPolicy 的字段值
+}
+
+// PolicyTransport 广播策略变更的通道
+type PolicyTransport interface {
+	// Publish 发布消息
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe 订阅频道，返回取消订阅的函数
+	Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func() error, error)
+}
+
+// RedisTransport 基于 Redis 发布订阅的通道
+type RedisTransport struct {
+	Client redis.UniversalClient // 为空时使用 global.REDIS
+}
+
+// client 获取 Redis 客户端
+func (t *RedisTransport) client() (redis.UniversalClient, error) {
+	if t.Client != nil {
+		return t.Client, nil
+	}
+	if global.REDIS == nil {
+		return nil, errors.New("未初始化 Redis 连接")
+	}
+	return global.REDIS, nil
+}
+
+// Publish 发布消息
+func (t *RedisTransport) Publish(ctx context.Context, channel string, payload []byte) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+	return client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe 订阅频道，断线后由 go-redis 自动重新订阅
+func (t *RedisTransport) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func() error, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+	pubsub := client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return pubsub.Close, nil
+}
+
+// MQTTTransport 基于 MQTT 的通道
+type MQTTTransport struct {
+	Client pahoMqtt.Client // 为空时使用 global.MQTT
+	Qos    byte            // 默认 DefaultWatcherMQTTQos
+}
+
+// client 获取 MQTT 客户端
+func (t *MQTTTransport) client() (pahoMqtt.Client, error) {
+	if t.Client != nil {
+		return t.Client, nil
+	}
+	if global.MQTT == nil || *global.MQTT == nil {
+		return nil, errors.New("未初始化 MQTT 连接")
+	}
+	return *global.MQTT, nil
+}
+
+// qos 获取 QoS
+func (t *MQTTTransport) qos() byte {
+	if t.Qos == 0 {
+		return DefaultWatcherMQTTQos
+	}
+	return t.Qos
+}
+
+// Publish 发布消息
+func (t *MQTTTransport) Publish(ctx context.Context, channel string, payload []byte) error {
+	client, err := t.client()
+	if err != nil {
+		return err
+	}
+	return waitMQTTToken(ctx, client.Publish(channel, t.qos(), false, payload))
+}
+
+// Subscribe 订阅主题
+func (t *MQTTTransport) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func() error, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+	token := client.Subscribe(channel, t.qos(), func(_ pahoMqtt.Client, msg pahoMqtt.Message) {
+		handler(msg.Payload())
+	})
+	if err := waitMQTTToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return waitMQTTToken(context.Background(), client.Unsubscribe(channel))
+	}, nil
+}
+
+// waitMQTTToken 等待 MQTT 操作完成
+func waitMQTTToken(ctx context.Context, token pahoMqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WatcherConfig 策略变更监听配置
+//
+//	casbin-watcher:
+//	  channel: goc_casbin_policy_watcher
+//	  reload-interval: 5m
+type WatcherConfig struct {
+	Channel        string        `mapstructure:"channel"         json:"channel"         yaml:"channel"`         // 频道或主题，默认 global.GPerFix + "casbin_policy_watcher"
+	ReloadInterval time.Duration `mapstructure:"reload-interval" json:"reloadInterval"  yaml:"reload-interval"` // 全量加载间隔，默认 DefaultWatcherReload，负数时关闭
+}
+
+// Watcher 策略变更监听，实现 persist.WatcherEx 与 persist.UpdatableWatcher
+//
+// 本实例修改策略后由 casbin 调用 UpdateFor* 广播变更，其他实例收到后只在内存中增量应用，不再写入存储，
+// 增量应用失败或收到全量变更时重新加载全部策略，另按 ReloadInterval 定期全量加载
+type Watcher struct {
+	Config     WatcherConfig
+	Transport  PolicyTransport
+	InstanceId string
+
+	enforcer    *casbin.SyncedEnforcer
+	callback    func(string)
+	unsubscribe func() error
+	done        chan struct{}
+	closeOnce   sync.Once
+	mu          sync.RWMutex
+}
+
+var (
+	_ persist.WatcherEx        = (*Watcher)(nil)
+	_ persist.UpdatableWatcher = (*Watcher)(nil)
+)
+
+// NewRedisWatcher 使用 global.REDIS 发布订阅监听策略变更
+func NewRedisWatcher(enforcer *casbin.SyncedEnforcer, configs ...*WatcherConfig) (*Watcher, error) {
+	return NewWatcher(enforcer, &RedisTransport{}, configs...)
+}
+
+// NewMQTTWatcher 使用 global.MQTT 监听策略变更
+func NewMQTTWatcher(enforcer *casbin.SyncedEnforcer, configs ...*WatcherConfig) (*Watcher, error) {
+	return NewWatcher(enforcer, &MQTTTransport{}, configs...)
+}
+
+// NewWatcher
+/**
+ *  @Description: 创建策略变更监听，订阅频道、设置为执行者的 watcher 并开始定期全量加载
+ *  @param enforcer casbin执行者
+ *  @param transport 广播通道
+ *  @param configs 配置，未传时使用默认配置
+ *  @return Watcher 策略变更监听，停止时调用 Close
+ */
+func NewWatcher(enforcer *casbin.SyncedEnforcer, transport PolicyTransport, configs ...*WatcherConfig) (*Watcher, error) {
+	if enforcer == nil {
+		return nil, errors.New("casbin 执行者不能为空")
+	}
+	config := WatcherConfig{}
+	if len(configs) > 0 && configs[0] != nil {
+		config = *configs[0]
+	}
+	if config.Channel == "" {
+		config.Channel = global.GPerFix + defaultWatcherChannelName
+	}
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = DefaultWatcherReload
+	}
+	instanceId, err := newInstanceId()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		Config:     config,
+		Transport:  transport,
+		InstanceId: instanceId,
+		enforcer:   enforcer,
+		done:       make(chan struct{}),
+	}
+	w.unsubscribe, err = transport.Subscribe(context.Background(), config.Channel, w.receive)
+	if err != nil {
+		return nil, fmt.Errorf("订阅 casbin 策略变更失败：%w", err)
+	}
+	if err := enforcer.SetWatcher(w); err != nil {
+		_ = w.unsubscribe()
+		return nil, err
+	}
+	if config.ReloadInterval > 0 {
+		go w.reloadPeriodically(config.ReloadInterval)
+	}
+	return w, nil
+}
+
+// newInstanceId 生成实例id
+func newInstanceId() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SetUpdateCallback 设置全量加载的回调，默认调用执行者的 LoadPolicy
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update 广播未知变更，其他实例全量加载
+func (w *Watcher) Update() error {
+	return w.publish(&PolicyMessage{Method: PolicyUpdate})
+}
+
+// UpdateForAddPolicy 广播新增规则
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(&PolicyMessage{Method: PolicyAdd, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemovePolicy 广播删除规则
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(&PolicyMessage{Method: PolicyRemove, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemoveFilteredPolicy 广播按字段删除规则
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(&PolicyMessage{Method: PolicyRemoveFiltered, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+// UpdateForSavePolicy 广播保存全部策略，其他实例全量加载
+func (w *Watcher) UpdateForSavePolicy(model.Model) error {
+	return w.publish(&PolicyMessage{Method: PolicySave})
+}
+
+// UpdateForAddPolicies 广播批量新增规则
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&PolicyMessage{Method: PolicyAdd, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies 广播批量删除规则
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&PolicyMessage{Method: PolicyRemove, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForUpdatePolicy 广播更新规则
+func (w *Watcher) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return w.publish(&PolicyMessage{Method: PolicyUpdateRules, Sec: sec, Ptype: ptype, OldRules: [][]string{oldRule}, Rules: [][]string{newRule}})
+}
+
+// UpdateForUpdatePolicies 广播批量更新规则
+func (w *Watcher) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return w.publish(&PolicyMessage{Method: PolicyUpdateRules, Sec: sec, Ptype: ptype, OldRules: oldRules, Rules: newRules})
+}
+
+// Close 取消订阅并停止定期全量加载
+func (w *Watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.unsubscribe != nil {
+			if err := w.unsubscribe(); err != nil && global.LOGGER != nil {
+				global.LOGGER.WithError(err).ErrorMsg("取消订阅 casbin 策略变更失败")
+			}
+		}
+	})
+}
+
+// publish 发布变更，由 casbin 在修改策略后调用
+func (w *Watcher) publish(msg *PolicyMessage) error {
+	InvalidateDecisionCache()
+	msg.InstanceId = w.InstanceId
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), watcherPublishTimeout)
+	defer cancel()
+	if err := w.Transport.Publish(ctx, w.Config.Channel, payload); err != nil {
+		return fmt.Errorf("广播 casbin 策略变更失败：%w", err)
+	}
+	return nil
+}
+
+// receive 处理收到的消息，忽略本实例发出的消息
+func (w *Watcher) receive(payload []byte) {
+	var msg PolicyMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		if global.LOGGER != nil {
+			global.LOGGER.WithError(err).ErrorMsg("解析 casbin 策略变更失败")
+		}
+		w.reload("消息无法解析")
+		return
+	}
+	if msg.InstanceId == w.InstanceId {
+		return
+	}
+	if global.LOGGER != nil {
+		global.LOGGER.DebugKV("收到 casbin 策略变更", "method", msg.Method, "ptype", msg.Ptype, "from", msg.InstanceId)
+	}
+	defer InvalidateDecisionCache()
+	if err := w.apply(&msg); err != nil {
+		if global.LOGGER != nil {
+			global.LOGGER.WithError(err).ErrorMsg("增量应用 casbin 策略变更失败，改为全量加载")
+		}
+		w.reload(msg.Method)
+	}
+}
+
+// apply 增量应用变更，只修改内存中的策略，变更已由发送方写入存储
+func (w *Watcher) apply(msg *PolicyMessage) error {
+	rules := msg.Rules
+	if msg.Method == PolicyAdd {
+		// 按域加载的执行者忽略其他域的策略
+		if filter := GetDomainFilter(w.enforcer); filter != nil {
+			fields := domainFields(w.enforcer.GetModel())
+			rules = rules[:0:0]
+			for _, rule := range msg.Rules {
+				if filter.match(fields, msg.Ptype, rule) {
+					rules = append(rules, rule)
+				}
+			}
+			if len(rules) == 0 {
+				return nil
+			}
+		}
+	}
+
+	var err error
+	switch msg.Method {
+	case PolicyAdd:
+		err = w.withoutPersist(func(e *casbin.Enforcer) error {
+			_, err := e.SelfAddPoliciesEx(msg.Sec, msg.Ptype, rules)
+			return err
+		})
+	case PolicyRemove:
+		err = w.withoutPersist(func(e *casbin.Enforcer) error {
+			_, err := e.SelfRemovePolicies(msg.Sec, msg.Ptype, msg.Rules)
+			return err
+		})
+	case PolicyRemoveFiltered:
+		err = w.withoutPersist(func(e *casbin.Enforcer) error {
+			_, err := e.SelfRemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+			return err
+		})
+	case PolicyUpdateRules:
+		err = w.withoutPersist(func(e *casbin.Enforcer) error {
+			_, err := e.SelfUpdatePolicies(msg.Sec, msg.Ptype, msg.OldRules, msg.Rules)
+			return err
+		})
+	default:
+		w.reload(msg.Method)
+	}
+	return err
+}
+
+// withoutPersist 持有执行者的写锁并暂时摘下适配器后执行 fn，开启自动保存时 Self* 方法不会重复写入存储
+// 本实例的策略修改同样需要该锁，摘下适配器期间不会有其他写入
+func (w *Watcher) withoutPersist(fn func(e *casbin.Enforcer) error) error {
+	lock := w.enforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+	e := w.enforcer.Enforcer
+	adapter := e.GetAdapter()
+	e.SetAdapter(nil)
+	defer e.SetAdapter(adapter)
+	return fn(e)
+}
+
+// reload 全量加载，设置了回调时调用回调，按域加载的执行者只加载原有的域
+func (w *Watcher) reload(reason string) {
+	defer InvalidateDecisionCache()
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(reason)
+		return
+	}
+	if err := ReloadPolicy(w.enforcer); err != nil {
+		if global.LOGGER != nil {
+			global.LOGGER.WithError(err).ErrorMsg("全量加载 casbin 策略失败")
+		}
+		return
+	}
+	if global.LOGGER != nil {
+		global.LOGGER.DebugKV("全量加载 casbin 策略", "reason", reason)
+	}
+}
+
+// reloadPeriodically 定期全量加载，弥补丢失的消息
+func (w *Watcher) reloadPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.reload("定期全量加载")
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 22:18:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 22:18:36
+ * @FilePath: \go-core\pkg\casbin\watcher_test.go
+ * @Description: 策略变更监听测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package casbin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	gormAdapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryTransport 进程内的广播通道，异步投递给全部订阅者
+type memoryTransport struct {
+	mu       sync.Mutex
+	handlers map[int]func([]byte)
+	next     int
+}
+
+func (t *memoryTransport) Publish(_ context.Context, _ string, payload []byte) error {
+	t.mu.Lock()
+	handlers := make([]func([]byte), 0, len(t.handlers))
+	for _, handler := range t.handlers {
+		handlers = append(handlers, handler)
+	}
+	t.mu.Unlock()
+	// casbin 在持有执行者写锁时调用 watcher，异步投递避免同一实例内的死锁
+	go func() {
+		for _, handler := range handlers {
+			handler(payload)
+		}
+	}()
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(_ context.Context, _ string, handler func([]byte)) (func() error, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handlers == nil {
+		t.handlers = map[int]func([]byte){}
+	}
+	id := t.next
+	t.next++
+	t.handlers[id] = handler
+	return func() error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.handlers, id)
+		return nil
+	}, nil
+}
+
+// newWatchedEnforcer 创建使用共享数据库的执行者并挂载 watcher
+func newWatchedEnforcer(t *testing.T, db *gorm.DB, transport PolicyTransport, config *WatcherConfig) (*casbin.SyncedEnforcer, *Watcher) {
+	m, err := model.NewModelFromString(DefaultModel)
+	require.NoError(t, err)
+	adapter, err := gormAdapter.NewAdapterByDB(db)
+	require.NoError(t, err)
+	enforcer, err := casbin.NewSyncedEnforcer(m, adapter)
+	require.NoError(t, err)
+	watcher, err := NewWatcher(enforcer, transport, config)
+	require.NoError(t, err)
+	t.Cleanup(watcher.Close)
+	return enforcer, watcher
+}
+
+// openWatcherDB 打开多个执行者共用的内存数据库，测试结束后关闭以清空数据
+func openWatcherDB(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+// eventually 等待另一个实例应用变更
+func eventually(t *testing.T, enforcer *casbin.SyncedEnforcer, want bool, rvals ...interface{}) {
+	assert.Eventually(t, func() bool {
+		ok, err := enforcer.Enforce(rvals...)
+		return err == nil && ok == want
+	}, 2*time.Second, 10*time.Millisecond, "%v", rvals)
+}
+
+func TestWatcher(t *testing.T) {
+	db := openWatcherDB(t, "casbin_watcher")
+	transport := &memoryTransport{}
+	config := &WatcherConfig{ReloadInterval: -1}
+	a, watcherA := newWatchedEnforcer(t, db, transport, config)
+	b, watcherB := newWatchedEnforcer(t, db, transport, config)
+
+	// 增量应用不写入存储，共用数据库时不会因记录已存在而退回全量加载
+	var reloads atomic.Int32
+	countReloads := func(string) { reloads.Add(1) }
+	require.NoError(t, watcherA.SetUpdateCallback(countReloads))
+	require.NoError(t, watcherB.SetUpdateCallback(countReloads))
+	storedRules := func() int64 {
+		var count int64
+		require.NoError(t, db.Table("casbin_rule").Count(&count).Error)
+		return count
+	}
+
+	_, err := a.AddPolicy("admin", "/users", "GET")
+	require.NoError(t, err)
+	_, err = a.AddGroupingPolicy("user-u1", "admin")
+	require.NoError(t, err)
+	eventually(t, b, true, "user-u1", "/users", "GET")
+	assert.Equal(t, int64(2), storedRules())
+
+	_, err = a.UpdatePolicy([]string{"admin", "/users", "GET"}, []string{"admin", "/users", "POST"})
+	require.NoError(t, err)
+	eventually(t, b, false, "user-u1", "/users", "GET")
+	eventually(t, b, true, "user-u1", "/users", "POST")
+
+	_, err = b.RemoveFilteredGroupingPolicy(0, "user-u1")
+	require.NoError(t, err)
+	eventually(t, a, false, "user-u1", "/users", "POST")
+	policiesA, err := a.GetGroupingPolicy()
+	require.NoError(t, err)
+	policiesB, err := b.GetGroupingPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, policiesA, policiesB)
+
+	rules, err := b.GetPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"admin", "/users", "POST"}}, rules)
+	assert.Equal(t, int64(1), storedRules())
+	assert.Zero(t, reloads.Load())
+}
+
+func TestWatcherReloadPeriodically(t *testing.T) {
+	db := openWatcherDB(t, "casbin_watcher_reload")
+	a, _ := newWatchedEnforcer(t, db, &memoryTransport{}, &WatcherConfig{ReloadInterval: -1})
+	b, _ := newWatchedEnforcer(t, db, &memoryTransport{}, &WatcherConfig{ReloadInterval: 20 * time.Millisecond})
+
+	// 两个实例不在同一通道上，模拟消息丢失，只能依靠定期全量加载
+	_, err := a.AddPolicy("user-u1", "/orders", "GET")
+	require.NoError(t, err)
+	eventually(t, b, true, "user-u1", "/orders", "GET")
+}
+
+func TestWatcherIgnoresOwnMessages(t *testing.T) {
+	enforcer, err := casbin.NewSyncedEnforcer(newTestModel(t))
+	require.NoError(t, err)
+	watcher, err := NewWatcher(enforcer, &memoryTransport{}, &WatcherConfig{ReloadInterval: -1})
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	reloads := 0
+	require.NoError(t, watcher.SetUpdateCallback(func(string) { reloads++ }))
+	watcher.receive([]byte(`{"instanceId":"` + watcher.InstanceId + `","method":"update"}`))
+	assert.Equal(t, 0, reloads)
+	watcher.receive([]byte(`{"instanceId":"other","method":"update"}`))
+	watcher.receive([]byte(`not json`))
+	assert.Equal(t, 2, reloads)
+}
+
+// newTestModel 默认 RBAC 模型
+func newTestModel(t *testing.T) model.Model {
+	m, err := model.NewModelFromString(DefaultModel)
+	require.NoError(t, err)
+	return m
+}
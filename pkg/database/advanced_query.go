@@ -11,13 +11,19 @@
 package database
 
 import (
+	"fmt"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // 数据库方言常量
 const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+
 	// SQL模式常量
 	SQLLikePattern   = " LIKE ?"
 	SQLEqualsPattern = " = ?"
@@ -25,10 +31,14 @@ const (
 
 // AdvancedQueryParam 高级查询参数，支持复杂查询构建
 type AdvancedQueryParam struct {
-	option     *FindOptionCommon
-	filters    []*BaseInfoFilter
-	timeRanges map[string][2]string // 时间范围查询 key: 字段名, value: [开始时间, 结束时间]
-	findInSets map[string][]string  // FIND_IN_SET查询 key: 字段名, value: 查找值列表
+	option      *FindOptionCommon
+	filters     []*BaseInfoFilter
+	timeRanges  []*TimeRangeFilter  // 时间范围查询，按添加顺序生成条件
+	findInSets  map[string][]string // FIND_IN_SET查询 key: 字段名, value: 查找值列表
+	fullTexts   []*FullTextFilter   // 全文检索
+	jsonFilters []*JSONFilter       // JSON 字段查询
+	relevance   bool                // 是否按全文检索相关度排序
+	errs        []error             // 构建过程中产生的错误，查询时写入 gorm.DB
 }
 
 // NewAdvancedQueryParam 创建高级查询参数
@@ -39,7 +49,7 @@ func NewAdvancedQueryParam(option *FindOptionCommon) *AdvancedQueryParam {
 	return &AdvancedQueryParam{
 		option:     option,
 		filters:    make([]*BaseInfoFilter, 0),
-		timeRanges: make(map[string][2]string),
+		timeRanges: make([]*TimeRangeFilter, 0),
 		findInSets: make(map[string][]string),
 	}
 }
@@ -52,10 +62,36 @@ func (a *AdvancedQueryParam) AddFilter(filter *BaseInfoFilter) *AdvancedQueryPar
 	return a
 }
 
-// AddTimeRange 添加时间范围查询
+// AddTimeRange 添加时间范围查询（闭区间，字符串原样作为查询参数）
+// startTime 或 endTime 为空时退化为单边条件
 func (a *AdvancedQueryParam) AddTimeRange(field, startTime, endTime string) *AdvancedQueryParam {
-	if field != "" && startTime != "" && endTime != "" {
-		a.timeRanges[field] = [2]string{startTime, endTime}
+	if field == "" || (startTime == "" && endTime == "") {
+		return a
+	}
+	filter := &TimeRangeFilter{DBField: field, EndInclusive: true}
+	if startTime != "" {
+		filter.Start = startTime
+	}
+	if endTime != "" {
+		filter.End = endTime
+	}
+	a.timeRanges = append(a.timeRanges, filter)
+	return a
+}
+
+// AddTimeRangeFilter 添加左闭右开的时间范围查询 [start, end)
+// start/end 支持 time.Time、时间戳及时间字符串，为空时不限制该边界，解析失败时查询返回错误
+func (a *AdvancedQueryParam) AddTimeRangeFilter(field string, start, end interface{}) *AdvancedQueryParam {
+	if field == "" {
+		return a
+	}
+	filter, err := NewTimeRangeFilter(field, start, end)
+	if err != nil {
+		a.errs = append(a.errs, fmt.Errorf("字段 %s 的时间范围参数错误: %w", field, err))
+		return a
+	}
+	if filter.Start != nil || filter.End != nil {
+		a.timeRanges = append(a.timeRanges, filter)
 	}
 	return a
 }
@@ -68,12 +104,47 @@ func (a *AdvancedQueryParam) AddFindInSet(field string, values []string) *Advanc
 	return a
 }
 
+// AddFullText 添加全文检索
+func (a *AdvancedQueryParam) AddFullText(filter *FullTextFilter) *AdvancedQueryParam {
+	if filter.valid() {
+		a.fullTexts = append(a.fullTexts, filter)
+	}
+	return a
+}
+
+// AddJSONFilter 添加 JSON 字段查询，err 不为空时查询返回该错误
+func (a *AdvancedQueryParam) AddJSONFilter(filter *JSONFilter, err error) *AdvancedQueryParam {
+	if err != nil {
+		a.errs = append(a.errs, err)
+		return a
+	}
+	if filter != nil {
+		a.jsonFilters = append(a.jsonFilters, filter)
+	}
+	return a
+}
+
+// OrderByRelevance 设置是否按全文检索相关度排序，相关度排序优先于 FindOptionCommon 中的排序字段
+func (a *AdvancedQueryParam) OrderByRelevance(enable bool) *AdvancedQueryParam {
+	a.relevance = enable
+	return a
+}
+
 // Where 实现 QueryParam 接口
 func (a *AdvancedQueryParam) Where(db *gorm.DB) *gorm.DB {
+	if len(a.errs) > 0 {
+		// 使用新会话，避免错误写入共享的 gorm.DB 实例
+		db = db.Session(&gorm.Session{})
+		for _, err := range a.errs {
+			_ = db.AddError(err)
+		}
+	}
 	db = a.applyBusinessAndShopConditions(db)
 	db = a.applyFilters(db)
 	db = a.applyTimeRangeConditions(db)
 	db = a.applyFindInSetConditions(db)
+	db = a.applyFullTextConditions(db)
+	db = a.applyJSONConditions(db)
 	db = a.applyGroupAndOrder(db)
 	db = a.applyPagination(db)
 	return db
@@ -144,8 +215,10 @@ func (a *AdvancedQueryParam) applyFilter(db *gorm.DB, filter *BaseInfoFilter) *g
 
 // applyTimeRangeConditions 应用时间范围条件
 func (a *AdvancedQueryParam) applyTimeRangeConditions(db *gorm.DB) *gorm.DB {
-	for field, timeRange := range a.timeRanges {
-		db = db.Where(field+" BETWEEN ? AND ?", timeRange[0], timeRange[1])
+	for _, filter := range a.timeRanges {
+		if condition, args := filter.Condition(); condition != "" {
+			db = db.Where(condition, args...)
+		}
 	}
 	return db
 }
@@ -161,7 +234,7 @@ func (a *AdvancedQueryParam) applyFindInSetConditions(db *gorm.DB) *gorm.DB {
 			for _, value := range values {
 				// 检测数据库类型并使用相应的语法
 				dialectName := db.Dialector.Name()
-				if dialectName == "sqlite" {
+				if dialectName == DialectSQLite {
 					// SQLite 使用 LIKE 和 % 通配符来模拟 FIND_IN_SET
 					condition := a.buildSQLiteFindInSetCondition(field)
 					conditions = append(conditions, condition)
@@ -195,7 +268,7 @@ func (a *AdvancedQueryParam) buildSQLiteFindInSetCondition(field string) string
 // buildFindInSetCondition 构建单个FIND_IN_SET条件
 func (a *AdvancedQueryParam) buildFindInSetCondition(db *gorm.DB, field string, value string) *gorm.DB {
 	dialectName := db.Dialector.Name()
-	if dialectName == "sqlite" {
+	if dialectName == DialectSQLite {
 		// SQLite 使用 LIKE 模式匹配
 		condition := a.buildSQLiteFindInSetCondition(field)
 		return db.Where(condition, value+",%", "%,"+value+",%", "%,"+value, value)
@@ -204,19 +277,76 @@ func (a *AdvancedQueryParam) buildFindInSetCondition(db *gorm.DB, field string,
 	return db.Where("FIND_IN_SET(?, "+field+")", value)
 }
 
+// applyFullTextConditions 应用全文检索条件
+func (a *AdvancedQueryParam) applyFullTextConditions(db *gorm.DB) *gorm.DB {
+	dialectName := db.Dialector.Name()
+	for _, filter := range a.fullTexts {
+		if condition, args := filter.Condition(dialectName); condition != "" {
+			db = db.Where(condition, args...)
+		}
+	}
+	return db
+}
+
+// applyJSONConditions 应用 JSON 字段查询条件
+func (a *AdvancedQueryParam) applyJSONConditions(db *gorm.DB) *gorm.DB {
+	dialectName := db.Dialector.Name()
+	for _, filter := range a.jsonFilters {
+		condition, args, err := filter.Condition(dialectName)
+		if err != nil {
+			_ = db.AddError(err)
+			continue
+		}
+		db = db.Where(condition, args...)
+	}
+	return db
+}
+
+// relevanceOrders 生成全文检索相关度排序表达式
+func (a *AdvancedQueryParam) relevanceOrders(dialectName string) ([]string, []interface{}) {
+	if !a.relevance {
+		return nil, nil
+	}
+	var expressions []string
+	var args []interface{}
+	for _, filter := range a.fullTexts {
+		if expression, vars := filter.Relevance(dialectName); expression != "" {
+			expressions = append(expressions, expression)
+			args = append(args, vars...)
+		}
+	}
+	return expressions, args
+}
+
 // applyGroupAndOrder 应用分组和排序
 func (a *AdvancedQueryParam) applyGroupAndOrder(db *gorm.DB) *gorm.DB {
 	if a.option.GroupBy != "" {
 		db = db.Group(a.option.GroupBy)
 	}
 
-	if !a.option.DisableOrderBy && a.option.By != "" {
+	if a.option.DisableOrderBy {
+		return db
+	}
+
+	var orders []string
+	relevanceOrders, args := a.relevanceOrders(db.Dialector.Name())
+	orders = append(orders, relevanceOrders...)
+
+	if a.option.By != "" {
 		orderField := a.option.TablePrefix + a.option.By
 		orderDirection := "DESC"
 		if a.option.Order != "" {
 			orderDirection = a.option.Order
 		}
-		db = db.Order(orderField + " " + orderDirection)
+		orders = append(orders, orderField+" "+orderDirection)
+	}
+
+	switch {
+	case len(args) > 0:
+		// 带参数的排序表达式需要合并为一个子句，gorm 合并 ORDER BY 时会丢弃之前的表达式
+		db = db.Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(orders, ","), Vars: args, WithoutParentheses: true}})
+	case len(orders) > 0:
+		db = db.Order(strings.Join(orders, ","))
 	}
 
 	return db
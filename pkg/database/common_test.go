@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"time"
 
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -131,3 +133,28 @@ func seedTestData(db *gorm.DB) error {
 
 	return nil
 }
+
+// openDryRunDB 创建只生成SQL不执行的数据库连接，用于校验不同方言的SQL
+func openDryRunDB(dialect string) (*gorm.DB, error) {
+	config := &gorm.Config{DryRun: true, DisableAutomaticPing: true}
+	switch dialect {
+	case "mysql":
+		return gorm.Open(mysql.New(mysql.Config{
+			DSN:                       "user:pass@tcp(127.0.0.1:3306)/test",
+			SkipInitializeWithVersion: true,
+		}), config)
+	case "postgres":
+		return gorm.Open(postgres.New(postgres.Config{
+			DSN: "host=127.0.0.1 user=user password=pass dbname=test port=5432",
+		}), config)
+	default:
+		return gorm.Open(sqlite.Open(InMemoryDB), config)
+	}
+}
+
+// toSQL 生成查询参数在指定连接下的SQL
+func toSQL(db *gorm.DB, param QueryParam, dest interface{}) string {
+	return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return param.Where(tx).Find(dest)
+	})
+}
@@ -0,0 +1,160 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:05:48
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:05:48
+ * @FilePath: \go-core\pkg\database\fulltext.go
+ * @Description: 全文检索过滤器，按数据库方言生成检索条件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"strings"
+)
+
+// FullTextMode 全文检索模式
+type FullTextMode int
+
+const (
+	// FullTextNatural 自然语言模式
+	FullTextNatural FullTextMode = iota
+	// FullTextBoolean 布尔模式（MySQL BOOLEAN MODE / PostgreSQL websearch 语法 / FTS5 查询语法）
+	FullTextBoolean
+)
+
+// 全文检索默认配置
+const (
+	DefaultTextSearchConfig = "simple" // PostgreSQL 默认文本检索配置
+	DefaultFTSKey           = "rowid"  // SQLite 主表与 FTS5 虚拟表关联的字段
+)
+
+// FullTextFilter 全文检索过滤器
+// MySQL 使用 MATCH ... AGAINST（需要 FULLTEXT 索引），PostgreSQL 使用 to_tsvector @@ tsquery，
+// SQLite 在指定 FTSTable 时使用 FTS5 虚拟表，其余情况退化为 LIKE 模糊查询
+type FullTextFilter struct {
+	Fields           []string     // 检索字段
+	Query            string       // 检索内容
+	Mode             FullTextMode // 检索模式
+	TextSearchConfig string       // PostgreSQL 文本检索配置，默认 simple
+	FTSTable         string       // SQLite FTS5 虚拟表名，为空时退化为 LIKE
+	FTSKey           string       // SQLite 主表中与 FTS5 rowid 对应的字段，默认 rowid
+}
+
+// NewFullTextFilter 创建全文检索过滤器
+func NewFullTextFilter(fields []string, query string, mode FullTextMode) *FullTextFilter {
+	return &FullTextFilter{
+		Fields: fields,
+		Query:  strings.TrimSpace(query),
+		Mode:   mode,
+	}
+}
+
+// valid 检查过滤器是否可用
+func (f *FullTextFilter) valid() bool {
+	return f != nil && len(f.Fields) > 0 && f.Query != ""
+}
+
+// Condition 按方言生成检索条件
+func (f *FullTextFilter) Condition(dialect string) (string, []interface{}) {
+	if !f.valid() {
+		return "", nil
+	}
+	switch dialect {
+	case DialectMySQL:
+		return f.mysqlMatch(), []interface{}{f.Query}
+	case DialectPostgres:
+		return f.postgresVector() + " @@ " + f.postgresQuery(), []interface{}{f.textSearchConfig(), f.textSearchConfig(), f.Query}
+	case DialectSQLite:
+		if f.FTSTable != "" {
+			return f.ftsKey() + " IN (SELECT rowid FROM " + f.FTSTable + " WHERE " + f.FTSTable + " MATCH ?)", []interface{}{f.ftsQuery()}
+		}
+	}
+	return f.likeCondition()
+}
+
+// Relevance 按方言生成相关度排序表达式，不支持相关度的方言返回空字符串
+func (f *FullTextFilter) Relevance(dialect string) (string, []interface{}) {
+	if !f.valid() {
+		return "", nil
+	}
+	switch dialect {
+	case DialectMySQL:
+		return f.mysqlMatch() + " DESC", []interface{}{f.Query}
+	case DialectPostgres:
+		return "ts_rank(" + f.postgresVector() + ", " + f.postgresQuery() + ") DESC", []interface{}{f.textSearchConfig(), f.textSearchConfig(), f.Query}
+	case DialectSQLite:
+		if f.FTSTable != "" {
+			// bm25 越小相关度越高
+			return "(SELECT bm25(" + f.FTSTable + ") FROM " + f.FTSTable + " WHERE " + f.FTSTable + " MATCH ? AND " +
+				f.FTSTable + ".rowid = " + f.ftsKey() + ") ASC", []interface{}{f.ftsQuery()}
+		}
+	}
+	return "", nil
+}
+
+// mysqlMatch 生成 MySQL MATCH ... AGAINST 表达式
+func (f *FullTextFilter) mysqlMatch() string {
+	mode := "IN NATURAL LANGUAGE MODE"
+	if f.Mode == FullTextBoolean {
+		mode = "IN BOOLEAN MODE"
+	}
+	return "MATCH (" + strings.Join(f.Fields, ", ") + ") AGAINST (? " + mode + ")"
+}
+
+// postgresVector 生成 PostgreSQL to_tsvector 表达式
+func (f *FullTextFilter) postgresVector() string {
+	columns := make([]string, 0, len(f.Fields))
+	for _, field := range f.Fields {
+		columns = append(columns, "coalesce("+field+", '')")
+	}
+	return "to_tsvector(?::regconfig, " + strings.Join(columns, " || ' ' || ") + ")"
+}
+
+// postgresQuery 生成 PostgreSQL tsquery 表达式
+func (f *FullTextFilter) postgresQuery() string {
+	if f.Mode == FullTextBoolean {
+		return "websearch_to_tsquery(?::regconfig, ?)"
+	}
+	return "plainto_tsquery(?::regconfig, ?)"
+}
+
+// textSearchConfig 获取 PostgreSQL 文本检索配置
+func (f *FullTextFilter) textSearchConfig() string {
+	if f.TextSearchConfig == "" {
+		return DefaultTextSearchConfig
+	}
+	return f.TextSearchConfig
+}
+
+// ftsKey 获取 SQLite 主表关联字段
+func (f *FullTextFilter) ftsKey() string {
+	if f.FTSKey == "" {
+		return DefaultFTSKey
+	}
+	return f.FTSKey
+}
+
+// ftsQuery 生成 FTS5 查询语句，自然语言模式下将每个词作为短语以 OR 连接，避免语法错误
+func (f *FullTextFilter) ftsQuery() string {
+	if f.Mode == FullTextBoolean {
+		return f.Query
+	}
+	terms := strings.Fields(f.Query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// likeCondition 生成 LIKE 降级条件
+func (f *FullTextFilter) likeCondition() (string, []interface{}) {
+	conditions := make([]string, 0, len(f.Fields))
+	args := make([]interface{}, 0, len(f.Fields))
+	for _, field := range f.Fields {
+		conditions = append(conditions, field+SQLLikePattern)
+		args = append(args, "%"+f.Query+"%")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
@@ -0,0 +1,175 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:40:22
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:40:22
+ * @FilePath: \go-core\pkg\database\fulltext_test.go
+ * @Description: 全文检索过滤器测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TestFullTextFilterDialects 测试全文检索在不同方言下的SQL
+func TestFullTextFilterDialects(t *testing.T) {
+	fields := []string{"title", "content"}
+
+	tests := []struct {
+		dialect   string
+		filter    *FullTextFilter
+		condition string
+		order     string
+	}{
+		{
+			dialect:   "mysql",
+			filter:    NewFullTextFilter(fields, "golang orm", FullTextNatural),
+			condition: "MATCH (title, content) AGAINST ('golang orm' IN NATURAL LANGUAGE MODE)",
+			order:     "ORDER BY MATCH (title, content) AGAINST ('golang orm' IN NATURAL LANGUAGE MODE) DESC",
+		},
+		{
+			dialect:   "mysql",
+			filter:    NewFullTextFilter(fields, "+golang -java", FullTextBoolean),
+			condition: "MATCH (title, content) AGAINST ('+golang -java' IN BOOLEAN MODE)",
+			order:     "ORDER BY MATCH (title, content) AGAINST ('+golang -java' IN BOOLEAN MODE) DESC",
+		},
+		{
+			dialect:   "postgres",
+			filter:    NewFullTextFilter(fields, "golang orm", FullTextNatural),
+			condition: "to_tsvector('simple'::regconfig, coalesce(title, '') || ' ' || coalesce(content, '')) @@ plainto_tsquery('simple'::regconfig, 'golang orm')",
+			order:     "ORDER BY ts_rank(to_tsvector('simple'::regconfig, coalesce(title, '') || ' ' || coalesce(content, '')), plainto_tsquery('simple'::regconfig, 'golang orm')) DESC",
+		},
+		{
+			dialect:   "postgres",
+			filter:    &FullTextFilter{Fields: []string{"title"}, Query: "golang -java", Mode: FullTextBoolean, TextSearchConfig: "english"},
+			condition: "to_tsvector('english'::regconfig, coalesce(title, '')) @@ websearch_to_tsquery('english'::regconfig, 'golang -java')",
+			order:     "ORDER BY ts_rank(",
+		},
+		{
+			dialect:   "sqlite",
+			filter:    &FullTextFilter{Fields: fields, Query: "golang orm", FTSTable: "articles_fts"},
+			condition: `rowid IN (SELECT rowid FROM articles_fts WHERE articles_fts MATCH """golang"" OR ""orm""")`,
+			order:     `ORDER BY (SELECT bm25(articles_fts) FROM articles_fts WHERE articles_fts MATCH """golang"" OR ""orm""" AND articles_fts.rowid = rowid) ASC`,
+		},
+		{
+			dialect:   "sqlite",
+			filter:    NewFullTextFilter(fields, "golang", FullTextNatural),
+			condition: `(title LIKE "%golang%" OR content LIKE "%golang%")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect+"_"+tt.filter.Query, func(t *testing.T) {
+			db, err := openDryRunDB(tt.dialect)
+			assert.NoError(t, err)
+
+			param := NewQueryBuilder().WhereFullTextFilter(tt.filter).OrderByRelevance().Build()
+			var users []TestUser
+			sql := toSQL(db, param, &users)
+			assert.Contains(t, sql, tt.condition)
+			if tt.order != "" {
+				assert.Contains(t, sql, tt.order)
+			} else {
+				assert.NotContains(t, sql, "ORDER BY")
+			}
+		})
+	}
+}
+
+// TestFullTextFilterInvalid 测试无效的全文检索条件被忽略
+func TestFullTextFilterInvalid(t *testing.T) {
+	filter := NewFullTextFilter(nil, "golang", FullTextNatural)
+	condition, _ := filter.Condition(DialectMySQL)
+	assert.Empty(t, condition)
+
+	filter = NewFullTextFilter([]string{"title"}, "   ", FullTextNatural)
+	expression, _ := filter.Relevance(DialectMySQL)
+	assert.Empty(t, expression)
+
+	param := NewAdvancedQueryParam(nil).AddFullText(filter)
+	assert.Empty(t, param.fullTexts)
+}
+
+// TestFullTextRelevanceAfterOrder 测试相关度排序优先于普通排序
+func TestFullTextRelevanceAfterOrder(t *testing.T) {
+	db, err := openDryRunDB("mysql")
+	assert.NoError(t, err)
+
+	param := NewQueryBuilder().
+		WhereFullText([]string{"title"}, "golang", FullTextNatural).
+		OrderByRelevance().
+		WithOrder("id", "DESC").
+		Build()
+	var users []TestUser
+	assert.Contains(t, toSQL(db, param, &users), "ORDER BY MATCH (title) AGAINST ('golang' IN NATURAL LANGUAGE MODE) DESC,id DESC")
+}
+
+// FullTextTestSuite 全文检索查询测试套件
+type FullTextTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	handler Handler
+}
+
+// SetupSuite 测试套件初始化
+func (suite *FullTextTestSuite) SetupSuite() {
+	db, handler, err := setupTestDB()
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.handler = handler
+	suite.Require().NoError(seedTestData(db))
+
+	// FTS4 与 FTS5 的 MATCH 语法一致，测试环境的 SQLite 未编译 FTS5
+	suite.Require().NoError(db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS test_users_fts USING fts4(username, email)").Error)
+	suite.Require().NoError(db.Exec("DELETE FROM test_users_fts").Error)
+	suite.Require().NoError(db.Exec("INSERT INTO test_users_fts(rowid, username, email) SELECT id, username, email FROM test_users").Error)
+}
+
+// TearDownSuite 测试套件清理
+func (suite *FullTextTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		suite.handler.Close()
+	}
+}
+
+// TestFTSTableSearch 测试使用虚拟表检索
+func (suite *FullTextTestSuite) TestFTSTableSearch() {
+	param := NewQueryBuilder().
+		WhereFullTextFilter(&FullTextFilter{Fields: []string{"username", "email"}, Query: "john", FTSTable: "test_users_fts", FTSKey: "id"}).
+		Build()
+
+	var users []TestUser
+	suite.NoError(suite.handler.Query(param).Find(&users).Error)
+	suite.NotEmpty(users)
+	for _, user := range users {
+		suite.Contains(user.Username+user.Email, "john")
+	}
+}
+
+// TestLikeFallback 测试未配置虚拟表时退化为 LIKE
+func (suite *FullTextTestSuite) TestLikeFallback() {
+	param := NewQueryBuilder().
+		WhereFullText([]string{"username", "email"}, "smith", FullTextNatural).
+		OrderByRelevance().
+		Build()
+
+	var users []TestUser
+	suite.NoError(suite.handler.Query(param).Find(&users).Error)
+	suite.NotEmpty(users)
+	for _, user := range users {
+		suite.Contains(user.Username, "smith")
+	}
+}
+
+// TestFullTextTestSuite 运行全文检索测试套件
+func TestFullTextTestSuite(t *testing.T) {
+	suite.Run(t, new(FullTextTestSuite))
+}
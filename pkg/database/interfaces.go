@@ -29,4 +29,9 @@ type Handler interface {
 	Begin(opts ...*sql.TxOptions) Handler
 	Commit() error
 	Rollback() error
+}
+
+// VersionUpdater 可选的乐观锁更新接口，DatabaseHandler 已实现，其他 Handler 可通过类型断言判断是否支持
+type VersionUpdater interface {
+	UpdateWithVersion(model interface{}, updates interface{}) error
 }
\ No newline at end of file
@@ -0,0 +1,349 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 14:20:09
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 14:20:09
+ * @FilePath: \go-core\pkg\database\json_filter.go
+ * @Description: JSON 字段查询过滤器，按数据库方言生成查询条件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONFilterKind JSON 过滤器类型
+type JSONFilterKind int
+
+const (
+	// JSONExtract 按路径取值比较
+	JSONExtract JSONFilterKind = iota
+	// JSONContains 包含指定的 JSON 值
+	JSONContains
+	// JSONHasKey 存在指定路径
+	JSONHasKey
+)
+
+var (
+	// ErrInvalidJSONPath 无法解析的 JSON 路径
+	ErrInvalidJSONPath = errors.New("无法解析的 JSON 路径")
+	// ErrInvalidJSONOperator 不支持的 JSON 比较运算符
+	ErrInvalidJSONOperator = errors.New("不支持的 JSON 比较运算符")
+)
+
+// jsonOperators 支持的比较运算符
+var jsonOperators = map[string]string{
+	"=":        "=",
+	"!=":       "<>",
+	"<>":       "<>",
+	">":        ">",
+	">=":       ">=",
+	"<":        "<",
+	"<=":       "<=",
+	"LIKE":     "LIKE",
+	"NOT LIKE": "NOT LIKE",
+}
+
+// jsonPathSegmentRegex JSON 路径片段，key 或 [index]
+var jsonPathSegmentRegex = regexp.MustCompile(`^([^.\[\]]+)?((\[\d+\])*)$`)
+
+// jsonPathIndexRegex JSON 路径中的数组下标
+var jsonPathIndexRegex = regexp.MustCompile(`\d+`)
+
+// jsonIdentifierRegex 无需引号的 key
+var jsonIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPathSegment JSON 路径片段
+type jsonPathSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// JSONFilter JSON 字段过滤器
+// MySQL 使用 JSON_EXTRACT / JSON_CONTAINS / JSON_CONTAINS_PATH，PostgreSQL 使用 #>> / @> / #>，SQLite 使用 json_extract / json_each / json_type
+type JSONFilter struct {
+	DBField  string         // DB中字段名称
+	Path     string         // JSON 路径，如 $.a.b、a.b、$.items[0].id
+	Operator string         // 比较运算符，仅 JSONExtract 有效
+	Value    interface{}    // 比较值
+	Kind     JSONFilterKind // 过滤器类型
+	segments []jsonPathSegment
+}
+
+// NewJSONFilter 创建 JSON 路径取值比较过滤器
+func NewJSONFilter(field, path, operator string, value interface{}) (*JSONFilter, error) {
+	op, ok := jsonOperators[strings.ToUpper(strings.TrimSpace(operator))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJSONOperator, operator)
+	}
+	return newJSONFilter(field, path, JSONExtract, op, value)
+}
+
+// NewJSONContainsFilter 创建 JSON 包含过滤器，value 为任意可序列化为 JSON 的值
+func NewJSONContainsFilter(field string, value interface{}) (*JSONFilter, error) {
+	return NewJSONContainsPathFilter(field, "$", value)
+}
+
+// NewJSONContainsPathFilter 创建指定路径下的 JSON 包含过滤器，如 $.tags 包含 ["vip"]
+func NewJSONContainsPathFilter(field, path string, value interface{}) (*JSONFilter, error) {
+	return newJSONFilter(field, path, JSONContains, "", value)
+}
+
+// NewJSONHasKeyFilter 创建 JSON 路径存在过滤器
+func NewJSONHasKeyFilter(field, path string) (*JSONFilter, error) {
+	return newJSONFilter(field, path, JSONHasKey, "", nil)
+}
+
+// newJSONFilter 创建 JSON 过滤器并解析路径
+func newJSONFilter(field, path string, kind JSONFilterKind, operator string, value interface{}) (*JSONFilter, error) {
+	if field == "" {
+		return nil, errors.New("JSON 过滤器字段不能为空")
+	}
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if kind == JSONHasKey && len(segments) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJSONPath, path)
+	}
+	return &JSONFilter{
+		DBField:  field,
+		Path:     path,
+		Operator: operator,
+		Value:    value,
+		Kind:     kind,
+		segments: segments,
+	}, nil
+}
+
+// parseJSONPath 解析 JSON 路径，支持 $.a.b、a.b、$.a[0].b、$[0]
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, nil
+	}
+
+	var segments []jsonPathSegment
+	for _, part := range strings.Split(path, ".") {
+		matches := jsonPathSegmentRegex.FindStringSubmatch(part)
+		if part == "" || matches == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidJSONPath, path)
+		}
+		if matches[1] != "" {
+			segments = append(segments, jsonPathSegment{key: strings.Trim(matches[1], `"`), isKey: true})
+		}
+		for _, index := range jsonPathIndexRegex.FindAllString(matches[2], -1) {
+			i, _ := strconv.Atoi(index)
+			segments = append(segments, jsonPathSegment{index: i})
+		}
+	}
+	return segments, nil
+}
+
+// mysqlPath 生成 MySQL/SQLite 路径表达式
+func mysqlPath(segments []jsonPathSegment) string {
+	var builder strings.Builder
+	builder.WriteString("$")
+	for _, segment := range segments {
+		if !segment.isKey {
+			builder.WriteString("[" + strconv.Itoa(segment.index) + "]")
+			continue
+		}
+		if jsonIdentifierRegex.MatchString(segment.key) {
+			builder.WriteString("." + segment.key)
+		} else {
+			builder.WriteString(`."` + strings.ReplaceAll(segment.key, `"`, `\"`) + `"`)
+		}
+	}
+	return builder.String()
+}
+
+// postgresPath 生成 PostgreSQL text[] 路径表达式
+func postgresPath(segments []jsonPathSegment) string {
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if !segment.isKey {
+			parts = append(parts, strconv.Itoa(segment.index))
+			continue
+		}
+		if jsonIdentifierRegex.MatchString(segment.key) {
+			parts = append(parts, segment.key)
+		} else {
+			parts = append(parts, `"`+strings.ReplaceAll(segment.key, `"`, `\"`)+`"`)
+		}
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Condition 按方言生成查询条件
+func (f *JSONFilter) Condition(dialect string) (string, []interface{}, error) {
+	switch f.Kind {
+	case JSONContains:
+		return f.containsCondition(dialect)
+	case JSONHasKey:
+		return f.hasKeyCondition(dialect), f.pathArgs(dialect), nil
+	default:
+		value := f.Value
+		if b, ok := value.(bool); ok && dialect == DialectMySQL {
+			// MySQL 中 JSON 布尔值与整数不相等，需要转换为 JSON 再比较
+			value = strconv.FormatBool(b)
+		}
+		return f.extractCondition(dialect), append(f.pathArgs(dialect), value), nil
+	}
+}
+
+// pathArgs 路径参数
+func (f *JSONFilter) pathArgs(dialect string) []interface{} {
+	if dialect == DialectPostgres {
+		return []interface{}{postgresPath(f.segments)}
+	}
+	return []interface{}{mysqlPath(f.segments)}
+}
+
+// extractCondition 生成按路径取值比较的条件
+func (f *JSONFilter) extractCondition(dialect string) string {
+	switch dialect {
+	case DialectMySQL:
+		if isJSONBool(f.Value) {
+			return "JSON_EXTRACT(" + f.DBField + ", ?) " + f.Operator + " CAST(? AS JSON)"
+		}
+		if isJSONScalarNumberOrBool(f.Value) {
+			return "JSON_EXTRACT(" + f.DBField + ", ?) " + f.Operator + " ?"
+		}
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + f.DBField + ", ?)) " + f.Operator + " ?"
+	case DialectPostgres:
+		expression := "(" + f.DBField + "::jsonb #>> ?::text[])"
+		switch {
+		case isJSONBool(f.Value):
+			expression += "::boolean"
+		case isJSONScalarNumberOrBool(f.Value):
+			expression += "::numeric"
+		}
+		return expression + " " + f.Operator + " ?"
+	default:
+		return "json_extract(" + f.DBField + ", ?) " + f.Operator + " ?"
+	}
+}
+
+// hasKeyCondition 生成路径存在条件
+func (f *JSONFilter) hasKeyCondition(dialect string) string {
+	switch dialect {
+	case DialectMySQL:
+		return "JSON_CONTAINS_PATH(" + f.DBField + ", 'one', ?)"
+	case DialectPostgres:
+		return "(" + f.DBField + "::jsonb #> ?::text[]) IS NOT NULL"
+	default:
+		return "json_type(" + f.DBField + ", ?) IS NOT NULL"
+	}
+}
+
+// containsCondition 生成包含条件
+func (f *JSONFilter) containsCondition(dialect string) (string, []interface{}, error) {
+	document, err := json.Marshal(f.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	switch dialect {
+	case DialectMySQL:
+		if len(f.segments) > 0 {
+			return "JSON_CONTAINS(" + f.DBField + ", ?, ?)", []interface{}{string(document), mysqlPath(f.segments)}, nil
+		}
+		return "JSON_CONTAINS(" + f.DBField + ", ?)", []interface{}{string(document)}, nil
+	case DialectPostgres:
+		if len(f.segments) > 0 {
+			return "(" + f.DBField + "::jsonb #> ?::text[]) @> ?::jsonb", []interface{}{postgresPath(f.segments), string(document)}, nil
+		}
+		return f.DBField + "::jsonb @> ?::jsonb", []interface{}{string(document)}, nil
+	default:
+		// SQLite 没有 JSON_CONTAINS，按对象的 key 逐层展开比较
+		var value interface{}
+		if err := json.Unmarshal(document, &value); err != nil {
+			return "", nil, err
+		}
+		conditions, args := f.sqliteContains(f.segments, value)
+		if len(conditions) == 0 {
+			return "json_valid(" + f.DBField + ")", nil, nil
+		}
+		return "(" + strings.Join(conditions, " AND ") + ")", args, nil
+	}
+}
+
+// sqliteContains 递归生成 SQLite 包含条件
+func (f *JSONFilter) sqliteContains(segments []jsonPathSegment, value interface{}) ([]string, []interface{}) {
+	path := mysqlPath(segments)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var conditions []string
+		var args []interface{}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			item := v[key]
+			child := append(append([]jsonPathSegment(nil), segments...), jsonPathSegment{key: key, isKey: true})
+			childConditions, childArgs := f.sqliteContains(child, item)
+			conditions = append(conditions, childConditions...)
+			args = append(args, childArgs...)
+		}
+		return conditions, args
+	case []interface{}:
+		var conditions []string
+		var args []interface{}
+		for _, item := range v {
+			itemConditions, itemArgs := f.sqliteContainsElement(path, item)
+			conditions = append(conditions, itemConditions)
+			args = append(args, itemArgs...)
+		}
+		return conditions, args
+	default:
+		condition, args := f.sqliteContainsElement(path, v)
+		return []string{condition}, args
+	}
+}
+
+// sqliteContainsElement 生成 SQLite 数组元素（或标量）包含条件
+func (f *JSONFilter) sqliteContainsElement(path string, value interface{}) (string, []interface{}) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		document, _ := json.Marshal(value)
+		return "EXISTS (SELECT 1 FROM json_each(" + f.DBField + ", ?) WHERE json(value) = json(?))", []interface{}{path, string(document)}
+	case nil:
+		return "EXISTS (SELECT 1 FROM json_each(" + f.DBField + ", ?) WHERE type = 'null')", []interface{}{path}
+	default:
+		return "EXISTS (SELECT 1 FROM json_each(" + f.DBField + ", ?) WHERE value = ?)", []interface{}{path, value}
+	}
+}
+
+// isJSONScalarNumberOrBool 是否为数值或布尔值
+func isJSONScalarNumberOrBool(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isJSONBool 是否为布尔值
+func isJSONBool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
@@ -0,0 +1,314 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 15:02:44
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 15:02:44
+ * @FilePath: \go-core\pkg\database\json_filter_test.go
+ * @Description: JSON 字段查询测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TestJSONProfile JSON 字段测试模型
+type TestJSONProfile struct {
+	ID     uint   `gorm:"primarykey"`
+	Name   string `gorm:"size:50"`
+	Extend string `gorm:"type:text"`
+}
+
+// TableName 指定表名
+func (TestJSONProfile) TableName() string {
+	return "test_json_profiles"
+}
+
+// TestParseJSONPath 测试 JSON 路径解析
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		mysql    string
+		postgres string
+		hasErr   bool
+	}{
+		{"$.a.b", "$.a.b", "{a,b}", false},
+		{"a.b", "$.a.b", "{a,b}", false},
+		{"$.items[0].id", "$.items[0].id", "{items,0,id}", false},
+		{"$[1]", "$[1]", "{1}", false},
+		{"$", "$", "{}", false},
+		{"$.user-name", `$."user-name"`, `{"user-name"}`, false},
+		{"$.a..b", "", "", true},
+		{"$.a[x]", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segments, err := parseJSONPath(tt.path)
+			if tt.hasErr {
+				assert.ErrorIs(t, err, ErrInvalidJSONPath)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.mysql, mysqlPath(segments))
+			assert.Equal(t, tt.postgres, postgresPath(segments))
+		})
+	}
+}
+
+// TestJSONFilterDialects 测试 JSON 查询在不同方言下的SQL
+func TestJSONFilterDialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *QueryBuilder
+		sql     map[string]string
+	}{
+		{
+			name:    "extract string",
+			builder: NewQueryBuilder().WhereJSON("extend", "$.profile.city", "=", "shanghai"),
+			sql: map[string]string{
+				"mysql":    "JSON_UNQUOTE(JSON_EXTRACT(extend, '$.profile.city')) = 'shanghai'",
+				"postgres": "(extend::jsonb #>> '{profile,city}'::text[]) = 'shanghai'",
+				"sqlite":   `json_extract(extend, "$.profile.city") = "shanghai"`,
+			},
+		},
+		{
+			name:    "extract number",
+			builder: NewQueryBuilder().WhereJSON("extend", "age", ">=", 18),
+			sql: map[string]string{
+				"mysql":    "JSON_EXTRACT(extend, '$.age') >= 18",
+				"postgres": "(extend::jsonb #>> '{age}'::text[])::numeric >= 18",
+				"sqlite":   `json_extract(extend, "$.age") >= 18`,
+			},
+		},
+		{
+			name:    "extract bool",
+			builder: NewQueryBuilder().WhereJSON("extend", "$.vip", "!=", true),
+			sql: map[string]string{
+				"mysql":    "JSON_EXTRACT(extend, '$.vip') <> CAST('true' AS JSON)",
+				"postgres": "(extend::jsonb #>> '{vip}'::text[])::boolean <> true",
+			},
+		},
+		{
+			name:    "contains",
+			builder: NewQueryBuilder().WhereJSONContains("extend", map[string]interface{}{"tags": []string{"vip"}}),
+			sql: map[string]string{
+				"mysql":    `JSON_CONTAINS(extend, '{"tags":["vip"]}')`,
+				"postgres": `extend::jsonb @> '{"tags":["vip"]}'::jsonb`,
+				"sqlite":   `EXISTS (SELECT 1 FROM json_each(extend, "$.tags") WHERE value = "vip")`,
+			},
+		},
+		{
+			name:    "contains path",
+			builder: NewQueryBuilder().WhereJSONContainsPath("extend", "$.tags", []string{"vip"}),
+			sql: map[string]string{
+				"mysql":    `JSON_CONTAINS(extend, '["vip"]', '$.tags')`,
+				"postgres": `(extend::jsonb #> '{tags}'::text[]) @> '["vip"]'::jsonb`,
+				"sqlite":   `EXISTS (SELECT 1 FROM json_each(extend, "$.tags") WHERE value = "vip")`,
+			},
+		},
+		{
+			name:    "has key",
+			builder: NewQueryBuilder().WhereJSONHasKey("extend", "$.profile.city"),
+			sql: map[string]string{
+				"mysql":    "JSON_CONTAINS_PATH(extend, 'one', '$.profile.city')",
+				"postgres": "(extend::jsonb #> '{profile,city}'::text[]) IS NOT NULL",
+				"sqlite":   `json_type(extend, "$.profile.city") IS NOT NULL`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for dialect, expected := range tt.sql {
+			t.Run(tt.name+"_"+dialect, func(t *testing.T) {
+				db, err := openDryRunDB(dialect)
+				assert.NoError(t, err)
+				var profiles []TestJSONProfile
+				assert.Contains(t, toSQL(db, tt.builder.Build(), &profiles), expected)
+			})
+		}
+	}
+}
+
+// TestJSONFilterInvalid 测试非法参数
+func TestJSONFilterInvalid(t *testing.T) {
+	_, err := NewJSONFilter("extend", "$.a", "~", 1)
+	assert.ErrorIs(t, err, ErrInvalidJSONOperator)
+
+	_, err = NewJSONHasKeyFilter("extend", "$")
+	assert.ErrorIs(t, err, ErrInvalidJSONPath)
+
+	_, err = NewJSONFilter("", "$.a", "=", 1)
+	assert.Error(t, err)
+}
+
+// TestParseJSONCondition 测试URL中的JSON查询参数解析
+func TestParseJSONCondition(t *testing.T) {
+	tests := []struct {
+		value    string
+		kind     JSONFilterKind
+		operator string
+		expected interface{}
+	}{
+		{"json:$.age:gte:18", JSONExtract, ">=", float64(18)},
+		{"json:$.city:shanghai", JSONExtract, "=", "shanghai"},
+		{`json:$.phone:eq:"138"`, JSONExtract, "=", "138"},
+		{"json:$.name:lk:jo", JSONExtract, "LIKE", "jo%"},
+		{"json:$.url:http://a.com", JSONExtract, "=", "http://a.com"},
+		{"json:$.vip:has", JSONHasKey, "", nil},
+		{`json:$:cs:["vip"]`, JSONContains, "", []interface{}{"vip"}},
+		{`json:$.tags:cs:["vip"]`, JSONContains, "", []interface{}{"vip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var jsonParams []*JSONFilter
+			ok, err := parseJSONCondition("extendInfo", tt.value, &jsonParams)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			assert.Len(t, jsonParams, 1)
+			assert.Equal(t, "extend_info", jsonParams[0].DBField)
+			assert.Equal(t, tt.kind, jsonParams[0].Kind)
+			assert.Equal(t, tt.operator, jsonParams[0].Operator)
+			assert.Equal(t, tt.expected, jsonParams[0].Value)
+		})
+	}
+
+	var jsonParams []*JSONFilter
+	ok, err := parseJSONCondition("extend", "eq:1", &jsonParams)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	assert.Empty(t, jsonParams)
+
+	// 无效的参数返回错误而不是丢弃条件
+	for _, value := range []string{"json:$.a", "json:$.a..b:gte:1", "json:$:has", "json:$.a[x]:cs:1"} {
+		ok, err = parseJSONCondition("extend", value, &jsonParams)
+		assert.True(t, ok, value)
+		assert.Error(t, err, value)
+	}
+	assert.Empty(t, jsonParams)
+}
+
+// TestParsePageParamInvalidJSON 测试URL中无效的JSON查询参数
+func TestParsePageParamInvalidJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	params := url.Values{}
+	params.Add("extend", "json:$.a..b:gte:18")
+	c.Request = &http.Request{URL: &url.URL{RawQuery: params.Encode()}}
+
+	pageInfo, err := ParsePageParam(c)
+	assert.ErrorIs(t, err, ErrInvalidJSONPath)
+	assert.Nil(t, pageInfo)
+	assert.Nil(t, PageParam(c))
+}
+
+// JSONFilterTestSuite JSON 字段查询测试套件
+type JSONFilterTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	handler Handler
+}
+
+// SetupSuite 测试套件初始化
+func (suite *JSONFilterTestSuite) SetupSuite() {
+	db, handler, err := setupTestDB()
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.handler = handler
+	suite.Require().NoError(handler.AutoMigrate(&TestJSONProfile{}))
+	suite.Require().NoError(db.Exec("DELETE FROM test_json_profiles").Error)
+
+	profiles := []TestJSONProfile{
+		{Name: "john", Extend: `{"age": 25, "vip": true, "city": "shanghai", "tags": ["vip", "active"], "profile": {"level": 3}}`},
+		{Name: "jane", Extend: `{"age": 17, "vip": false, "city": "beijing", "tags": ["active"]}`},
+		{Name: "bob", Extend: `{"age": 40, "city": "shanghai", "tags": []}`},
+	}
+	suite.Require().NoError(db.Create(&profiles).Error)
+}
+
+// TearDownSuite 测试套件清理
+func (suite *JSONFilterTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		suite.handler.Close()
+	}
+}
+
+// findNames 查询并返回名称列表
+func (suite *JSONFilterTestSuite) findNames(qb *QueryBuilder) []string {
+	var profiles []TestJSONProfile
+	suite.Require().NoError(suite.handler.Query(qb.Build()).Order("id").Find(&profiles).Error)
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// TestWhereJSON 测试按路径取值比较
+func (suite *JSONFilterTestSuite) TestWhereJSON() {
+	suite.Equal([]string{"john", "bob"}, suite.findNames(NewQueryBuilder().WhereJSON("extend", "$.age", ">=", 18)))
+	suite.Equal([]string{"john", "bob"}, suite.findNames(NewQueryBuilder().WhereJSON("extend", "city", "=", "shanghai")))
+	suite.Equal([]string{"john"}, suite.findNames(NewQueryBuilder().WhereJSON("extend", "$.profile.level", ">", 1)))
+	suite.Equal([]string{"john"}, suite.findNames(NewQueryBuilder().WhereJSON("extend", "$.vip", "=", true)))
+}
+
+// TestWhereJSONContains 测试包含条件
+func (suite *JSONFilterTestSuite) TestWhereJSONContains() {
+	suite.Equal([]string{"john", "jane"}, suite.findNames(NewQueryBuilder().WhereJSONContains("extend", map[string]interface{}{"tags": []string{"active"}})))
+	suite.Equal([]string{"john"}, suite.findNames(NewQueryBuilder().WhereJSONContains("extend", map[string]interface{}{"tags": []string{"vip", "active"}, "city": "shanghai"})))
+}
+
+// TestWhereJSONHasKey 测试路径存在条件
+func (suite *JSONFilterTestSuite) TestWhereJSONHasKey() {
+	suite.Equal([]string{"john", "jane"}, suite.findNames(NewQueryBuilder().WhereJSONHasKey("extend", "$.vip")))
+	suite.Equal([]string{"john"}, suite.findNames(NewQueryBuilder().WhereJSONHasKey("extend", "profile.level")))
+}
+
+// TestWhereJSONInvalid 测试非法参数使查询失败
+func (suite *JSONFilterTestSuite) TestWhereJSONInvalid() {
+	var profiles []TestJSONProfile
+	err := suite.handler.Query(NewQueryBuilder().WhereJSON("extend", "$.age", "~", 1).Build()).Find(&profiles).Error
+	suite.ErrorIs(err, ErrInvalidJSONOperator)
+}
+
+// TestFindPageWithJSON 测试URL中的JSON查询参数
+func (suite *JSONFilterTestSuite) TestFindPageWithJSON() {
+	originalDB := global.DB
+	global.DB = suite.db
+	defer func() { global.DB = originalDB }()
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	params := url.Values{}
+	params.Add("extend", "json:$.age:gte:18")
+	params.Add("name", "lk:j")
+	c.Request = &http.Request{URL: &url.URL{RawQuery: params.Encode()}}
+
+	pageInfo := PageParam(c)
+	pageInfo.Current = 1
+	pageInfo.RowCount = 10
+
+	var profiles []TestJSONProfile
+	pageBean, err := FindPage(&TestJSONProfile{}, &profiles, pageInfo)
+	suite.NoError(err)
+	suite.Equal(int64(1), pageBean.Total)
+	suite.Len(profiles, 1)
+	suite.Equal("john", profiles[0].Name)
+}
+
+// TestJSONFilterTestSuite 运行 JSON 字段查询测试套件
+func TestJSONFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONFilterTestSuite))
+}
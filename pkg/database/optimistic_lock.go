@@ -0,0 +1,176 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 10:20:41
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 10:20:41
+ * @FilePath: \go-core\pkg\database\optimistic_lock.go
+ * @Description: 乐观锁（版本号）更新实现
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kamalyes/go-core/pkg/response"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// VersionFieldName 约定的版本号字段名
+	VersionFieldName = "Version"
+	// VersionTagSetting 通过 gorm:"version" 标签指定版本号字段
+	VersionTagSetting = "VERSION"
+)
+
+var (
+	// ErrStaleObject 数据已被其他操作修改（版本号不匹配或记录不存在）
+	ErrStaleObject = errors.New("数据已被其他操作修改，请刷新后重试")
+	// ErrVersionFieldNotFound 模型中未找到版本号字段
+	ErrVersionFieldNotFound = errors.New("模型中未找到版本号字段")
+)
+
+func init() {
+	response.RegisterErrorCode(ErrStaleObject, response.StatusConflict, response.DataConflict)
+}
+
+// StaleObjectError 乐观锁冲突错误，可通过 errors.Is(err, ErrStaleObject) 判断
+type StaleObjectError struct {
+	Table      string      // 表名
+	PrimaryKey interface{} // 主键值
+	Version    int64       // 更新时携带的版本号
+}
+
+// Error 实现 error 接口
+func (e *StaleObjectError) Error() string {
+	return fmt.Sprintf("%s: table=%s, id=%v, version=%d", ErrStaleObject.Error(), e.Table, e.PrimaryKey, e.Version)
+}
+
+// Unwrap 返回 ErrStaleObject
+func (e *StaleObjectError) Unwrap() error {
+	return ErrStaleObject
+}
+
+// UpdateWithVersion implements VersionUpdater
+func (d *DatabaseHandler) UpdateWithVersion(model interface{}, updates interface{}) error {
+	return UpdateWithVersion(d.db, model, updates)
+}
+
+// UpdateWithVersion 基于版本号的乐观锁更新
+// model 必须为带主键的结构体指针，版本号字段为 Version 或带有 gorm:"version" 标签的整型字段；
+// updates 为空时按 model 的非零字段更新，也可以传入 map[string]interface{} 指定更新列；
+// 更新条件追加 version = 当前版本，成功后版本号自增，未命中任何记录时返回 *StaleObjectError
+func UpdateWithVersion(db *gorm.DB, model interface{}, updates interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	versionField := lookupVersionField(stmt.Schema)
+	if versionField == nil {
+		return ErrVersionFieldNotFound
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	modelValue := reflect.Indirect(reflect.ValueOf(model))
+	if modelValue.Kind() != reflect.Struct || !modelValue.CanAddr() {
+		return gorm.ErrInvalidValue
+	}
+
+	primaryField := stmt.Schema.PrioritizedPrimaryField
+	if primaryField == nil {
+		return gorm.ErrPrimaryKeyRequired
+	}
+	primaryKey, isZero := primaryField.ValueOf(ctx, modelValue)
+	if isZero {
+		return gorm.ErrPrimaryKeyRequired
+	}
+
+	currentValue, _ := versionField.ValueOf(ctx, modelValue)
+	currentVersion := versionOf(reflect.ValueOf(currentValue))
+	nextVersion := currentVersion + 1
+
+	tx := db.Model(model).Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: versionField.DBName},
+		Value:  currentVersion,
+	})
+
+	var result *gorm.DB
+	switch values := updates.(type) {
+	case nil:
+		if err := versionField.Set(ctx, modelValue, nextVersion); err != nil {
+			return err
+		}
+		result = tx.Updates(model)
+	case map[string]interface{}:
+		columns := make(map[string]interface{}, len(values)+1)
+		for column, value := range values {
+			columns[column] = value
+		}
+		columns[versionField.DBName] = nextVersion
+		result = tx.Updates(columns)
+	default:
+		return fmt.Errorf("UpdateWithVersion 不支持的更新参数类型: %T", updates)
+	}
+
+	if result.Error != nil {
+		_ = versionField.Set(ctx, modelValue, currentVersion)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		_ = versionField.Set(ctx, modelValue, currentVersion)
+		return &StaleObjectError{
+			Table:      stmt.Schema.Table,
+			PrimaryKey: primaryKey,
+			Version:    currentVersion,
+		}
+	}
+
+	return versionField.Set(ctx, modelValue, nextVersion)
+}
+
+// lookupVersionField 查找版本号字段，优先使用 gorm:"version" 标签
+func lookupVersionField(s *schema.Schema) *schema.Field {
+	var byName *schema.Field
+	for _, field := range s.Fields {
+		if !isIntegerField(field) {
+			continue
+		}
+		if _, ok := field.TagSettings[VersionTagSetting]; ok {
+			return field
+		}
+		if byName == nil && field.Name == VersionFieldName {
+			byName = field
+		}
+	}
+	return byName
+}
+
+// isIntegerField 判断字段是否为整型，包括无符号整型
+func isIntegerField(field *schema.Field) bool {
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// versionOf 读取整型或无符号整型的版本号
+func versionOf(value reflect.Value) int64 {
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint())
+	default:
+		return value.Int()
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 10:35:12
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 10:35:12
+ * @FilePath: \go-core\pkg\database\optimistic_lock_test.go
+ * @Description: 乐观锁更新测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kamalyes/go-core/pkg/response"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TestVersionedArticle 带版本号的测试模型
+type TestVersionedArticle struct {
+	ID      uint   `gorm:"primarykey"`
+	Title   string `gorm:"size:100"`
+	Content string
+	Version int64 `gorm:"not null;default:1"`
+}
+
+// TableName 指定表名
+func (TestVersionedArticle) TableName() string {
+	return "test_versioned_articles"
+}
+
+// TestTaggedArticle 通过标签指定版本号字段的测试模型
+type TestTaggedArticle struct {
+	ID       uint `gorm:"primarykey"`
+	Title    string
+	Revision int `gorm:"version;not null;default:1"`
+}
+
+// TableName 指定表名
+func (TestTaggedArticle) TableName() string {
+	return "test_tagged_articles"
+}
+
+// TestUnsignedArticle 无符号整型版本号的测试模型
+type TestUnsignedArticle struct {
+	ID      uint `gorm:"primarykey"`
+	Title   string
+	Version uint32 `gorm:"not null;default:1"`
+}
+
+// TableName 指定表名
+func (TestUnsignedArticle) TableName() string {
+	return "test_unsigned_articles"
+}
+
+// OptimisticLockTestSuite 乐观锁测试套件
+type OptimisticLockTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	handler   Handler
+	versioned VersionUpdater
+}
+
+// SetupSuite 测试套件初始化
+func (suite *OptimisticLockTestSuite) SetupSuite() {
+	db, handler, err := setupTestDB()
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.handler = handler
+	versioned, ok := handler.(VersionUpdater)
+	suite.Require().True(ok)
+	suite.versioned = versioned
+	suite.Require().NoError(handler.AutoMigrate(&TestVersionedArticle{}, &TestTaggedArticle{}, &TestUnsignedArticle{}))
+}
+
+// TearDownSuite 测试套件清理
+func (suite *OptimisticLockTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		suite.handler.Close()
+	}
+}
+
+// TestUpdateWithVersionModel 测试按模型更新并自增版本号
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionModel() {
+	article := TestVersionedArticle{Title: "v1", Version: 1}
+	suite.Require().NoError(suite.db.Create(&article).Error)
+
+	article.Title = "v2"
+	suite.NoError(suite.versioned.UpdateWithVersion(&article, nil))
+	suite.Equal(int64(2), article.Version)
+
+	var stored TestVersionedArticle
+	suite.NoError(suite.db.First(&stored, article.ID).Error)
+	suite.Equal("v2", stored.Title)
+	suite.Equal(int64(2), stored.Version)
+}
+
+// TestUpdateWithVersionMap 测试按 map 更新
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionMap() {
+	article := TestVersionedArticle{Title: "map", Version: 1}
+	suite.Require().NoError(suite.db.Create(&article).Error)
+
+	err := UpdateWithVersion(suite.db, &article, map[string]interface{}{"content": "updated"})
+	suite.NoError(err)
+	suite.Equal(int64(2), article.Version)
+
+	var stored TestVersionedArticle
+	suite.NoError(suite.db.First(&stored, article.ID).Error)
+	suite.Equal("updated", stored.Content)
+	suite.Equal(int64(2), stored.Version)
+}
+
+// TestUpdateWithVersionStale 测试并发修改时返回 ErrStaleObject
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionStale() {
+	article := TestVersionedArticle{Title: "origin", Version: 1}
+	suite.Require().NoError(suite.db.Create(&article).Error)
+
+	first := article
+	second := article
+
+	first.Title = "first"
+	suite.NoError(suite.versioned.UpdateWithVersion(&first, nil))
+
+	second.Title = "second"
+	err := suite.versioned.UpdateWithVersion(&second, nil)
+	suite.Error(err)
+	suite.True(errors.Is(err, ErrStaleObject))
+
+	var staleErr *StaleObjectError
+	suite.True(errors.As(err, &staleErr))
+	suite.Equal("test_versioned_articles", staleErr.Table)
+	suite.Equal(int64(1), staleErr.Version)
+	// 冲突后版本号保持不变
+	suite.Equal(int64(1), second.Version)
+
+	var stored TestVersionedArticle
+	suite.NoError(suite.db.First(&stored, article.ID).Error)
+	suite.Equal("first", stored.Title)
+}
+
+// TestUpdateWithVersionTag 测试通过标签指定版本号字段
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionTag() {
+	article := TestTaggedArticle{Title: "tag", Revision: 1}
+	suite.Require().NoError(suite.db.Create(&article).Error)
+
+	article.Title = "tag2"
+	suite.NoError(suite.versioned.UpdateWithVersion(&article, nil))
+	suite.Equal(2, article.Revision)
+}
+
+// TestUpdateWithVersionUnsigned 测试无符号整型版本号字段
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionUnsigned() {
+	article := TestUnsignedArticle{Title: "unsigned", Version: 1}
+	suite.Require().NoError(suite.db.Create(&article).Error)
+
+	stale := article
+	article.Title = "unsigned2"
+	suite.NoError(suite.versioned.UpdateWithVersion(&article, nil))
+	suite.Equal(uint32(2), article.Version)
+
+	stale.Title = "stale"
+	suite.ErrorIs(suite.versioned.UpdateWithVersion(&stale, nil), ErrStaleObject)
+	suite.Equal(uint32(1), stale.Version)
+}
+
+// TestUpdateWithVersionInvalid 测试非法参数
+func (suite *OptimisticLockTestSuite) TestUpdateWithVersionInvalid() {
+	// 模型中没有版本号字段
+	user := TestUser{ID: 1}
+	suite.ErrorIs(suite.versioned.UpdateWithVersion(&user, nil), ErrVersionFieldNotFound)
+
+	// 主键为空
+	article := TestVersionedArticle{Version: 1}
+	suite.ErrorIs(suite.versioned.UpdateWithVersion(&article, nil), gorm.ErrPrimaryKeyRequired)
+
+	// 不支持的更新参数
+	article.ID = 1
+	suite.Error(suite.versioned.UpdateWithVersion(&article, "title = 1"))
+}
+
+// TestStaleObjectResponse 测试冲突错误映射为 HTTP 409
+func (suite *OptimisticLockTestSuite) TestStaleObjectResponse() {
+	err := &StaleObjectError{Table: "test", PrimaryKey: 1, Version: 1}
+	respOption := response.NewErrorResponseOption(err)
+	suite.Equal(response.StatusConflict, respOption.HttpCode)
+	suite.Equal(response.SceneCode(response.DataConflict), respOption.Code)
+}
+
+// TestOptimisticLockTestSuite 运行乐观锁测试套件
+func TestOptimisticLockTestSuite(t *testing.T) {
+	suite.Run(t, new(OptimisticLockTestSuite))
+}
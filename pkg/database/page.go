@@ -13,10 +13,13 @@ package database
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -47,7 +50,34 @@ const (
 	/** 模糊查询 */
 	lk = "lk:"
 
-	/** ------- or 条件 ------  */
+	/** 不等于 <> */
+	ne = "ne:"
+
+	/** 后缀模糊查询 LIKE %value */
+	elk = "elk:"
+
+	/** 包含模糊查询 LIKE %value% */
+	clk = "clk:"
+
+	/** 包含 IN，多个值以逗号分隔 */
+	in = "in:"
+
+	/** 不包含 NOT IN，多个值以逗号分隔 */
+	nin = "nin:"
+
+	/** 为空 IS NULL */
+	null = "null:"
+
+	/** 不为空 IS NOT NULL */
+	notnull = "notnull:"
+
+	/** 区间 BETWEEN，格式 bt:start,end，任一边界为空时为单边条件 */
+	bt = "bt:"
+
+	/** ------- or 条件（所有 or 条件组成一个括号包裹的 OR 组，与其他条件 AND 连接） ------  */
+
+	/** or 条件前缀，与任意 and 运算符组合，如 orne:、orin:、orbt: */
+	orPrefix = "or"
 
 	/** 小于 */
 	orlt = "orlt:"
@@ -67,6 +97,11 @@ const (
 	/** 模糊查询 */
 	orlk = "orlk:"
 
+	/** ------- JSON 字段查询 ------  */
+
+	/** JSON 路径查询，格式 json:路径:运算符:值，如 json:$.a.b:gte:18、json:$.a:has、json:$:cs:["vip"] */
+	jsonPrefix = "json:"
+
 	/** ------- 排序 ------  */
 
 	/** 降序 */
@@ -103,12 +138,21 @@ type PageInfo struct {
 	/** 表名 仅限于指定表名去查询 */
 	TableName string
 
-	/** 查询 and 条件参数 */
+	/** 查询 and 条件参数，key 相同的条件会被覆盖，PageParam 只写入单参数的条件，完整条件见 AndConditions */
 	AndParams map[string]interface{}
 
-	/** 查询 or 条件参数 */
+	/** 查询 or 条件参数，key 相同的条件会被覆盖，PageParam 只写入单参数的条件，完整条件见 OrConditions */
 	OrParams map[string]interface{}
 
+	/** 查询 and 条件，按参数顺序保存，同一字段可以出现多次 */
+	AndConditions []*PageCondition
+
+	/** 查询 or 条件，整体作为一个括号包裹的 OR 组与其他条件 AND 连接 */
+	OrConditions []*PageCondition
+
+	/** JSON 字段查询条件，按数据库方言生成 */
+	JSONParams []*JSONFilter
+
 	/** 排序 */
 	OrderStr string
 }
@@ -148,89 +192,238 @@ func parseBasicParams(key, value string, pageInfo *PageInfo) bool {
 	return false
 }
 
-// parseAndCondition 解析AND条件参数
-func parseAndCondition(key, value string, andParams map[string]interface{}) bool {
-	key = CamelToCase(key)
-	
-	switch {
-	case strings.Index(value, lt) == 0:
-		value = strings.Replace(value, lt, "", 1)
-		if value != "" {
-			andParams[key+" < ?"] = value
+// PageCondition 分页查询条件
+type PageCondition struct {
+
+	/** 条件语句，如 age >= ? */
+	Query string
+
+	/** 条件参数 */
+	Args []interface{}
+}
+
+// andOperators and 条件运算符，按前缀匹配
+var andOperators = []string{lt, lte, gt, gte, eq, ne, lk, elk, clk, in, nin, null, notnull, bt}
+
+// buildCondition 根据运算符生成查询条件，值无效时返回 nil
+func buildCondition(field, operator, value string) *PageCondition {
+	switch operator {
+	case null:
+		return &PageCondition{Query: field + " IS NULL"}
+	case notnull:
+		return &PageCondition{Query: field + " IS NOT NULL"}
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	switch operator {
+	case lt:
+		return &PageCondition{Query: field + " < ?", Args: []interface{}{value}}
+	case lte:
+		return &PageCondition{Query: field + " <= ?", Args: []interface{}{value}}
+	case gt:
+		return &PageCondition{Query: field + " > ?", Args: []interface{}{value}}
+	case gte:
+		return &PageCondition{Query: field + " >= ?", Args: []interface{}{value}}
+	case ne:
+		return &PageCondition{Query: field + " <> ?", Args: []interface{}{value}}
+	case lk:
+		return &PageCondition{Query: field + " LIKE ?", Args: []interface{}{value + "%"}}
+	case elk:
+		return &PageCondition{Query: field + " LIKE ?", Args: []interface{}{"%" + value}}
+	case clk:
+		return &PageCondition{Query: field + " LIKE ?", Args: []interface{}{"%" + value + "%"}}
+	case in, nin:
+		values := splitValues(value)
+		if len(values) == 0 {
+			return nil
 		}
-	case strings.Index(value, lte) == 0:
-		value = strings.Replace(value, lte, "", 1)
-		if value != "" {
-			andParams[key+" <= ?"] = value
+		if operator == nin {
+			return &PageCondition{Query: field + " NOT IN (?)", Args: []interface{}{values}}
 		}
-	case strings.Index(value, gt) == 0:
-		value = strings.Replace(value, gt, "", 1)
-		if value != "" {
-			andParams[key+" > ?"] = value
+		return &PageCondition{Query: field + " IN (?)", Args: []interface{}{values}}
+	case bt:
+		bounds := strings.SplitN(value, ",", 2)
+		start := strings.TrimSpace(bounds[0])
+		end := ""
+		if len(bounds) == 2 {
+			end = strings.TrimSpace(bounds[1])
 		}
-	case strings.Index(value, gte) == 0:
-		value = strings.Replace(value, gte, "", 1)
-		if value != "" {
-			andParams[key+" >= ?"] = value
+		switch {
+		case start != "" && end != "":
+			return &PageCondition{Query: field + " BETWEEN ? AND ?", Args: []interface{}{start, end}}
+		case start != "":
+			return &PageCondition{Query: field + " >= ?", Args: []interface{}{start}}
+		case end != "":
+			return &PageCondition{Query: field + " <= ?", Args: []interface{}{end}}
 		}
-	case strings.Index(value, lk) == 0:
-		value = strings.Replace(value, lk, "", 1)
-		if value != "" {
-			andParams[key+" LIKE ?"] = value + "%"
+		return nil
+	default:
+		return &PageCondition{Query: field + " = ?", Args: []interface{}{value}}
+	}
+}
+
+// splitValues 拆分逗号分隔的值并去除空值
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
 		}
-	case strings.Index(value, eq) == 0:
-		value = strings.Replace(value, eq, "", 1)
-		if value != "" {
-			andParams[key+" = ?"] = value
+	}
+	return values
+}
+
+// matchOperator 匹配运算符前缀
+func matchOperator(value string) (operator string, ok bool) {
+	for _, op := range andOperators {
+		if strings.Index(value, op) == 0 {
+			return op, true
 		}
-	default:
+	}
+	return "", false
+}
+
+// andCondition 解析AND条件，值无效时返回 nil
+func andCondition(key, value string) *PageCondition {
+	operator, ok := matchOperator(value)
+	if ok {
+		value = strings.Replace(value, operator, "", 1)
+	} else {
 		// 默认等于条件
-		if value != "" {
-			andParams[key+" = ?"] = value
+		operator = eq
+	}
+	return buildCondition(CamelToCase(key), operator, value)
+}
+
+// orCondition 解析OR条件，不是 or 条件时 ok 为 false，值无效时 condition 为 nil
+func orCondition(key, value string) (condition *PageCondition, ok bool) {
+	if strings.Index(value, orPrefix) != 0 {
+		return nil, false
+	}
+	operator, ok := matchOperator(strings.Replace(value, orPrefix, "", 1))
+	if !ok {
+		return nil, false
+	}
+	value = strings.Replace(value, orPrefix+operator, "", 1)
+	return buildCondition(CamelToCase(key), operator, value), true
+}
+
+// setParam 单参数的条件同时写入 AndParams、OrParams，兼容直接读取这两个 map 的调用方
+func setParam(params map[string]interface{}, condition *PageCondition) {
+	if condition != nil && len(condition.Args) == 1 && strings.HasSuffix(condition.Query, " ?") {
+		params[condition.Query] = condition.Args[0]
+	}
+}
+
+// hasCondition 条件中是否已有相同的单参数条件，避免 FindPage 重复拼接 PageParam 同时写入的条件
+func hasCondition(conditions []*PageCondition, query string, arg interface{}) bool {
+	for _, condition := range conditions {
+		if condition.Query == query && len(condition.Args) == 1 && reflect.DeepEqual(condition.Args[0], arg) {
+			return true
 		}
 	}
+	return false
+}
+
+// parseAndCondition 解析AND条件参数
+func parseAndCondition(key, value string, andParams map[string]interface{}) bool {
+	setParam(andParams, andCondition(key, value))
 	return true
 }
 
 // parseOrCondition 解析OR条件参数
 func parseOrCondition(key, value string, orParams map[string]interface{}) bool {
+	condition, ok := orCondition(key, value)
+	if ok {
+		setParam(orParams, condition)
+	}
+	return ok
+}
+
+// joinOrConditions 将 or 条件合并为一个 OR 组，与其他条件一同使用时 gorm 会为其加上括号
+func joinOrConditions(orConditions []*PageCondition, orParams map[string]interface{}) *PageCondition {
+	var queries []string
+	var args []interface{}
+	for _, condition := range orConditions {
+		queries = append(queries, condition.Query)
+		args = append(args, condition.Args...)
+	}
+	keys := make([]string, 0, len(orParams))
+	for k := range orParams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if hasCondition(orConditions, k, orParams[k]) {
+			continue
+		}
+		queries = append(queries, k)
+		args = append(args, orParams[k])
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+	return &PageCondition{Query: strings.Join(queries, " OR "), Args: args}
+}
+
+// jsonOperatorAlias URL参数中的JSON比较运算符
+var jsonOperatorAlias = map[string]string{
+	"eq":  "=",
+	"ne":  "<>",
+	"lt":  "<",
+	"lte": "<=",
+	"gt":  ">",
+	"gte": ">=",
+	"lk":  "LIKE",
+}
+
+// parseJSONCondition 解析JSON字段查询参数，参数无效时返回错误，避免丢弃条件后扩大查询范围
+func parseJSONCondition(key, value string, jsonParams *[]*JSONFilter) (bool, error) {
+	if strings.Index(value, jsonPrefix) != 0 {
+		return false, nil
+	}
 	key = CamelToCase(key)
-	
+	parts := strings.SplitN(strings.Replace(value, jsonPrefix, "", 1), ":", 3)
+	path := parts[0]
+
+	var filter *JSONFilter
+	var err error
 	switch {
-	case strings.Index(value, orlt) == 0:
-		value = strings.Replace(value, orlt, "", 1)
-		if value != "" {
-			orParams[key+" < ?"] = value
-		}
-	case strings.Index(value, orlte) == 0:
-		value = strings.Replace(value, orlte, "", 1)
-		if value != "" {
-			orParams[key+" <= ?"] = value
-		}
-	case strings.Index(value, orgte) == 0:
-		value = strings.Replace(value, orgte, "", 1)
-		if value != "" {
-			orParams[key+" >= ?"] = value
-		}
-	case strings.Index(value, orgt) == 0:
-		value = strings.Replace(value, orgt, "", 1)
-		if value != "" {
-			orParams[key+" > ?"] = value
-		}
-	case strings.Index(value, orlk) == 0:
-		value = strings.Replace(value, orlk, "", 1)
-		if value != "" {
-			orParams[key+" LIKE ?"] = value + "%"
-		}
-	case strings.Index(value, oreq) == 0:
-		value = strings.Replace(value, oreq, "", 1)
-		if value != "" {
-			orParams[key+" = ?"] = value
+	case len(parts) == 2 && parts[1] == "has":
+		filter, err = NewJSONHasKeyFilter(key, path)
+	case len(parts) == 3 && parts[1] == "cs":
+		filter, err = NewJSONContainsPathFilter(key, path, decodeJSONValue(parts[2]))
+	case len(parts) == 3 && jsonOperatorAlias[parts[1]] != "":
+		operator := jsonOperatorAlias[parts[1]]
+		if operator == "LIKE" {
+			filter, err = NewJSONFilter(key, path, operator, parts[2]+"%")
+		} else {
+			filter, err = NewJSONFilter(key, path, operator, decodeJSONValue(parts[2]))
 		}
+	case len(parts) >= 2:
+		// 省略运算符时默认等于
+		filter, err = NewJSONFilter(key, path, "=", decodeJSONValue(strings.Join(parts[1:], ":")))
 	default:
-		return false
+		return true, fmt.Errorf("%w: %s", ErrInvalidJSONPath, value)
 	}
-	return true
+
+	if err != nil {
+		return true, fmt.Errorf("JSON查询参数解析异常：%w", err)
+	}
+	*jsonParams = append(*jsonParams, filter)
+	return true, nil
+}
+
+// decodeJSONValue 将URL参数值按JSON解析（数值、布尔、带引号的字符串、数组和对象），解析失败时按原字符串处理
+func decodeJSONValue(value string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+	return v
 }
 
 // processOrderString 处理排序字符串
@@ -245,18 +438,29 @@ func processOrderString(orderStr string) string {
 	return v
 }
 
-// PageParam 获取url查询参数
+// PageParam 获取url查询参数，参数无效时返回空，需要错误信息时使用 ParsePageParam
 func PageParam(c *gin.Context) *PageInfo {
+	pageInfo, err := ParsePageParam(c)
+	if err != nil {
+		log.Println(err.Error())
+		return nil
+	}
+	return pageInfo
+}
+
+// ParsePageParam 获取url查询参数，参数无法解析时返回错误
+func ParsePageParam(c *gin.Context) (*PageInfo, error) {
 	s := c.Request.URL.RawQuery
 	paramStr, err := url.QueryUnescape(s)
 	if err != nil {
-		log.Println("url参数decode异常：" + err.Error())
-		return nil
+		return nil, fmt.Errorf("url参数decode异常：%w", err)
 	}
 	
 	pageInfo := PageInfo{}
 	andParams := make(map[string]interface{})
 	orParams := make(map[string]interface{})
+	var andConditions, orConditions []*PageCondition
+	var jsonParams []*JSONFilter
 	
 	paramArr := strings.Split(paramStr, "&")
 	for _, v := range paramArr {
@@ -278,20 +482,37 @@ func PageParam(c *gin.Context) *PageInfo {
 			continue
 		}
 		
+		// 处理JSON字段查询
+		if ok, err := parseJSONCondition(key, value, &jsonParams); err != nil {
+			return nil, err
+		} else if ok {
+			continue
+		}
+		
 		// 先尝试处理OR条件
-		if parseOrCondition(key, value, orParams) {
+		if condition, ok := orCondition(key, value); ok {
+			if condition != nil {
+				orConditions = append(orConditions, condition)
+				setParam(orParams, condition)
+			}
 			continue
 		}
 		
 		// 处理AND条件（包括默认等于条件）
-		parseAndCondition(key, value, andParams)
+		if condition := andCondition(key, value); condition != nil {
+			andConditions = append(andConditions, condition)
+			setParam(andParams, condition)
+		}
 	}
 	
 	// 处理排序字符串
 	pageInfo.OrderStr = processOrderString(pageInfo.OrderStr)
 	pageInfo.AndParams = andParams
 	pageInfo.OrParams = orParams
-	return &pageInfo
+	pageInfo.AndConditions = andConditions
+	pageInfo.OrConditions = orConditions
+	pageInfo.JSONParams = jsonParams
+	return &pageInfo, nil
 }
 
 func CamelToCase(name string) string {
@@ -382,17 +603,28 @@ func FindPage(v interface{}, rows interface{}, pageInfo *PageInfo) (pageBean *Pa
 		db = global.DB.Table(convert.MustString(v))
 	}
 	andCons := pageInfo.AndParams
-	orCons := pageInfo.OrParams
 	orderStr := pageInfo.OrderStr
 	if len(andCons) > 0 {
 		for k, v := range andCons {
+			if hasCondition(pageInfo.AndConditions, k, v) {
+				continue
+			}
 			db = db.Where(k, v)
 		}
 	}
-	if len(orCons) > 0 {
-		for k, v := range orCons {
-			db = db.Or(k, v)
+	for _, condition := range pageInfo.AndConditions {
+		db = db.Where(condition.Query, condition.Args...)
+	}
+	for _, filter := range pageInfo.JSONParams {
+		condition, args, err := filter.Condition(db.Dialector.Name())
+		if err != nil {
+			return nil, err
 		}
+		db = db.Where(condition, args...)
+	}
+	// 所有 or 条件组成一个 OR 组，与其他条件 AND 连接
+	if orGroup := joinOrConditions(pageInfo.OrConditions, pageInfo.OrParams); orGroup != nil {
+		db = db.Where(orGroup.Query, orGroup.Args...)
 	}
 	db.Count(&total)
 	if len(orderStr) > 0 {
@@ -137,6 +137,49 @@ func TestParseAndCondition(t *testing.T) {
 	}
 }
 
+// TestAndCondition 测试AND条件的运算符
+func TestAndCondition(t *testing.T) {
+	tests := []struct {
+		key      string
+		value    string
+		expected string
+		args     []interface{}
+	}{
+		{"userName", "lt:10", "user_name < ?", []interface{}{"10"}},
+		{"userName", "lte:20", "user_name <= ?", []interface{}{"20"}},
+		{"userName", "gt:5", "user_name > ?", []interface{}{"5"}},
+		{"userName", "gte:15", "user_name >= ?", []interface{}{"15"}},
+		{"userName", "lk:test", "user_name LIKE ?", []interface{}{"test%"}},
+		{"userName", "elk:test", "user_name LIKE ?", []interface{}{"%test"}},
+		{"userName", "clk:test", "user_name LIKE ?", []interface{}{"%test%"}},
+		{"userName", "eq:admin", "user_name = ?", []interface{}{"admin"}},
+		{"userName", "ne:admin", "user_name <> ?", []interface{}{"admin"}},
+		{"status", "in:1, 2,,3", "status IN (?)", []interface{}{[]string{"1", "2", "3"}}},
+		{"status", "nin:4,5", "status NOT IN (?)", []interface{}{[]string{"4", "5"}}},
+		{"deletedAt", "null:", "deleted_at IS NULL", nil},
+		{"deletedAt", "notnull:", "deleted_at IS NOT NULL", nil},
+		{"age", "bt:18,30", "age BETWEEN ? AND ?", []interface{}{"18", "30"}},
+		{"age", "bt:18,", "age >= ?", []interface{}{"18"}},
+		{"age", "bt:,30", "age <= ?", []interface{}{"30"}},
+		{"userName", "john", "user_name = ?", []interface{}{"john"}}, // 默认等于
+		{"userName", "", "", nil},                                    // 空值不处理
+		{"status", "in:", "", nil},                                   // 空列表不处理
+		{"age", "bt:,", "", nil},                                     // 空区间不处理
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"_"+tt.value, func(t *testing.T) {
+			condition := andCondition(tt.key, tt.value)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, condition.Query)
+				assert.Equal(t, tt.args, condition.Args)
+			} else {
+				assert.Nil(t, condition)
+			}
+		})
+	}
+}
+
 // TestParseOrCondition 测试OR条件解析
 func TestParseOrCondition(t *testing.T) {
 	tests := []struct {
@@ -171,6 +214,46 @@ func TestParseOrCondition(t *testing.T) {
 	}
 }
 
+// TestOrCondition 测试OR条件的运算符
+func TestOrCondition(t *testing.T) {
+	tests := []struct {
+		key      string
+		value    string
+		expected string
+		args     []interface{}
+	}{
+		{"userName", "orlt:10", "user_name < ?", []interface{}{"10"}},
+		{"userName", "orlte:20", "user_name <= ?", []interface{}{"20"}},
+		{"userName", "orgt:5", "user_name > ?", []interface{}{"5"}},
+		{"userName", "orgte:15", "user_name >= ?", []interface{}{"15"}},
+		{"userName", "orlk:test", "user_name LIKE ?", []interface{}{"test%"}},
+		{"userName", "orelk:test", "user_name LIKE ?", []interface{}{"%test"}},
+		{"userName", "orclk:test", "user_name LIKE ?", []interface{}{"%test%"}},
+		{"userName", "oreq:admin", "user_name = ?", []interface{}{"admin"}},
+		{"userName", "orne:admin", "user_name <> ?", []interface{}{"admin"}},
+		{"status", "orin:1,2", "status IN (?)", []interface{}{[]string{"1", "2"}}},
+		{"status", "ornin:1,2", "status NOT IN (?)", []interface{}{[]string{"1", "2"}}},
+		{"deletedAt", "ornull:", "deleted_at IS NULL", nil},
+		{"deletedAt", "ornotnull:", "deleted_at IS NOT NULL", nil},
+		{"age", "orbt:18,30", "age BETWEEN ? AND ?", []interface{}{"18", "30"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"_"+tt.value, func(t *testing.T) {
+			condition, ok := orCondition(tt.key, tt.value)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, condition.Query)
+			assert.Equal(t, tt.args, condition.Args)
+		})
+	}
+
+	// 非 or 条件交给 and 条件处理
+	for _, value := range []string{"oracle", "gt:1"} {
+		_, ok := orCondition("userName", value)
+		assert.False(t, ok, value)
+	}
+}
+
 // TestProcessOrderString 测试排序字符串处理
 func TestProcessOrderString(t *testing.T) {
 	tests := []struct {
@@ -294,6 +377,126 @@ func TestPageParam(t *testing.T) {
 	assert.Contains(t, pageInfo.AndParams, "age > ?")
 }
 
+// TestPageParamRepeatedKeys 测试同一字段的多个条件不会互相覆盖
+func TestPageParamRepeatedKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	c.Request = &http.Request{
+		URL: &url.URL{
+			RawQuery: "age=gte:18&age=lt:30&status=orin:1,2&status=oreq:3",
+		},
+	}
+
+	pageInfo := PageParam(c)
+
+	assert.Len(t, pageInfo.AndConditions, 2)
+	assert.Equal(t, "age >= ?", pageInfo.AndConditions[0].Query)
+	assert.Equal(t, "age < ?", pageInfo.AndConditions[1].Query)
+	assert.Len(t, pageInfo.OrConditions, 2)
+}
+
+// TestFindPageOrGroup 测试 or 条件组成一个 OR 组并与其他条件 AND 连接
+func TestFindPageOrGroup(t *testing.T) {
+	db, handler, err := setupTestDB()
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	originalDB := global.DB
+	global.DB = db
+	defer func() { global.DB = originalDB }()
+	assert.NoError(t, db.Exec("DELETE FROM test_users").Error)
+	assert.NoError(t, seedTestData(db))
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	c.Request = &http.Request{
+		URL: &url.URL{
+			RawQuery: "status=1&age=bt:26,33&username=orclk:smith&email=orelk:bob@test.com",
+		},
+	}
+	pageInfo := PageParam(c)
+	pageInfo.Current = 1
+	pageInfo.RowCount = 10
+
+	// 不加括号时 bob_wilson（status=2、age=35）也会命中 OR 条件
+	var users []TestUser
+	pageResult, err := FindPage(&TestUser{}, &users, pageInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), pageResult.Total)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "jane_smith", users[0].Username)
+}
+
+// TestFindPageLegacyParams 测试直接追加到 AndParams、OrParams 的条件
+func TestFindPageLegacyParams(t *testing.T) {
+	db, handler, err := setupTestDB()
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	originalDB := global.DB
+	global.DB = db
+	defer func() { global.DB = originalDB }()
+	assert.NoError(t, db.Exec("DELETE FROM test_users").Error)
+	assert.NoError(t, seedTestData(db))
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	c.Request = &http.Request{
+		URL: &url.URL{
+			RawQuery: "status=1&username=orclk:john",
+		},
+	}
+	pageInfo := PageParam(c)
+	assert.Equal(t, "1", pageInfo.AndParams["status = ?"])
+	assert.Equal(t, "%john%", pageInfo.OrParams["username LIKE ?"])
+	pageInfo.AndParams["business_id = ?"] = 1
+	pageInfo.OrParams["username = ?"] = "alice_brown"
+	pageInfo.Current = 1
+	pageInfo.RowCount = 10
+
+	var users []TestUser
+	pageResult, err := FindPage(&TestUser{}, &users, pageInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pageResult.Total)
+	for _, user := range users {
+		assert.Contains(t, []string{"john_doe", "alice_brown"}, user.Username)
+	}
+}
+
+// TestFindPageWithOperators 测试新增运算符的分页查询
+func TestFindPageWithOperators(t *testing.T) {
+	db, handler, err := setupTestDB()
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	originalDB := global.DB
+	global.DB = db
+	defer func() { global.DB = originalDB }()
+	assert.NoError(t, seedTestData(db))
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	c.Request = &http.Request{
+		URL: &url.URL{
+			RawQuery: "status=ne:2&age=bt:26,33&age=lt:32&username=orelk:smith&tags=orin:vip",
+		},
+	}
+	pageInfo := PageParam(c)
+	pageInfo.Current = 1
+	pageInfo.RowCount = 100
+
+	var users []TestUser
+	pageResult, err := FindPage(&TestUser{}, &users, pageInfo)
+	assert.NoError(t, err)
+	assert.Greater(t, pageResult.Total, int64(0))
+	for _, user := range users {
+		assert.NotEqual(t, 2, user.Status)
+		assert.GreaterOrEqual(t, user.Age, 26)
+		assert.Less(t, user.Age, 32)
+		assert.True(t, user.Username == "jane_smith" || user.Tags == "vip", user.Username)
+	}
+}
+
 // TestFindPage 测试分页查询
 func TestFindPage(t *testing.T) {
 	// 设置测试数据库
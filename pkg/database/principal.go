@@ -0,0 +1,280 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 18:32:50
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 18:32:50
+ * @FilePath: \go-core\pkg\database\principal.go
+ * @Description: 基于 context 中当前用户的审计字段填充与租户隔离
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// CreatedByFieldName 约定的创建人字段名
+	CreatedByFieldName = "CreatedBy"
+	// UpdatedByFieldName 约定的更新人字段名
+	UpdatedByFieldName = "UpdatedBy"
+	// TenantFieldName 约定的租户字段名
+	TenantFieldName = "TenantId"
+
+	skipTenantKey = "goc:skip_tenant"
+)
+
+// ErrTenantMismatch 写入的租户与当前租户不一致
+var ErrTenantMismatch = errors.New("不能写入或转移到其他租户")
+
+// Principal 当前操作者，jwt.Claims 均已实现
+type Principal interface {
+	GetUserId() string
+}
+
+// TenantPrincipal 携带租户的操作者，jwt.CustomClaims 以商户号作为租户
+type TenantPrincipal interface {
+	Principal
+	GetTenantId() string
+}
+
+// PrincipalFrom 从 context 中获取当前操作者，由 JWT 中间件或 jwt.WithClaims 写入
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	claims, ok := jwt.ClaimsFrom(ctx)
+	if !ok {
+		return nil, false
+	}
+	return claims, true
+}
+
+// TenantFrom 从 context 中获取当前租户
+func TenantFrom(ctx context.Context) (string, bool) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return "", false
+	}
+	tenant, ok := principal.(TenantPrincipal)
+	if !ok || tenant.GetTenantId() == "" {
+		return "", false
+	}
+	return tenant.GetTenantId(), true
+}
+
+// AuditPlugin 审计字段插件，创建时填充创建人与更新人，更新时填充更新人
+// 需要通过 db.WithContext(ctx) 传入携带当前用户的 context，模型中没有对应字段时忽略
+//
+//	db.Use(&database.AuditPlugin{})
+type AuditPlugin struct {
+	CreatedByField string // 创建人字段，默认 CreatedBy
+	UpdatedByField string // 更新人字段，默认 UpdatedBy
+}
+
+// Name 实现 gorm.Plugin
+func (p *AuditPlugin) Name() string {
+	return "goc:audit"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	if p.CreatedByField == "" {
+		p.CreatedByField = CreatedByFieldName
+	}
+	if p.UpdatedByField == "" {
+		p.UpdatedByField = UpdatedByFieldName
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("goc:audit_create", p.beforeCreate); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("goc:audit_update", p.beforeUpdate)
+}
+
+// beforeCreate 填充创建人与更新人，已有值时保留
+func (p *AuditPlugin) beforeCreate(db *gorm.DB) {
+	principal, ok := PrincipalFrom(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField(p.CreatedByField); field != nil {
+		setZeroField(db, field, principal.GetUserId())
+	}
+	if field := db.Statement.Schema.LookUpField(p.UpdatedByField); field != nil {
+		setZeroField(db, field, principal.GetUserId())
+	}
+}
+
+// beforeUpdate 填充更新人
+func (p *AuditPlugin) beforeUpdate(db *gorm.DB) {
+	principal, ok := PrincipalFrom(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	if field := db.Statement.Schema.LookUpField(p.UpdatedByField); field != nil {
+		db.Statement.SetColumn(field.DBName, principal.GetUserId(), true)
+	}
+}
+
+// TenantPlugin 租户隔离插件，context 中的当前用户携带租户时，创建填充租户字段，查询、更新、删除追加租户条件
+// 创建或更新时写入其他租户返回 ErrTenantMismatch，模型中没有租户字段或 context 中没有租户时不做处理，跨租户操作使用 SkipTenant
+//
+//	db.Use(&database.TenantPlugin{})
+type TenantPlugin struct {
+	TenantField string // 租户字段，默认 TenantId
+}
+
+// Name 实现 gorm.Plugin
+func (p *TenantPlugin) Name() string {
+	return "goc:tenant"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *TenantPlugin) Initialize(db *gorm.DB) error {
+	if p.TenantField == "" {
+		p.TenantField = TenantFieldName
+	}
+	callback := db.Callback()
+	if err := callback.Create().Before("gorm:create").Register("goc:tenant_create", p.beforeCreate); err != nil {
+		return err
+	}
+	if err := callback.Query().Before("gorm:query").Register("goc:tenant_query", p.addCondition); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("goc:tenant_update", p.beforeUpdate); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:delete").Register("goc:tenant_delete", p.addCondition); err != nil {
+		return err
+	}
+	return callback.Row().Before("gorm:row").Register("goc:tenant_row", p.addCondition)
+}
+
+// tenantField 获取当前租户与模型的租户字段
+func (p *TenantPlugin) tenantField(db *gorm.DB) (string, *schema.Field, bool) {
+	if skip, ok := db.Get(skipTenantKey); ok && skip == true {
+		return "", nil, false
+	}
+	tenant, ok := TenantFrom(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return "", nil, false
+	}
+	field := db.Statement.Schema.LookUpField(p.TenantField)
+	return tenant, field, field != nil
+}
+
+// beforeCreate 填充租户字段，指定了其他租户时报错
+func (p *TenantPlugin) beforeCreate(db *gorm.DB) {
+	if tenant, field, ok := p.tenantField(db); ok {
+		if otherTenant(field, db.Statement.ReflectValue, tenant) {
+			db.AddError(ErrTenantMismatch)
+			return
+		}
+		setZeroField(db, field, tenant)
+	}
+}
+
+// beforeUpdate 拒绝把租户字段改为其他租户，并追加租户条件
+func (p *TenantPlugin) beforeUpdate(db *gorm.DB) {
+	tenant, field, ok := p.tenantField(db)
+	if !ok {
+		return
+	}
+	dest := reflect.ValueOf(db.Statement.Dest)
+	if otherTenant(field, dest, tenant) {
+		db.AddError(ErrTenantMismatch)
+		return
+	}
+	// Save 会写入全部字段，租户字段为零值时填充当前租户，避免数据被移出租户
+	if model := reflect.ValueOf(db.Statement.Model); dest.Kind() == reflect.Ptr && model.Kind() == reflect.Ptr && dest.Pointer() == model.Pointer() {
+		setZeroField(db, field, tenant)
+	}
+	p.addCondition(db)
+}
+
+// addCondition 追加租户条件
+func (p *TenantPlugin) addCondition(db *gorm.DB) {
+	if tenant, field, ok := p.tenantField(db); ok {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant},
+		}})
+	}
+}
+
+// SkipTenant 跳过租户隔离，用于后台任务等需要跨租户访问的场景
+func SkipTenant(db *gorm.DB) *gorm.DB {
+	return db.Set(skipTenantKey, true)
+}
+
+// otherTenant 检查写入值中是否有非零且不是当前租户的租户字段，支持结构体、结构体切片与 map
+func otherTenant(field *schema.Field, rv reflect.Value, tenant string) bool {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if otherTenant(field, rv.Index(i), tenant) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			if name := fmt.Sprint(key.Interface()); name == field.DBName || name == field.Name {
+				if value, ok := tenantString(rv.MapIndex(key)); !ok || value != tenant {
+					return true
+				}
+			}
+		}
+	case reflect.Struct:
+		if value := rv.FieldByName(field.Name); value.IsValid() && !value.IsZero() {
+			str, ok := tenantString(value)
+			return !ok || str != tenant
+		}
+	}
+	return false
+}
+
+// tenantString 将租户字段的值转为字符串，nil 或 gorm 表达式等无法比较的值返回 false
+func tenantString(value reflect.Value) (string, bool) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(value.Interface()), true
+	}
+	return "", false
+}
+
+// setZeroField 为结构体或结构体切片中值为零的字段赋值
+func setZeroField(db *gorm.DB, field *schema.Field, value interface{}) {
+	ctx := db.Statement.Context
+	setOne := func(rv reflect.Value) {
+		rv = reflect.Indirect(rv)
+		if rv.Kind() != reflect.Struct {
+			return
+		}
+		if _, isZero := field.ValueOf(ctx, rv); isZero {
+			if err := field.Set(ctx, rv, value); err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			setOne(db.Statement.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		setOne(db.Statement.ReflectValue)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 18:50:26
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 18:50:26
+ * @FilePath: \go-core\pkg\database\principal_test.go
+ * @Description: 审计字段与租户隔离插件测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kamalyes/go-core/pkg/jwt"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TestTenantOrder 带审计字段与租户字段的测试模型
+type TestTenantOrder struct {
+	ID        uint   `gorm:"primarykey"`
+	Title     string `gorm:"size:100"`
+	TenantId  string `gorm:"size:32;index"`
+	CreatedBy string `gorm:"size:36"`
+	UpdatedBy string `gorm:"size:36"`
+}
+
+// TableName 指定表名
+func (TestTenantOrder) TableName() string {
+	return "test_tenant_orders"
+}
+
+// PrincipalTestSuite 审计与租户插件测试套件
+type PrincipalTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	handler Handler
+}
+
+// SetupSuite 测试套件初始化
+func (suite *PrincipalTestSuite) SetupSuite() {
+	db, handler, err := setupTestDB()
+	suite.Require().NoError(err)
+	suite.Require().NoError(db.Use(&AuditPlugin{}))
+	suite.Require().NoError(db.Use(&TenantPlugin{}))
+
+	suite.db = db
+	suite.handler = handler
+	suite.Require().NoError(handler.AutoMigrate(&TestTenantOrder{}))
+}
+
+// TearDownSuite 测试套件清理
+func (suite *PrincipalTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		suite.handler.Close()
+	}
+}
+
+// SetupTest 每个测试前清空数据
+func (suite *PrincipalTestSuite) SetupTest() {
+	suite.Require().NoError(suite.db.Exec("DELETE FROM test_tenant_orders").Error)
+}
+
+// principalContext 创建携带当前用户的 context
+func principalContext(userId, merchantNo string) context.Context {
+	return jwt.WithClaims(context.Background(), &jwt.CustomClaims{UserId: userId, MerchantNo: merchantNo})
+}
+
+// TestPrincipalFrom 测试从 context 获取当前用户与租户
+func (suite *PrincipalTestSuite) TestPrincipalFrom() {
+	principal, ok := PrincipalFrom(principalContext("u1", "m1"))
+	suite.True(ok)
+	suite.Equal("u1", principal.GetUserId())
+
+	tenant, ok := TenantFrom(principalContext("u1", "m1"))
+	suite.True(ok)
+	suite.Equal("m1", tenant)
+
+	_, ok = TenantFrom(principalContext("u1", ""))
+	suite.False(ok)
+	_, ok = PrincipalFrom(context.Background())
+	suite.False(ok)
+}
+
+// TestAuditFields 测试创建与更新时填充审计字段
+func (suite *PrincipalTestSuite) TestAuditFields() {
+	order := TestTenantOrder{Title: "audit"}
+	suite.Require().NoError(suite.db.WithContext(principalContext("creator", "")).Create(&order).Error)
+	suite.Equal("creator", order.CreatedBy)
+	suite.Equal("creator", order.UpdatedBy)
+
+	err := suite.db.WithContext(principalContext("editor", "")).Model(&order).Update("title", "edited").Error
+	suite.Require().NoError(err)
+
+	var stored TestTenantOrder
+	suite.NoError(suite.db.First(&stored, order.ID).Error)
+	suite.Equal("edited", stored.Title)
+	suite.Equal("creator", stored.CreatedBy)
+	suite.Equal("editor", stored.UpdatedBy)
+}
+
+// TestAuditWithoutPrincipal 测试没有当前用户时不填充
+func (suite *PrincipalTestSuite) TestAuditWithoutPrincipal() {
+	order := TestTenantOrder{Title: "anonymous"}
+	suite.Require().NoError(suite.db.Create(&order).Error)
+	suite.Empty(order.CreatedBy)
+	suite.Empty(order.TenantId)
+}
+
+// TestTenantIsolation 测试按租户填充与过滤
+func (suite *PrincipalTestSuite) TestTenantIsolation() {
+	ctxA := principalContext("u1", "tenant-a")
+	ctxB := principalContext("u2", "tenant-b")
+
+	orders := []TestTenantOrder{{Title: "a1"}, {Title: "a2"}}
+	suite.Require().NoError(suite.db.WithContext(ctxA).Create(&orders).Error)
+	suite.Equal("tenant-a", orders[0].TenantId)
+	suite.Equal("tenant-a", orders[1].TenantId)
+	suite.Require().NoError(suite.db.WithContext(ctxB).Create(&TestTenantOrder{Title: "b1"}).Error)
+
+	var visible []TestTenantOrder
+	suite.NoError(suite.db.WithContext(ctxA).Find(&visible).Error)
+	suite.Len(visible, 2)
+
+	var count int64
+	suite.NoError(suite.db.WithContext(ctxB).Model(&TestTenantOrder{}).Count(&count).Error)
+	suite.Equal(int64(1), count)
+
+	// 其他租户的数据不能被更新或删除
+	result := suite.db.WithContext(ctxB).Model(&TestTenantOrder{}).Where("id = ?", orders[0].ID).Update("title", "hacked")
+	suite.NoError(result.Error)
+	suite.Equal(int64(0), result.RowsAffected)
+	result = suite.db.WithContext(ctxB).Delete(&TestTenantOrder{}, orders[0].ID)
+	suite.NoError(result.Error)
+	suite.Equal(int64(0), result.RowsAffected)
+
+	var all []TestTenantOrder
+	suite.NoError(SkipTenant(suite.db.WithContext(ctxA)).Find(&all).Error)
+	suite.Len(all, 3)
+	suite.NoError(suite.db.Find(&all).Error)
+	suite.Len(all, 3)
+}
+
+// TestTenantMismatch 测试不能写入或转移到其他租户
+func (suite *PrincipalTestSuite) TestTenantMismatch() {
+	ctxA := principalContext("u1", "tenant-a")
+
+	err := suite.db.WithContext(ctxA).Create(&TestTenantOrder{Title: "spoof", TenantId: "tenant-b"}).Error
+	suite.ErrorIs(err, ErrTenantMismatch)
+	err = suite.db.WithContext(ctxA).Create(&[]TestTenantOrder{{Title: "a"}, {Title: "spoof", TenantId: "tenant-b"}}).Error
+	suite.ErrorIs(err, ErrTenantMismatch)
+
+	order := TestTenantOrder{Title: "own", TenantId: "tenant-a"}
+	suite.Require().NoError(suite.db.WithContext(ctxA).Create(&order).Error)
+
+	err = suite.db.WithContext(ctxA).Model(&order).Update("tenant_id", "tenant-b").Error
+	suite.ErrorIs(err, ErrTenantMismatch)
+	err = suite.db.WithContext(ctxA).Model(&order).Updates(map[string]interface{}{"title": "moved", "TenantId": "tenant-b"}).Error
+	suite.ErrorIs(err, ErrTenantMismatch)
+	err = suite.db.WithContext(ctxA).Model(&order).Updates(TestTenantOrder{Title: "moved", TenantId: "tenant-b"}).Error
+	suite.ErrorIs(err, ErrTenantMismatch)
+
+	// Save 时租户字段为零值，填充当前租户而不是写入空租户
+	order.TenantId = ""
+	order.Title = "saved"
+	suite.Require().NoError(suite.db.WithContext(ctxA).Save(&order).Error)
+
+	var stored TestTenantOrder
+	suite.NoError(SkipTenant(suite.db).First(&stored, order.ID).Error)
+	suite.Equal("tenant-a", stored.TenantId)
+	suite.Equal("saved", stored.Title)
+
+	var count int64
+	suite.NoError(SkipTenant(suite.db).Model(&TestTenantOrder{}).Count(&count).Error)
+	suite.Equal(int64(1), count)
+
+	// 跨租户操作仍可通过 SkipTenant 完成
+	err = SkipTenant(suite.db.WithContext(ctxA)).Model(&order).Update("tenant_id", "tenant-b").Error
+	suite.NoError(err)
+}
+
+// TestPrincipalSuite 运行审计与租户插件测试套件
+func TestPrincipalSuite(t *testing.T) {
+	suite.Run(t, new(PrincipalTestSuite))
+}
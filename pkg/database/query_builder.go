@@ -84,12 +84,72 @@ func (qb *QueryBuilder) WhereTimeRange(field, startTime, endTime string) *QueryB
 	return qb
 }
 
+// WhereTimeBetween 添加左闭右开的时间范围条件 [start, end)，任一边界为空时为开区间
+func (qb *QueryBuilder) WhereTimeBetween(field string, start, end interface{}) *QueryBuilder {
+	qb.param.AddTimeRangeFilter(field, start, end)
+	return qb
+}
+
+// WhereTimeAfter 添加时间下限条件 field >= start
+func (qb *QueryBuilder) WhereTimeAfter(field string, start interface{}) *QueryBuilder {
+	qb.param.AddTimeRangeFilter(field, start, nil)
+	return qb
+}
+
+// WhereTimeBefore 添加时间上限条件 field < end
+func (qb *QueryBuilder) WhereTimeBefore(field string, end interface{}) *QueryBuilder {
+	qb.param.AddTimeRangeFilter(field, nil, end)
+	return qb
+}
+
 // WhereFindInSet 添加FIND_IN_SET条件
 func (qb *QueryBuilder) WhereFindInSet(field string, values []string) *QueryBuilder {
 	qb.param.AddFindInSet(field, values)
 	return qb
 }
 
+// WhereFullText 添加全文检索条件，SQLite 需要使用 WhereFullTextFilter 指定 FTS5 虚拟表，否则退化为 LIKE
+func (qb *QueryBuilder) WhereFullText(fields []string, query string, mode FullTextMode) *QueryBuilder {
+	qb.param.AddFullText(NewFullTextFilter(fields, query, mode))
+	return qb
+}
+
+// WhereFullTextFilter 添加自定义全文检索条件
+func (qb *QueryBuilder) WhereFullTextFilter(filter *FullTextFilter) *QueryBuilder {
+	qb.param.AddFullText(filter)
+	return qb
+}
+
+// OrderByRelevance 按全文检索相关度排序
+func (qb *QueryBuilder) OrderByRelevance() *QueryBuilder {
+	qb.param.OrderByRelevance(true)
+	return qb
+}
+
+// WhereJSON 添加 JSON 路径取值比较条件，如 WhereJSON("extend", "$.profile.age", ">=", 18)
+func (qb *QueryBuilder) WhereJSON(field, path, operator string, value interface{}) *QueryBuilder {
+	qb.param.AddJSONFilter(NewJSONFilter(field, path, operator, value))
+	return qb
+}
+
+// WhereJSONContains 添加 JSON 包含条件，如 WhereJSONContains("tags", []string{"vip"})
+func (qb *QueryBuilder) WhereJSONContains(field string, value interface{}) *QueryBuilder {
+	qb.param.AddJSONFilter(NewJSONContainsFilter(field, value))
+	return qb
+}
+
+// WhereJSONContainsPath 添加指定路径下的 JSON 包含条件，如 WhereJSONContainsPath("extend", "$.tags", []string{"vip"})
+func (qb *QueryBuilder) WhereJSONContainsPath(field, path string, value interface{}) *QueryBuilder {
+	qb.param.AddJSONFilter(NewJSONContainsPathFilter(field, path, value))
+	return qb
+}
+
+// WhereJSONHasKey 添加 JSON 路径存在条件
+func (qb *QueryBuilder) WhereJSONHasKey(field, path string) *QueryBuilder {
+	qb.param.AddJSONFilter(NewJSONHasKeyFilter(field, path))
+	return qb
+}
+
 // Build 构建查询参数
 func (qb *QueryBuilder) Build() QueryParam {
 	return qb.param
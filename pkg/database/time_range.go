@@ -0,0 +1,203 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 11:02:17
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 11:02:17
+ * @FilePath: \go-core\pkg\database\time_range.go
+ * @Description: 时间范围过滤器及时间值解析
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamalyes/go-core/pkg/global"
+)
+
+// UnixSeconds 秒级时间戳
+type UnixSeconds int64
+
+// UnixMillis 毫秒级时间戳
+type UnixMillis int64
+
+// unixMillisThreshold 未显式声明单位的整数时间戳，绝对值大于该值时按毫秒处理
+const unixMillisThreshold = 1e12
+
+// ErrInvalidTimeValue 无法解析的时间值
+var ErrInvalidTimeValue = errors.New("无法解析的时间值")
+
+// timeSettings 时间解析配置
+var timeSettings = struct {
+	sync.RWMutex
+	location *time.Location
+	layouts  []string
+}{
+	location: time.Local,
+	layouts: []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04:05.000",
+		"2006-01-02T15:04:05",
+		time.RFC3339,
+		time.RFC3339Nano,
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02",
+	},
+}
+
+// SetTimeLocation 设置时间字符串解析及查询参数使用的时区
+func SetTimeLocation(loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	timeSettings.Lock()
+	defer timeSettings.Unlock()
+	timeSettings.location = loc
+}
+
+// GetTimeLocation 获取时间字符串解析及查询参数使用的时区
+func GetTimeLocation() *time.Location {
+	timeSettings.RLock()
+	defer timeSettings.RUnlock()
+	return timeSettings.location
+}
+
+// SetTimeLayouts 设置时间字符串解析格式，按顺序尝试
+func SetTimeLayouts(layouts ...string) {
+	if len(layouts) == 0 {
+		return
+	}
+	timeSettings.Lock()
+	defer timeSettings.Unlock()
+	timeSettings.layouts = append([]string(nil), layouts...)
+}
+
+// GetTimeLayouts 获取时间字符串解析格式
+func GetTimeLayouts() []string {
+	timeSettings.RLock()
+	defer timeSettings.RUnlock()
+	return append([]string(nil), timeSettings.layouts...)
+}
+
+// ParseTimeValue 将时间值解析为配置时区下的 time.Time
+// 支持 time.Time、*time.Time、global.TTime、UnixSeconds、UnixMillis、整数时间戳（自动识别秒/毫秒）以及时间字符串；
+// 值为空（nil、空字符串、零值时间）时 ok 返回 false
+func ParseTimeValue(value interface{}) (t time.Time, ok bool, err error) {
+	loc := GetTimeLocation()
+	switch v := value.(type) {
+	case nil:
+		return t, false, nil
+	case time.Time:
+		if v.IsZero() {
+			return t, false, nil
+		}
+		return v.In(loc), true, nil
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return t, false, nil
+		}
+		return v.In(loc), true, nil
+	case global.TTime:
+		return ParseTimeValue(time.Time(v))
+	case UnixSeconds:
+		return time.Unix(int64(v), 0).In(loc), true, nil
+	case UnixMillis:
+		return time.UnixMilli(int64(v)).In(loc), true, nil
+	case int:
+		return unixToTime(int64(v), loc), true, nil
+	case int32:
+		return unixToTime(int64(v), loc), true, nil
+	case int64:
+		return unixToTime(v, loc), true, nil
+	case uint32:
+		return unixToTime(int64(v), loc), true, nil
+	case uint64:
+		return unixToTime(int64(v), loc), true, nil
+	case string:
+		return parseTimeString(v, loc)
+	default:
+		return t, false, fmt.Errorf("%w: %v(%T)", ErrInvalidTimeValue, value, value)
+	}
+}
+
+// unixToTime 按数值大小识别秒/毫秒时间戳
+func unixToTime(v int64, loc *time.Location) time.Time {
+	if v >= unixMillisThreshold || v <= -unixMillisThreshold {
+		return time.UnixMilli(v).In(loc)
+	}
+	return time.Unix(v, 0).In(loc)
+}
+
+// parseTimeString 解析时间字符串，纯数字按时间戳处理
+func parseTimeString(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return unixToTime(unix, loc), true, nil
+	}
+	for _, layout := range GetTimeLayouts() {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.In(loc), true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%w: %s", ErrInvalidTimeValue, value)
+}
+
+// TimeRangeFilter 时间范围过滤器
+// 默认为左闭右开区间 [Start, End)，Start 或 End 为空时为开区间查询
+type TimeRangeFilter struct {
+	DBField      string      // DB中字段名称
+	Start        interface{} // 开始时间，nil 表示不限制
+	End          interface{} // 结束时间，nil 表示不限制
+	EndInclusive bool        // 结束时间是否包含（兼容 AddTimeRange 的 BETWEEN 语义）
+}
+
+// NewTimeRangeFilter 创建左闭右开的时间范围过滤器，start/end 支持 ParseTimeValue 的全部类型
+func NewTimeRangeFilter(field string, start, end interface{}) (*TimeRangeFilter, error) {
+	startTime, hasStart, err := ParseTimeValue(start)
+	if err != nil {
+		return nil, err
+	}
+	endTime, hasEnd, err := ParseTimeValue(end)
+	if err != nil {
+		return nil, err
+	}
+	filter := &TimeRangeFilter{DBField: field}
+	if hasStart {
+		filter.Start = startTime
+	}
+	if hasEnd {
+		filter.End = endTime
+	}
+	return filter, nil
+}
+
+// Condition 生成查询条件，没有任何边界时返回空字符串
+func (f *TimeRangeFilter) Condition() (string, []interface{}) {
+	if f == nil || f.DBField == "" {
+		return "", nil
+	}
+	switch {
+	case f.Start != nil && f.End != nil && f.EndInclusive:
+		return f.DBField + " BETWEEN ? AND ?", []interface{}{f.Start, f.End}
+	case f.Start != nil && f.End != nil:
+		return f.DBField + " >= ? AND " + f.DBField + " < ?", []interface{}{f.Start, f.End}
+	case f.Start != nil:
+		return f.DBField + " >= ?", []interface{}{f.Start}
+	case f.End != nil && f.EndInclusive:
+		return f.DBField + " <= ?", []interface{}{f.End}
+	case f.End != nil:
+		return f.DBField + " < ?", []interface{}{f.End}
+	}
+	return "", nil
+}
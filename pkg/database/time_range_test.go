@@ -0,0 +1,198 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 11:40:05
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 11:40:05
+ * @FilePath: \go-core\pkg\database\time_range_test.go
+ * @Description: 时间范围过滤器测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// TestParseTimeValue 测试时间值解析
+func TestParseTimeValue(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	original := GetTimeLocation()
+	SetTimeLocation(shanghai)
+	defer SetTimeLocation(original)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, shanghai)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected time.Time
+		ok       bool
+		hasErr   bool
+	}{
+		{"nil", nil, time.Time{}, false, false},
+		{"empty string", "", time.Time{}, false, false},
+		{"zero time", time.Time{}, time.Time{}, false, false},
+		{"time", base.UTC(), base, true, false},
+		{"time pointer", &base, base, true, false},
+		{"unix seconds", base.Unix(), base, true, false},
+		{"unix millis", base.UnixMilli(), base, true, false},
+		{"typed seconds", UnixSeconds(base.Unix()), base, true, false},
+		{"typed millis", UnixMillis(base.UnixMilli()), base, true, false},
+		{"numeric string", "1704135845", base, true, false},
+		{"layout in location", "2024-01-02 03:04:05", base, true, false},
+		{"rfc3339", "2024-01-01T19:04:05Z", base, true, false},
+		{"date only", "2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, shanghai), true, false},
+		{"invalid string", "not-a-time", time.Time{}, false, true},
+		{"invalid type", 1.5, time.Time{}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok, err := ParseTimeValue(tt.value)
+			if tt.hasErr {
+				assert.ErrorIs(t, err, ErrInvalidTimeValue)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.True(t, tt.expected.Equal(result))
+				assert.Equal(t, shanghai, result.Location())
+			}
+		})
+	}
+}
+
+// TestTimeRangeFilterCondition 测试时间范围条件生成
+func TestTimeRangeFilterCondition(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filter   *TimeRangeFilter
+		expected string
+		args     int
+	}{
+		{"half open", &TimeRangeFilter{DBField: "created_at", Start: start, End: end}, "created_at >= ? AND created_at < ?", 2},
+		{"start only", &TimeRangeFilter{DBField: "created_at", Start: start}, "created_at >= ?", 1},
+		{"end only", &TimeRangeFilter{DBField: "created_at", End: end}, "created_at < ?", 1},
+		{"between", &TimeRangeFilter{DBField: "created_at", Start: start, End: end, EndInclusive: true}, "created_at BETWEEN ? AND ?", 2},
+		{"end inclusive", &TimeRangeFilter{DBField: "created_at", End: end, EndInclusive: true}, "created_at <= ?", 1},
+		{"empty", &TimeRangeFilter{DBField: "created_at"}, "", 0},
+		{"nil", nil, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := tt.filter.Condition()
+			assert.Equal(t, tt.expected, condition)
+			assert.Len(t, args, tt.args)
+		})
+	}
+}
+
+// TestTimeRangeDialects 测试时间范围在不同方言下的SQL
+func TestTimeRangeDialects(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+	param := NewQueryBuilder().WhereTimeBetween("created_at", start, end).Build()
+
+	expected := map[string]string{
+		"mysql":    "created_at >= '2024-01-01 00:00:00' AND created_at < '2024-02-01 00:00:00'",
+		"postgres": "created_at >= '2024-01-01 00:00:00' AND created_at < '2024-02-01 00:00:00'",
+		"sqlite":   `created_at >= "2024-01-01 00:00:00" AND created_at < "2024-02-01 00:00:00"`,
+	}
+	for dialect, condition := range expected {
+		db, err := openDryRunDB(dialect)
+		assert.NoError(t, err)
+		var users []TestUser
+		assert.Contains(t, toSQL(db, param, &users), condition, dialect)
+	}
+}
+
+// TimeRangeTestSuite 时间范围查询测试套件
+type TimeRangeTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	handler Handler
+}
+
+// SetupSuite 测试套件初始化
+func (suite *TimeRangeTestSuite) SetupSuite() {
+	db, handler, err := setupTestDB()
+	suite.Require().NoError(err)
+
+	suite.db = db
+	suite.handler = handler
+	suite.Require().NoError(seedTestData(db))
+}
+
+// TearDownSuite 测试套件清理
+func (suite *TimeRangeTestSuite) TearDownSuite() {
+	if suite.handler != nil {
+		suite.handler.Close()
+	}
+}
+
+// countUsers 统计满足条件的用户数
+func (suite *TimeRangeTestSuite) countUsers(param QueryParam) (int64, error) {
+	var count int64
+	err := suite.handler.Query(param).Model(&TestUser{}).Count(&count).Error
+	return count, err
+}
+
+// TestOpenEndedRanges 测试开区间查询
+func (suite *TimeRangeTestSuite) TestOpenEndedRanges() {
+	hourAgo := time.Now().Add(-time.Hour)
+
+	count, err := suite.countUsers(NewQueryBuilder().WhereTimeAfter("created_at", hourAgo).Build())
+	suite.NoError(err)
+	suite.Greater(count, int64(0))
+
+	count, err = suite.countUsers(NewQueryBuilder().WhereTimeBefore("created_at", hourAgo).Build())
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	count, err = suite.countUsers(NewQueryBuilder().WhereTimeBetween("created_at", UnixSeconds(hourAgo.Unix()), nil).Build())
+	suite.NoError(err)
+	suite.Greater(count, int64(0))
+
+	count, err = suite.countUsers(NewQueryBuilder().WhereTimeBetween("created_at", nil, hourAgo.UnixMilli()).Build())
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
+
+// TestLegacyTimeRangeOpenEnded 测试字符串时间范围单边条件不再被丢弃
+func (suite *TimeRangeTestSuite) TestLegacyTimeRangeOpenEnded() {
+	future := time.Now().Add(24 * time.Hour).Format("2006-01-02 15:04:05")
+
+	param := NewAdvancedQueryParam(nil).AddTimeRange("created_at", future, "")
+	count, err := suite.countUsers(param)
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
+
+// TestInvalidTimeValue 测试无法解析的时间值使查询失败
+func (suite *TimeRangeTestSuite) TestInvalidTimeValue() {
+	param := NewQueryBuilder().WhereTimeAfter("created_at", "yesterday").Build()
+	_, err := suite.countUsers(param)
+	suite.ErrorIs(err, ErrInvalidTimeValue)
+
+	// 错误不会污染共享连接
+	suite.NoError(suite.handler.DB().Error)
+	count, err := suite.countUsers(NewQueryBuilder().Build())
+	suite.NoError(err)
+	suite.Greater(count, int64(0))
+}
+
+// TestTimeRangeTestSuite 运行时间范围查询测试套件
+func TestTimeRangeTestSuite(t *testing.T) {
+	suite.Run(t, new(TimeRangeTestSuite))
+}
@@ -24,6 +24,12 @@ type Model struct {
 	UpdateTime TTime         `json:"updateTime,omitempty"    gorm:"column:update_time;comment:更新时间;"`
 }
 
+// VersionModel 带乐观锁版本号的 Model，配合 database.UpdateWithVersion 使用
+type VersionModel struct {
+	Model
+	Version int64 `json:"version"                 gorm:"column:version;not null;default:1;comment:乐观锁版本号;"`
+}
+
 // CreateId
 /**
  *  @Description: 创建一个分布式ID（雪花ID）
@@ -0,0 +1,376 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 19:36:18
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 19:36:18
+ * @FilePath: \go-core\pkg\jwt\apikey.go
+ * @Description: API Key，存储中只保存哈希值，支持授权范围、过期时间与最近使用时间
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// API Key 相关错误
+var (
+	APIKeyInvalid error = errors.New("API Key 无效")
+	APIKeyExpired error = errors.New("API Key 已过期")
+	APIKeyRevoked error = errors.New("API Key 已被吊销")
+)
+
+const apiKeySeparator = "."
+
+var (
+	apiKeyPerFixKey     = global.GPerFix + "jwt_api_key"
+	apiKeyTouchInterval = time.Minute // 最近使用时间的最小更新间隔，避免每次请求都写存储
+)
+
+// SetAPIKeyPerFixKey 设置 API Key Redis 键前缀
+func SetAPIKeyPerFixKey(key string) {
+	apiKeyPerFixKey = key
+}
+
+// GetAPIKeyPerFixKey 获取 API Key Redis 键前缀
+func GetAPIKeyPerFixKey() string {
+	return apiKeyPerFixKey
+}
+
+// SetAPIKeyTouchInterval 设置最近使用时间的最小更新间隔
+func SetAPIKeyTouchInterval(interval time.Duration) {
+	apiKeyTouchInterval = interval
+}
+
+// GetAPIKeyTouchInterval 获取最近使用时间的最小更新间隔
+func GetAPIKeyTouchInterval() time.Duration {
+	return apiKeyTouchInterval
+}
+
+// APIKey API Key 记录，明文格式为 keyId.secret，只保存 secret 的哈希值
+type APIKey struct {
+
+	/** API Key id，明文中 . 之前的部分，可用于展示与吊销 */
+	KeyId string `json:"keyId"       gorm:"column:key_id;primary_key;comment:API Key id;type:varchar(32);"`
+
+	/** 密钥 SHA-256 哈希 */
+	KeyHash string `json:"keyHash"     gorm:"column:key_hash;comment:密钥哈希;type:varchar(64);"`
+
+	/** 所属客户端id，即服务名，作为 casbin 授权的主体 */
+	ClientId string `json:"clientId"    gorm:"column:client_id;index;comment:客户端id;type:varchar(64);"`
+
+	/** 名称 */
+	Name string `json:"name"        gorm:"column:name;comment:名称;type:varchar(128);"`
+
+	/** 授权范围 */
+	Scopes []string `json:"scopes"      gorm:"column:scopes;serializer:json;comment:授权范围;type:text;"`
+
+	/** 所属商户的商户号 */
+	MerchantNo string `json:"merchantNo"  gorm:"column:merchant_no;comment:商户号;type:varchar(32);"`
+
+	/** 过期时间，为空时永不过期 */
+	ExpiresAt *time.Time `json:"expiresAt"   gorm:"column:expires_at;comment:过期时间;"`
+
+	/** 最近使用时间 */
+	LastUsedAt *time.Time `json:"lastUsedAt"  gorm:"column:last_used_at;comment:最近使用时间;"`
+
+	/** 是否已吊销 */
+	Revoked bool `json:"revoked"     gorm:"column:revoked;comment:是否已吊销;"`
+
+	/** 创建时间 */
+	CreatedAt time.Time `json:"createdAt"   gorm:"column:created_at;comment:创建时间;"`
+}
+
+// TableName 自定义表名
+func (APIKey) TableName() string {
+	return global.GPerFix + "jwt_api_keys"
+}
+
+// APIKeyOptions 创建 API Key 的参数
+type APIKeyOptions struct {
+	ClientId   string        // 所属客户端id，必填
+	Name       string        // 名称
+	Scopes     []string      // 授权范围，* 表示全部
+	MerchantNo string        // 所属商户的商户号
+	ExpiresIn  time.Duration // 有效期，为 0 时永不过期
+}
+
+// APIKeyStore API Key 存储
+type APIKeyStore interface {
+	// Save 保存 API Key
+	Save(ctx context.Context, key *APIKey) error
+	// Get 获取 API Key，不存在时返回 APIKeyInvalid
+	Get(ctx context.Context, keyId string) (*APIKey, error)
+	// List 获取客户端的全部 API Key
+	List(ctx context.Context, clientId string) ([]*APIKey, error)
+	// Revoke 吊销 API Key
+	Revoke(ctx context.Context, keyId string) error
+	// Touch 更新最近使用时间
+	Touch(ctx context.Context, keyId string, usedAt time.Time) error
+}
+
+// NewDefaultAPIKeyStore 有 Redis 时使用 Redis，否则使用数据库
+func NewDefaultAPIKeyStore() APIKeyStore {
+	if global.REDIS != nil {
+		return &RedisAPIKeyStore{PrefixKey: GetAPIKeyPerFixKey()}
+	}
+	return &DBAPIKeyStore{}
+}
+
+// RedisAPIKeyStore 基于 global.REDIS 的 API Key 存储，最近使用时间单独保存，避免与吊销并发覆盖
+type RedisAPIKeyStore struct {
+	PrefixKey string
+}
+
+// keyKey API Key 键
+func (s *RedisAPIKeyStore) keyKey(keyId string) string {
+	return s.PrefixKey + ":key:" + keyId
+}
+
+// usedKey 最近使用时间键
+func (s *RedisAPIKeyStore) usedKey(keyId string) string {
+	return s.PrefixKey + ":used:" + keyId
+}
+
+// clientKey 客户端的 API Key 集合键
+func (s *RedisAPIKeyStore) clientKey(clientId string) string {
+	return s.PrefixKey + ":client:" + clientId
+}
+
+// ttl 记录保留到 API Key 过期为止
+func (s *RedisAPIKeyStore) ttl(key *APIKey) time.Duration {
+	if key.ExpiresAt == nil {
+		return 0
+	}
+	return time.Until(*key.ExpiresAt)
+}
+
+// Save 保存 API Key 并加入客户端集合
+func (s *RedisAPIKeyStore) Save(ctx context.Context, key *APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	pipe := global.REDIS.TxPipeline()
+	pipe.Set(ctx, s.keyKey(key.KeyId), data, s.ttl(key))
+	pipe.SAdd(ctx, s.clientKey(key.ClientId), key.KeyId)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get 获取 API Key
+func (s *RedisAPIKeyStore) Get(ctx context.Context, keyId string) (*APIKey, error) {
+	data, err := global.REDIS.Get(ctx, s.keyKey(keyId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, APIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key APIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	usedAt, err := global.REDIS.Get(ctx, s.usedKey(keyId)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if err == nil {
+		lastUsedAt := time.Unix(usedAt, 0)
+		key.LastUsedAt = &lastUsedAt
+	}
+	return &key, nil
+}
+
+// List 获取客户端的全部 API Key，并清理已过期的成员
+func (s *RedisAPIKeyStore) List(ctx context.Context, clientId string) ([]*APIKey, error) {
+	keyIds, err := global.REDIS.SMembers(ctx, s.clientKey(clientId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, 0, len(keyIds))
+	for _, keyId := range keyIds {
+		key, err := s.Get(ctx, keyId)
+		if errors.Is(err, APIKeyInvalid) {
+			global.REDIS.SRem(ctx, s.clientKey(clientId), keyId)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Revoke 标记为已吊销，保留原有过期时间
+func (s *RedisAPIKeyStore) Revoke(ctx context.Context, keyId string) error {
+	key, err := s.Get(ctx, keyId)
+	if err != nil {
+		return err
+	}
+	key.Revoked = true
+	key.LastUsedAt = nil
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return global.REDIS.Set(ctx, s.keyKey(keyId), data, redis.KeepTTL).Err()
+}
+
+// Touch 更新最近使用时间
+func (s *RedisAPIKeyStore) Touch(ctx context.Context, keyId string, usedAt time.Time) error {
+	ttl, err := global.REDIS.TTL(ctx, s.keyKey(keyId)).Result()
+	if err != nil {
+		return err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return global.REDIS.Set(ctx, s.usedKey(keyId), strconv.FormatInt(usedAt.Unix(), 10), ttl).Err()
+}
+
+// DBAPIKeyStore 基于 global.DB 的 API Key 存储，表需通过 AutoCreateTables 创建
+type DBAPIKeyStore struct{}
+
+// Save 保存 API Key
+func (s *DBAPIKeyStore) Save(ctx context.Context, key *APIKey) error {
+	return global.DB.WithContext(ctx).Create(key).Error
+}
+
+// Get 获取 API Key
+func (s *DBAPIKeyStore) Get(ctx context.Context, keyId string) (*APIKey, error) {
+	var key APIKey
+	err := global.DB.WithContext(ctx).Where("key_id = ?", keyId).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, APIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List 获取客户端的全部 API Key
+func (s *DBAPIKeyStore) List(ctx context.Context, clientId string) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := global.DB.WithContext(ctx).Where("client_id = ?", clientId).Order("created_at").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke 标记为已吊销
+func (s *DBAPIKeyStore) Revoke(ctx context.Context, keyId string) error {
+	return global.DB.WithContext(ctx).Model(&APIKey{}).Where("key_id = ?", keyId).Update("revoked", true).Error
+}
+
+// Touch 更新最近使用时间
+func (s *DBAPIKeyStore) Touch(ctx context.Context, keyId string, usedAt time.Time) error {
+	return global.DB.WithContext(ctx).Model(&APIKey{}).Where("key_id = ?", keyId).Update("last_used_at", usedAt).Error
+}
+
+// apiKeyStore 获取 API Key 存储
+func (j *JWT) apiKeyStore() APIKeyStore {
+	if j.APIKeyStore != nil {
+		return j.APIKeyStore
+	}
+	return NewDefaultAPIKeyStore()
+}
+
+// CreateAPIKey 创建 API Key，明文只在此时返回一次
+func (j *JWT) CreateAPIKey(ctx context.Context, options APIKeyOptions) (string, *APIKey, error) {
+	if options.ClientId == "" {
+		return "", nil, errors.New("clientId 不能为空")
+	}
+	keyId, err := randomToken(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	key := &APIKey{
+		KeyId:      keyId,
+		KeyHash:    hashToken(secret),
+		ClientId:   options.ClientId,
+		Name:       options.Name,
+		Scopes:     options.Scopes,
+		MerchantNo: options.MerchantNo,
+		CreatedAt:  time.Now(),
+	}
+	if options.ExpiresIn > 0 {
+		expiresAt := key.CreatedAt.Add(options.ExpiresIn)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := j.apiKeyStore().Save(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return keyId + apiKeySeparator + secret, key, nil
+}
+
+// VerifyAPIKey 校验 API Key 并返回机器身份，同时按 GetAPIKeyTouchInterval 更新最近使用时间
+func (j *JWT) VerifyAPIKey(ctx context.Context, plaintext string) (*ServiceClaims, error) {
+	keyId, secret, ok := strings.Cut(plaintext, apiKeySeparator)
+	if !ok || keyId == "" || secret == "" {
+		return nil, APIKeyInvalid
+	}
+	store := j.apiKeyStore()
+	key, err := store.Get(ctx, keyId)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(secret))) != 1 {
+		return nil, APIKeyInvalid
+	}
+	now := time.Now()
+	if key.Revoked {
+		return nil, APIKeyRevoked
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, APIKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= GetAPIKeyTouchInterval() {
+		// 最近使用时间仅用于审计，更新失败不影响本次请求
+		if err := store.Touch(ctx, keyId, now); err != nil && global.LOGGER != nil {
+			global.LOGGER.WithError(err).ErrorMsg("更新 API Key 最近使用时间失败")
+		}
+	}
+
+	claims := &ServiceClaims{
+		ClientId:   key.ClientId,
+		Name:       key.Name,
+		Scopes:     key.Scopes,
+		MerchantNo: key.MerchantNo,
+		TokenUse:   TokenUseAPIKey,
+	}
+	claims.ID = key.KeyId
+	claims.Subject = key.ClientId
+	claims.IssuedAt = jwt.NewNumericDate(key.CreatedAt)
+	if key.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*key.ExpiresAt)
+	}
+	return claims, nil
+}
+
+// RevokeAPIKey 吊销 API Key，立即生效
+func (j *JWT) RevokeAPIKey(ctx context.Context, keyId string) error {
+	return j.apiKeyStore().Revoke(ctx, keyId)
+}
+
+// ListAPIKeys 获取客户端的全部 API Key，不含明文
+func (j *JWT) ListAPIKeys(ctx context.Context, clientId string) ([]*APIKey, error) {
+	return j.apiKeyStore().List(ctx, clientId)
+}
@@ -0,0 +1,300 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 15:02:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 15:02:36
+ * @FilePath: \go-core\pkg\jwt\claims.go
+ * @Description: 自定义 claims 类型与泛型 JWT
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/labstack/echo/v4"
+)
+
+// claims 相关错误
+var (
+	ClaimsNotFound     error = errors.New("获取用户claims失败，请检查路由是否使用jwt中间件")
+	ClaimsTypeMismatch error = errors.New("用户claims类型不匹配")
+	ClaimsInvalid      error = errors.New("claims 校验失败")
+)
+
+// Claims 自定义 claims 需要实现的接口，嵌入 BaseClaims 即可获得默认实现
+type Claims interface {
+	jwt.Claims
+
+	// GetRegisteredClaims 返回标准 claims，签发时会写入 iat、exp 等
+	GetRegisteredClaims() *jwt.RegisteredClaims
+
+	// GetTokenId 返回 tokenId，用于吊销与多端会话
+	GetTokenId() string
+
+	// GetUserId 返回用户id，用于按用户吊销与多端会话
+	GetUserId() string
+
+	// Validate 校验自定义字段，签发前调用
+	Validate() error
+}
+
+// tokenIdSetter 可设置 tokenId 的 claims，未实现时写入 jti
+type tokenIdSetter interface {
+	SetTokenId(tokenId string)
+}
+
+// platformClaims 携带平台类型的 claims，多端会话按平台限制数量
+type platformClaims interface {
+	GetPlatformType() int32
+}
+
+// bufferTimeClaims 自带刷新缓冲时间的 claims，未实现时使用配置中的 BufferTime
+type bufferTimeClaims interface {
+	GetBufferTime() int64
+}
+
+// BaseClaims 自定义 claims 的基础实现，tokenId 为 jti，用户id 为 sub
+type BaseClaims struct {
+	jwt.RegisteredClaims
+}
+
+// GetRegisteredClaims 返回标准 claims
+func (c *BaseClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &c.RegisteredClaims
+}
+
+// GetTokenId 返回 jti
+func (c *BaseClaims) GetTokenId() string {
+	return c.ID
+}
+
+// GetUserId 返回 sub
+func (c *BaseClaims) GetUserId() string {
+	return c.Subject
+}
+
+// Validate 默认不校验，自定义 claims 可覆盖
+func (c *BaseClaims) Validate() error {
+	return nil
+}
+
+// GetRegisteredClaims 返回标准 claims
+func (c *CustomClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &c.RegisteredClaims
+}
+
+// GetTokenId 返回 tokenId
+func (c *CustomClaims) GetTokenId() string {
+	return c.TokenId
+}
+
+// SetTokenId 设置 tokenId
+func (c *CustomClaims) SetTokenId(tokenId string) {
+	c.TokenId = tokenId
+}
+
+// GetUserId 返回用户id
+func (c *CustomClaims) GetUserId() string {
+	return c.UserId
+}
+
+// GetPlatformType 返回平台类型
+func (c *CustomClaims) GetPlatformType() int32 {
+	return c.PlatformType
+}
+
+// GetBufferTime 返回刷新缓冲时间
+func (c *CustomClaims) GetBufferTime() int64 {
+	return c.BufferTime
+}
+
+// GetUserType 返回用户类型
+func (c *CustomClaims) GetUserType() string {
+	return c.UserType
+}
+
+// GetTenantId 返回租户id，即商户号
+func (c *CustomClaims) GetTenantId() string {
+	return c.MerchantNo
+}
+
+// Validate 校验用户id，吊销与多端会话均依赖用户id
+func (c *CustomClaims) Validate() error {
+	if c.UserId == "" {
+		return errors.New("userId 不能为空")
+	}
+	return nil
+}
+
+// newClaims 创建 T 指向的零值 claims，T 必须为指针类型
+func newClaims[T Claims]() (T, error) {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return zero, fmt.Errorf("%w: %v 不是指针类型", ClaimsTypeMismatch, typ)
+	}
+	return reflect.New(typ.Elem()).Interface().(T), nil
+}
+
+// validateClaims 签发前校验自定义字段
+func validateClaims(claims Claims) error {
+	if err := claims.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ClaimsInvalid, err)
+	}
+	return nil
+}
+
+// setTokenId 设置 tokenId，未实现 SetTokenId 时写入 jti
+func setTokenId(claims Claims, tokenId string) {
+	if setter, ok := claims.(tokenIdSetter); ok {
+		setter.SetTokenId(tokenId)
+		return
+	}
+	claims.GetRegisteredClaims().ID = tokenId
+}
+
+// claimsPlatformType 获取 claims 的平台类型
+func claimsPlatformType(claims Claims) int32 {
+	if platform, ok := claims.(platformClaims); ok {
+		return platform.GetPlatformType()
+	}
+	return 0
+}
+
+// claimsBufferTime 获取刷新缓冲时间，claims 未设置时使用配置
+func claimsBufferTime(claims Claims) int64 {
+	if buffer, ok := claims.(bufferTimeClaims); ok && buffer.GetBufferTime() > 0 {
+		return buffer.GetBufferTime()
+	}
+	if global.CONFIG != nil {
+		return global.CONFIG.JWT.BufferTime
+	}
+	return 0
+}
+
+// TypedJWT 使用自定义 claims 类型的 jwt，T 为指向自定义 claims 结构体的指针
+// 签发时会把 iat、exp、tokenId 等写回传入的 claims
+type TypedJWT[T Claims] struct {
+	*JWT
+}
+
+// NewTypedJWT 新建使用自定义 claims 类型的 jwt 实例，密钥设置与 NewJWT 一致
+func NewTypedJWT[T Claims]() *TypedJWT[T] {
+	return &TypedJWT[T]{JWT: NewJWT()}
+}
+
+// NewTypedJWTWithKeys 使用密钥提供者新建使用自定义 claims 类型的 jwt 实例
+func NewTypedJWTWithKeys[T Claims](keys KeyProvider) *TypedJWT[T] {
+	return &TypedJWT[T]{JWT: NewJWTWithKeys(keys)}
+}
+
+// CreateToken 校验并签发 token，开启多点登录拦截时同时登记会话
+func (t *TypedJWT[T]) CreateToken(claims T) (string, error) {
+	return t.createToken(claims)
+}
+
+// CreateSessionToken 签发 token 并登记会话，见 JWT.CreateSessionToken
+func (t *TypedJWT[T]) CreateSessionToken(ctx context.Context, claims T, meta SessionMeta) (string, []*Session, error) {
+	return t.createSessionToken(ctx, claims, meta)
+}
+
+// ResolveToken 解析 token
+func (t *TypedJWT[T]) ResolveToken(tokenString string) (T, error) {
+	return resolveToken[T](t.JWT, tokenString)
+}
+
+// RefreshToken 更新token，仅允许在过期后 BufferTime 秒内刷新
+func (t *TypedJWT[T]) RefreshToken(tokenString string) (string, error) {
+	return refreshToken[T](t.JWT, tokenString)
+}
+
+// RenewToken 延长 Token 有效期并重新签发，见 JWT.RenewToken
+func (t *TypedJWT[T]) RenewToken(claims T) (string, error) {
+	return t.renewToken(claims)
+}
+
+// CreateTokenPair 签发访问令牌与刷新令牌
+func (t *TypedJWT[T]) CreateTokenPair(ctx context.Context, claims T) (*TokenPair, error) {
+	return t.createTokenPair(ctx, claims)
+}
+
+// RefreshTokenPair 使用刷新令牌换取新的令牌对，见 JWT.RefreshTokenPair
+func (t *TypedJWT[T]) RefreshTokenPair(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return t.refreshTokenPair(ctx, refreshToken, decodeClaims[T])
+}
+
+// ResolveClaims 实现 ClaimsResolver
+func (t *TypedJWT[T]) ResolveClaims(tokenString string) (Claims, error) {
+	return t.ResolveToken(tokenString)
+}
+
+// RenewClaims 实现 ClaimsResolver
+func (t *TypedJWT[T]) RenewClaims(claims Claims) (string, error) {
+	return t.renewToken(claims)
+}
+
+// claimsAs 将上下文中保存的值转换为 T，不存在或类型不符时返回错误而不是零值
+func claimsAs[T Claims](value interface{}, exists bool) (T, error) {
+	var zero T
+	if !exists || value == nil {
+		return zero, ClaimsNotFound
+	}
+	claims, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %T", ClaimsTypeMismatch, value)
+	}
+	return claims, nil
+}
+
+// claimsKeyOf 获取上下文中保存 claims 的键
+func claimsKeyOf(key []string) string {
+	if len(key) > 0 && key[0] != "" {
+		return key[0]
+	}
+	return DefaultClaimsKey
+}
+
+// GetTypedClaims 从Gin的Context中获取自定义类型的claims，key 默认为 claims
+func GetTypedClaims[T Claims](c *gin.Context, key ...string) (T, error) {
+	value, exists := c.Get(claimsKeyOf(key))
+	return claimsAs[T](value, exists)
+}
+
+// GetEchoTypedClaims 从Echo的Context中获取自定义类型的claims，key 默认为 claims
+func GetEchoTypedClaims[T Claims](c echo.Context, key ...string) (T, error) {
+	value := c.Get(claimsKeyOf(key))
+	return claimsAs[T](value, value != nil)
+}
+
+// GetFiberTypedClaims 从Fiber的Locals中获取自定义类型的claims，key 默认为 claims
+func GetFiberTypedClaims[T Claims](c *fiber.Ctx, key ...string) (T, error) {
+	value := c.Locals(claimsKeyOf(key))
+	return claimsAs[T](value, value != nil)
+}
+
+// GetRequestTypedClaims 从请求的 context 中获取自定义类型的claims
+func GetRequestTypedClaims[T Claims](r *http.Request) (T, error) {
+	return TypedClaimsFrom[T](r.Context())
+}
+
+// ClaimValue 从Gin的Context中获取claims的某个字段，claims 不存在或类型不符时返回错误
+//
+//	merchantNo, err := jwt.ClaimValue(c, func(claims *MyClaims) string { return claims.MerchantNo })
+func ClaimValue[T Claims, V any](c *gin.Context, field func(T) V) (V, error) {
+	claims, err := GetTypedClaims[T](c)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return field(claims), nil
+}
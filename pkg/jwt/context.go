@@ -0,0 +1,102 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 18:05:12
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 18:05:12
+ * @FilePath: \go-core\pkg\jwt\context.go
+ * @Description: 基于 context.Context 传递当前用户，并在调用下游服务时转发 Token
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// claimsContextKey context 中保存 claims 的键
+type claimsContextKey struct{}
+
+// tokenContextKey context 中保存原始 Token 的键
+type tokenContextKey struct{}
+
+// WithClaims 返回携带 claims 的 context，供服务层与后台任务读取当前用户
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFrom 从 context 中获取 claims
+func ClaimsFrom(ctx context.Context) (Claims, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	claims, ok := ctx.Value(claimsContextKey{}).(Claims)
+	return claims, ok && claims != nil
+}
+
+// TypedClaimsFrom 从 context 中获取自定义类型的 claims，不存在或类型不符时返回错误
+func TypedClaimsFrom[T Claims](ctx context.Context) (T, error) {
+	claims, ok := ClaimsFrom(ctx)
+	return claimsAs[T](claims, ok)
+}
+
+// WithToken 返回携带原始 Token 的 context，调用下游服务时转发
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// TokenFrom 从 context 中获取原始 Token
+func TokenFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	token, ok := ctx.Value(tokenContextKey{}).(string)
+	return token, ok && token != ""
+}
+
+// withPrincipal 中间件写入 claims 与原始 Token
+func withPrincipal(ctx context.Context, claims Claims, token string) context.Context {
+	return WithToken(WithClaims(ctx, claims), token)
+}
+
+// ForwardingTransport 转发 Token 的 http.RoundTripper，请求的 context 中有 Token 且未设置认证头时写入
+//
+//	client := &http.Client{Transport: &jwt.ForwardingTransport{}}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+type ForwardingTransport struct {
+	Base       http.RoundTripper // 实际发送请求的 RoundTripper，默认 http.DefaultTransport
+	HeaderName string            // 认证头，默认 Authorization，值为 Bearer Token
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *ForwardingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	headerName := t.HeaderName
+	if headerName == "" {
+		headerName = DefaultHeaderName
+	}
+	token, ok := TokenFrom(req.Context())
+	if !ok || req.Header.Get(headerName) != "" {
+		return base.RoundTrip(req)
+	}
+	// RoundTripper 不应修改原请求
+	forwarded := req.Clone(req.Context())
+	forwarded.Header.Set(headerName, bearerPrefix+token)
+	return base.RoundTrip(forwarded)
+}
+
+// ForwardingMetadata 返回转发 Token 的 gRPC metadata 键值对，context 中没有 Token 时返回 nil
+//
+//	ctx = metadata.AppendToOutgoingContext(ctx, jwt.ForwardingMetadata(ctx)...)
+func ForwardingMetadata(ctx context.Context) []string {
+	token, ok := TokenFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return []string{strings.ToLower(DefaultHeaderName), bearerPrefix + token}
+}
@@ -0,0 +1,54 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:55:08
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:55:08
+ * @FilePath: \go-core\pkg\jwt\echox.go
+ * @Description: Echo JWT 中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"github.com/kamalyes/go-core/pkg/response"
+	"github.com/labstack/echo/v4"
+)
+
+// echoSource 从 Echo 请求读取 Token
+type echoSource struct {
+	ctx echo.Context
+}
+
+func (s echoSource) header(name string) string { return s.ctx.Request().Header.Get(name) }
+func (s echoSource) query(name string) string  { return s.ctx.QueryParam(name) }
+func (s echoSource) cookie(name string) string {
+	if cookie, err := s.ctx.Cookie(name); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// EchoJWTHandler Echo JWT 中间件，解析成功后 claims 可通过 GetEchoClaims 或 ClaimsFrom(c.Request().Context()) 获取
+func EchoJWTHandler(configs ...*MiddlewareConfig) echo.MiddlewareFunc {
+	m := newMiddleware(configs...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth, err := m.authenticate(echoSource{ctx: c})
+			if err != nil {
+				return response.GenEchoResponse(c, response.NewErrorResponseOption(err))
+			}
+			for key, value := range m.headers(auth.refreshed) {
+				c.Response().Header().Set(key, value)
+			}
+			c.Set(m.config.ClaimsKey, auth.claims)
+			c.SetRequest(c.Request().WithContext(auth.context(c.Request().Context())))
+			return next(c)
+		}
+	}
+}
+
+// GetEchoClaims 从 Echo 的 Context 中获取 claims，key 默认为 claims
+func GetEchoClaims(c echo.Context, key ...string) (*CustomClaims, error) {
+	return GetEchoTypedClaims[*CustomClaims](c, key...)
+}
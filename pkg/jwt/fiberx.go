@@ -0,0 +1,47 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:55:08
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:55:08
+ * @FilePath: \go-core\pkg\jwt\fiberx.go
+ * @Description: Fiber JWT 中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// fiberSource 从 Fiber 请求读取 Token
+type fiberSource struct {
+	ctx *fiber.Ctx
+}
+
+func (s fiberSource) header(name string) string { return s.ctx.Get(name) }
+func (s fiberSource) query(name string) string  { return s.ctx.Query(name) }
+func (s fiberSource) cookie(name string) string { return s.ctx.Cookies(name) }
+
+// FiberJWTHandler Fiber JWT 中间件，解析成功后 claims 可通过 GetFiberClaims 或 ClaimsFrom(c.UserContext()) 获取
+func FiberJWTHandler(configs ...*MiddlewareConfig) fiber.Handler {
+	m := newMiddleware(configs...)
+	return func(c *fiber.Ctx) error {
+		auth, err := m.authenticate(fiberSource{ctx: c})
+		if err != nil {
+			return response.GenFiberResponse(c, response.NewErrorResponseOption(err))
+		}
+		for key, value := range m.headers(auth.refreshed) {
+			c.Set(key, value)
+		}
+		c.Locals(m.config.ClaimsKey, auth.claims)
+		c.SetUserContext(auth.context(c.UserContext()))
+		return c.Next()
+	}
+}
+
+// GetFiberClaims 从 Fiber 的 Locals 中获取 claims，key 默认为 claims
+func GetFiberClaims(c *fiber.Ctx, key ...string) (*CustomClaims, error) {
+	return GetFiberTypedClaims[*CustomClaims](c, key...)
+}
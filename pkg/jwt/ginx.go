@@ -0,0 +1,47 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:55:08
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:55:08
+ * @FilePath: \go-core\pkg\jwt\ginx.go
+ * @Description: Gin JWT 中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// ginSource 从 Gin 请求读取 Token
+type ginSource struct {
+	ctx *gin.Context
+}
+
+func (s ginSource) header(name string) string { return s.ctx.GetHeader(name) }
+func (s ginSource) query(name string) string  { return s.ctx.Query(name) }
+func (s ginSource) cookie(name string) string {
+	value, _ := s.ctx.Cookie(name)
+	return value
+}
+
+// GinJWTHandler Gin JWT 中间件，解析成功后 claims 可通过 GetClaims 或 ClaimsFrom(c.Request.Context()) 获取
+func GinJWTHandler(configs ...*MiddlewareConfig) gin.HandlerFunc {
+	m := newMiddleware(configs...)
+	return func(c *gin.Context) {
+		auth, err := m.authenticate(ginSource{ctx: c})
+		if err != nil {
+			response.GenGinResponse(c, response.NewErrorResponseOption(err))
+			c.Abort()
+			return
+		}
+		for key, value := range m.headers(auth.refreshed) {
+			c.Header(key, value)
+		}
+		c.Set(m.config.ClaimsKey, auth.claims)
+		c.Request = c.Request.WithContext(auth.context(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:55:08
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:55:08
+ * @FilePath: \go-core\pkg\jwt\httpx.go
+ * @Description: net/http JWT 中间件
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"net/http"
+
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// httpSource 从 net/http 请求读取 Token
+type httpSource struct {
+	req *http.Request
+}
+
+func (s httpSource) header(name string) string { return s.req.Header.Get(name) }
+func (s httpSource) query(name string) string  { return s.req.URL.Query().Get(name) }
+func (s httpSource) cookie(name string) string {
+	if cookie, err := s.req.Cookie(name); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// HttpJWTHandler net/http JWT 中间件，解析成功后 claims 可通过 GetRequestClaims 或 ClaimsFrom(r.Context()) 获取
+func HttpJWTHandler(next http.Handler, configs ...*MiddlewareConfig) http.Handler {
+	m := newMiddleware(configs...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, err := m.authenticate(httpSource{req: r})
+		if err != nil {
+			response.GenNetHttpResponse(w, response.NewErrorResponseOption(err))
+			return
+		}
+		for key, value := range m.headers(auth.refreshed) {
+			w.Header().Set(key, value)
+		}
+		next.ServeHTTP(w, r.WithContext(auth.context(r.Context())))
+	})
+}
+
+// GetRequestClaims 从请求的 context 中获取 claims
+func GetRequestClaims(r *http.Request) (*CustomClaims, error) {
+	return GetRequestTypedClaims[*CustomClaims](r)
+}
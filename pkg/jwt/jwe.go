@@ -0,0 +1,469 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 17:20:45
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 17:20:45
+ * @FilePath: \go-core\pkg\jwt\jwe.go
+ * @Description: JWE 加密，先签名后加密（Nested JWT），支持 AES-GCM 密钥包装与 RSA-OAEP
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/kamalyes/go-core/pkg/global"
+)
+
+// 密钥管理算法
+const (
+	KeyAlgA128GCMKW  = "A128GCMKW"
+	KeyAlgA192GCMKW  = "A192GCMKW"
+	KeyAlgA256GCMKW  = "A256GCMKW"
+	KeyAlgRSAOAEP    = "RSA-OAEP"
+	KeyAlgRSAOAEP256 = "RSA-OAEP-256"
+)
+
+// 内容加密算法
+const (
+	EncA128GCM = "A128GCM"
+	EncA192GCM = "A192GCM"
+	EncA256GCM = "A256GCM"
+)
+
+// 加密相关错误
+var (
+	EncryptionUnsupported   error = errors.New("不支持的加密算法或密钥类型")
+	EncryptionKeyNotFound   error = errors.New("未找到可用的解密密钥")
+	TokenEncryptionRequired error = errors.New("Token 未加密")
+)
+
+// wrapKeySizes AES-GCM 密钥包装算法对应的密钥长度
+var wrapKeySizes = map[string]int{
+	KeyAlgA128GCMKW: 16,
+	KeyAlgA192GCMKW: 24,
+	KeyAlgA256GCMKW: 32,
+}
+
+// contentKeySizes 内容加密算法对应的密钥长度
+var contentKeySizes = map[string]int{
+	EncA128GCM: 16,
+	EncA192GCM: 24,
+	EncA256GCM: 32,
+}
+
+// EncryptionKey JWE 加密密钥
+// AES-GCM 密钥包装时 Key 为 []byte；RSA-OAEP 时 Key 为 *rsa.PublicKey（只能加密）或 *rsa.PrivateKey
+type EncryptionKey struct {
+	KeyId      string      // 密钥标识，写入 JWE 头部的 kid
+	Algorithm  string      // 密钥管理算法，如 A256GCMKW、RSA-OAEP-256
+	Encryption string      // 内容加密算法，默认 A256GCM
+	Key        interface{} // 加密密钥
+}
+
+// EncryptionConfig JWE 配置
+type EncryptionConfig struct {
+	Enabled        bool   `mapstructure:"enabled"          json:"enabled"        yaml:"enabled"`          // 是否签发 JWE
+	Required       bool   `mapstructure:"required"         json:"required"       yaml:"required"`         // 是否拒绝未加密的 Token，迁移完成后开启，需同时开启 enabled
+	KeyId          string `mapstructure:"key-id"           json:"keyId"          yaml:"key-id"`           // 密钥标识
+	Algorithm      string `mapstructure:"algorithm"        json:"algorithm"      yaml:"algorithm"`        // 密钥管理算法，默认 A256GCMKW，配置 RSA 密钥时默认 RSA-OAEP-256
+	Encryption     string `mapstructure:"encryption"       json:"encryption"     yaml:"encryption"`       // 内容加密算法，默认 A256GCM
+	Secret         string `mapstructure:"secret"           json:"secret"         yaml:"secret"`           // AES 密钥，长度等于密钥长度的字符串或其十六进制编码
+	PrivateKey     string `mapstructure:"private-key"      json:"privateKey"     yaml:"private-key"`      // PEM 格式 RSA 私钥，解密需要
+	PublicKey      string `mapstructure:"public-key"       json:"publicKey"      yaml:"public-key"`       // PEM 格式 RSA 公钥，只加密时使用
+	PrivateKeyFile string `mapstructure:"private-key-file" json:"privateKeyFile" yaml:"private-key-file"` // PEM 私钥文件路径
+	PublicKeyFile  string `mapstructure:"public-key-file"  json:"publicKeyFile"  yaml:"public-key-file"`  // PEM 公钥文件路径
+}
+
+// Encryption JWE 设置，第一把密钥用于加密，全部密钥按 kid 用于解密
+type Encryption struct {
+	Keys     []*EncryptionKey // 加密密钥，轮换时把新密钥放在最前
+	Required bool             // 是否拒绝未加密的 Token
+}
+
+// JWTEncryptionConfigKey JWE 设置在配置文件中的键
+const JWTEncryptionConfigKey = "jwt-encryption"
+
+var jwtEncryption = struct {
+	sync.RWMutex
+	loaded     bool
+	encryption *Encryption
+}{}
+
+// SetJWTEncryption 设置默认的 JWE 设置，为 nil 时签发普通 JWS，设置后不再读取配置
+func SetJWTEncryption(encryption *Encryption) {
+	jwtEncryption.Lock()
+	defer jwtEncryption.Unlock()
+	jwtEncryption.encryption = encryption
+	jwtEncryption.loaded = true
+}
+
+// GetJWTEncryption 获取默认的 JWE 设置，未调用 SetJWTEncryption 时首次获取从配置 jwt-encryption 加载
+func GetJWTEncryption() *Encryption {
+	jwtEncryption.RLock()
+	if jwtEncryption.loaded {
+		defer jwtEncryption.RUnlock()
+		return jwtEncryption.encryption
+	}
+	jwtEncryption.RUnlock()
+
+	jwtEncryption.Lock()
+	defer jwtEncryption.Unlock()
+	if !jwtEncryption.loaded {
+		encryption, err := loadEncryptionConfig()
+		if err != nil {
+			// 配置开启了加密却无法创建密钥时拒绝签发与解析，不退化为明文 JWS
+			message := "加载JWT加密配置（" + JWTEncryptionConfigKey + "）失败，已拒绝签发与解析 Token：" + err.Error()
+			if global.LOGGER != nil {
+				global.LOGGER.Error(message)
+			} else {
+				log.Println(message)
+			}
+			encryption = &Encryption{Required: true}
+		}
+		jwtEncryption.encryption = encryption
+		jwtEncryption.loaded = true
+	}
+	return jwtEncryption.encryption
+}
+
+// LoadJWTEncryption 重新从配置 jwt-encryption 加载默认的 JWE 设置，失败时保留原设置
+func LoadJWTEncryption() error {
+	encryption, err := loadEncryptionConfig()
+	if err != nil {
+		return err
+	}
+	SetJWTEncryption(encryption)
+	return nil
+}
+
+// loadEncryptionConfig 读取配置 jwt-encryption 并创建 JWE 设置，未配置或未开启时返回 nil
+func loadEncryptionConfig() (*Encryption, error) {
+	if global.VP == nil || !global.VP.IsSet(JWTEncryptionConfigKey) {
+		return nil, nil
+	}
+	var config EncryptionConfig
+	if err := global.VP.UnmarshalKey(JWTEncryptionConfigKey, &config); err != nil {
+		return nil, err
+	}
+	return NewEncryptionFromConfig(config)
+}
+
+// encryption 获取实例的 JWE 设置，未设置时使用默认设置
+func (j *JWT) encryption() *Encryption {
+	if j.Encryption != nil {
+		return j.Encryption
+	}
+	return GetJWTEncryption()
+}
+
+// NewEncryptionKey 创建加密密钥并校验算法与密钥类型
+func NewEncryptionKey(kid, algorithm, encryption string, key interface{}) (*EncryptionKey, error) {
+	if encryption == "" {
+		encryption = EncA256GCM
+	}
+	if _, ok := contentKeySizes[encryption]; !ok {
+		return nil, fmt.Errorf("%w: %s", EncryptionUnsupported, encryption)
+	}
+	switch algorithm {
+	case KeyAlgA128GCMKW, KeyAlgA192GCMKW, KeyAlgA256GCMKW:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) != wrapKeySizes[algorithm] {
+			return nil, fmt.Errorf("%w: %s 需要 %d 字节密钥", EncryptionUnsupported, algorithm, wrapKeySizes[algorithm])
+		}
+	case KeyAlgRSAOAEP, KeyAlgRSAOAEP256:
+		switch key.(type) {
+		case *rsa.PrivateKey, *rsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("%w: %s 需要 RSA 密钥", EncryptionUnsupported, algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", EncryptionUnsupported, algorithm)
+	}
+	return &EncryptionKey{KeyId: kid, Algorithm: algorithm, Encryption: encryption, Key: key}, nil
+}
+
+// NewEncryptionFromConfig 根据配置创建 JWE 设置，未开启时返回 nil
+func NewEncryptionFromConfig(config EncryptionConfig) (*Encryption, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	key, err := newEncryptionKeyFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Encryption{Keys: []*EncryptionKey{key}, Required: config.Required}, nil
+}
+
+// newEncryptionKeyFromConfig 根据配置创建加密密钥
+func newEncryptionKeyFromConfig(config EncryptionConfig) (*EncryptionKey, error) {
+	if config.Secret != "" {
+		algorithm := config.Algorithm
+		if algorithm == "" {
+			algorithm = KeyAlgA256GCMKW
+		}
+		secret := []byte(config.Secret)
+		if decoded, err := hex.DecodeString(config.Secret); err == nil && len(decoded) == wrapKeySizes[algorithm] {
+			secret = decoded
+		}
+		return NewEncryptionKey(config.KeyId, algorithm, config.Encryption, secret)
+	}
+
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = KeyAlgRSAOAEP256
+	}
+	privatePEM, err := readPEM(config.PrivateKey, config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(privatePEM) > 0 {
+		privateKey, err := parsePrivateKeyPEM(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		return NewEncryptionKey(config.KeyId, algorithm, config.Encryption, privateKey)
+	}
+	publicPEM, err := readPEM(config.PublicKey, config.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicPEM) > 0 {
+		publicKey, err := parsePublicKeyPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+		return NewEncryptionKey(config.KeyId, algorithm, config.Encryption, publicKey)
+	}
+	return nil, EncryptionKeyNotFound
+}
+
+// jweHeader JWE 受保护头部
+type jweHeader struct {
+	Algorithm   string `json:"alg"`
+	Encryption  string `json:"enc"`
+	KeyId       string `json:"kid,omitempty"`
+	ContentType string `json:"cty,omitempty"`
+	IV          string `json:"iv,omitempty"`  // AES-GCM 密钥包装的 IV
+	Tag         string `json:"tag,omitempty"` // AES-GCM 密钥包装的认证标签
+}
+
+// isJWE 紧凑序列化的 JWE 由 5 段组成，JWS 为 3 段
+func isJWE(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// encryptToken 将已签名的 JWS 加密为紧凑序列化的 JWE
+func (e *Encryption) encryptToken(jws string) (string, error) {
+	if len(e.Keys) == 0 {
+		return "", EncryptionKeyNotFound
+	}
+	key := e.Keys[0]
+	cek := make([]byte, contentKeySizes[key.Encryption])
+	if _, err := rand.Read(cek); err != nil {
+		return "", err
+	}
+
+	header := jweHeader{Algorithm: key.Algorithm, Encryption: key.Encryption, KeyId: key.KeyId, ContentType: "JWT"}
+	encryptedKey, err := key.wrapKey(cek, &header)
+	if err != nil {
+		return "", err
+	}
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(headerJson)
+
+	iv, ciphertext, tag, err := gcmSeal(cek, []byte(jws), []byte(protected))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decryptToken 解密紧凑序列化的 JWE，返回内层 JWS，失败时统一返回 TokenInvalid
+func (e *Encryption) decryptToken(jwe string) (string, error) {
+	parts := strings.Split(jwe, ".")
+	if len(parts) != 5 {
+		return "", TokenMalformed
+	}
+	segments := make([][]byte, 5)
+	for i, part := range parts {
+		segment, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return "", TokenMalformed
+		}
+		segments[i] = segment
+	}
+	var header jweHeader
+	if err := json.Unmarshal(segments[0], &header); err != nil {
+		return "", TokenMalformed
+	}
+
+	key, err := e.decryptionKey(header)
+	if err != nil {
+		return "", err
+	}
+	cek, err := key.unwrapKey(segments[1], header)
+	if err != nil || len(cek) != contentKeySizes[header.Encryption] {
+		return "", TokenInvalid
+	}
+	plaintext, err := gcmOpen(cek, segments[2], segments[3], segments[4], []byte(parts[0]))
+	if err != nil {
+		return "", TokenInvalid
+	}
+	return string(plaintext), nil
+}
+
+// decryptionKey 按 kid 与算法查找解密密钥
+func (e *Encryption) decryptionKey(header jweHeader) (*EncryptionKey, error) {
+	for _, key := range e.Keys {
+		if header.KeyId != "" && key.KeyId != header.KeyId {
+			continue
+		}
+		if key.Algorithm != header.Algorithm || key.Encryption != header.Encryption {
+			continue
+		}
+		if _, isPublic := key.Key.(*rsa.PublicKey); isPublic {
+			continue
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid=%s alg=%s enc=%s", EncryptionKeyNotFound, header.KeyId, header.Algorithm, header.Encryption)
+}
+
+// wrapKey 加密内容密钥，AES-GCM 密钥包装时把 iv 与 tag 写入头部
+func (k *EncryptionKey) wrapKey(cek []byte, header *jweHeader) ([]byte, error) {
+	switch k.Algorithm {
+	case KeyAlgA128GCMKW, KeyAlgA192GCMKW, KeyAlgA256GCMKW:
+		iv, encryptedKey, tag, err := gcmSeal(k.Key.([]byte), cek, nil)
+		if err != nil {
+			return nil, err
+		}
+		header.IV = base64.RawURLEncoding.EncodeToString(iv)
+		header.Tag = base64.RawURLEncoding.EncodeToString(tag)
+		return encryptedKey, nil
+	case KeyAlgRSAOAEP, KeyAlgRSAOAEP256:
+		publicKey, ok := k.Key.(*rsa.PublicKey)
+		if privateKey, isPrivate := k.Key.(*rsa.PrivateKey); isPrivate {
+			publicKey, ok = &privateKey.PublicKey, true
+		}
+		if !ok {
+			return nil, EncryptionUnsupported
+		}
+		return rsa.EncryptOAEP(oaepHash(k.Algorithm), rand.Reader, publicKey, cek, nil)
+	}
+	return nil, fmt.Errorf("%w: %s", EncryptionUnsupported, k.Algorithm)
+}
+
+// unwrapKey 解密内容密钥
+func (k *EncryptionKey) unwrapKey(encryptedKey []byte, header jweHeader) ([]byte, error) {
+	switch k.Algorithm {
+	case KeyAlgA128GCMKW, KeyAlgA192GCMKW, KeyAlgA256GCMKW:
+		iv, err := base64.RawURLEncoding.DecodeString(header.IV)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := base64.RawURLEncoding.DecodeString(header.Tag)
+		if err != nil {
+			return nil, err
+		}
+		return gcmOpen(k.Key.([]byte), iv, encryptedKey, tag, nil)
+	case KeyAlgRSAOAEP, KeyAlgRSAOAEP256:
+		privateKey, ok := k.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, EncryptionUnsupported
+		}
+		return rsa.DecryptOAEP(oaepHash(k.Algorithm), rand.Reader, privateKey, encryptedKey, nil)
+	}
+	return nil, fmt.Errorf("%w: %s", EncryptionUnsupported, k.Algorithm)
+}
+
+// oaepHash RSA-OAEP 使用 SHA-1，RSA-OAEP-256 使用 SHA-256
+func oaepHash(algorithm string) hash.Hash {
+	if algorithm == KeyAlgRSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// gcmSeal 使用随机 IV 进行 AES-GCM 加密，返回 IV、密文与认证标签
+func gcmSeal(key, plaintext, aad []byte) ([]byte, []byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, aad)
+	tagStart := len(sealed) - aead.Overhead()
+	return iv, sealed[:tagStart], sealed[tagStart:], nil
+}
+
+// gcmOpen AES-GCM 解密并校验认证标签
+func gcmOpen(key, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aead.NonceSize() || len(tag) != aead.Overhead() {
+		return nil, TokenInvalid
+	}
+	return aead.Open(nil, iv, append(append([]byte{}, ciphertext...), tag...), aad)
+}
+
+// unwrapToken 解密 JWE 得到内层 JWS，开启 Required 时拒绝未加密的 Token
+func (j *JWT) unwrapToken(tokenString string) (string, error) {
+	encryption := j.encryption()
+	if !isJWE(tokenString) {
+		if encryption != nil && encryption.Required {
+			return "", TokenEncryptionRequired
+		}
+		return tokenString, nil
+	}
+	if encryption == nil {
+		return "", EncryptionKeyNotFound
+	}
+	return encryption.decryptToken(tokenString)
+}
+
+// wrapToken 开启加密时将 JWS 加密为 JWE
+func (j *JWT) wrapToken(jws string) (string, error) {
+	encryption := j.encryption()
+	if encryption == nil {
+		return jws, nil
+	}
+	return encryption.encryptToken(jws)
+}
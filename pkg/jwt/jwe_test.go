@@ -0,0 +1,171 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 10:26:13
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 10:26:13
+ * @FilePath: \go-core\pkg\jwt\jwe_test.go
+ * @Description: JWE 加密往返、篡改拒绝与配置加载测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestEncryption 清空默认的 JWE 设置与配置，测试结束后恢复
+func setupTestEncryption(t *testing.T) *viper.Viper {
+	originalVP := global.VP
+	jwtEncryption.Lock()
+	originalLoaded, originalEncryption := jwtEncryption.loaded, jwtEncryption.encryption
+	jwtEncryption.loaded, jwtEncryption.encryption = false, nil
+	jwtEncryption.Unlock()
+
+	global.VP = viper.New()
+	t.Cleanup(func() {
+		global.VP = originalVP
+		jwtEncryption.Lock()
+		jwtEncryption.loaded, jwtEncryption.encryption = originalLoaded, originalEncryption
+		jwtEncryption.Unlock()
+	})
+	return global.VP
+}
+
+// newTestEncryption 创建 AES-GCM 密钥包装的 JWE 设置
+func newTestEncryption(t *testing.T, kid string, required bool) *Encryption {
+	key, err := NewEncryptionKey(kid, KeyAlgA256GCMKW, "", []byte(strings.Repeat("k", 32)))
+	require.NoError(t, err)
+	return &Encryption{Keys: []*EncryptionKey{key}, Required: required}
+}
+
+// TestEncryptionRoundTrip 测试 AES-GCM 与 RSA-OAEP 加密后的 Token 可以解析
+func TestEncryptionRoundTrip(t *testing.T) {
+	setupTestConfig(t)
+	rsaKey := newRSAKey(t, "sig")
+	oaepKey, err := NewEncryptionKey("enc-rsa", KeyAlgRSAOAEP256, EncA128GCM, rsaKey.PrivateKey)
+	require.NoError(t, err)
+
+	cases := map[string]*Encryption{
+		"A256GCMKW":    newTestEncryption(t, "enc-aes", false),
+		"RSA-OAEP-256": {Keys: []*EncryptionKey{oaepKey}},
+	}
+	for name, encryption := range cases {
+		t.Run(name, func(t *testing.T) {
+			j := &JWT{SigningKey: []byte("jwe-secret"), Encryption: encryption}
+			token, err := j.CreateToken(testClaims("1001"))
+			require.NoError(t, err)
+			assert.Equal(t, 4, strings.Count(token, "."))
+
+			// 载荷不再以明文出现
+			header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			require.NoError(t, err)
+			assert.Contains(t, string(header), `"cty":"JWT"`)
+			assert.NotContains(t, token, base64.RawURLEncoding.EncodeToString([]byte("1001")))
+
+			claims, err := j.ResolveToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "1001", claims.UserId)
+		})
+	}
+}
+
+// TestEncryptionTamper 测试篡改任意一段的 JWE 均被拒绝
+func TestEncryptionTamper(t *testing.T) {
+	setupTestConfig(t)
+	j := &JWT{SigningKey: []byte("jwe-secret"), Encryption: newTestEncryption(t, "enc-aes", false)}
+	token, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+
+	segments := map[string]int{"header": 0, "encrypted-key": 1, "iv": 2, "ciphertext": 3, "tag": 4}
+	for name, index := range segments {
+		t.Run(name, func(t *testing.T) {
+			parts := strings.Split(token, ".")
+			segment, err := base64.RawURLEncoding.DecodeString(parts[index])
+			require.NoError(t, err)
+			segment[len(segment)-1] ^= 0x01
+			parts[index] = base64.RawURLEncoding.EncodeToString(segment)
+
+			_, err = j.ResolveToken(strings.Join(parts, "."))
+			assert.Error(t, err)
+		})
+	}
+
+	// 头部改动后作为附加认证数据校验失败
+	parts := strings.Split(token, ".")
+	parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"A256GCMKW","enc":"A256GCM","kid":"enc-aes","cty":"JWT","iv":"x"}`))
+	_, err = j.ResolveToken(strings.Join(parts, "."))
+	assert.Error(t, err)
+
+	// 其他密钥无法解密
+	other, err := NewEncryptionKey("enc-aes", KeyAlgA256GCMKW, "", []byte(strings.Repeat("o", 32)))
+	require.NoError(t, err)
+	_, err = (&JWT{SigningKey: []byte("jwe-secret"), Encryption: &Encryption{Keys: []*EncryptionKey{other}}}).ResolveToken(token)
+	assert.ErrorIs(t, err, TokenInvalid)
+}
+
+// TestEncryptionRequired 测试要求加密时拒绝普通 JWS
+func TestEncryptionRequired(t *testing.T) {
+	setupTestConfig(t)
+	plain, err := (&JWT{SigningKey: []byte("jwe-secret")}).CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+
+	j := &JWT{SigningKey: []byte("jwe-secret"), Encryption: newTestEncryption(t, "enc-aes", false)}
+	_, err = j.ResolveToken(plain)
+	assert.NoError(t, err)
+
+	j.Encryption.Required = true
+	_, err = j.ResolveToken(plain)
+	assert.ErrorIs(t, err, TokenEncryptionRequired)
+}
+
+// TestEncryptionFromConfig 测试 NewJWT 从配置 jwt-encryption 开启加密
+func TestEncryptionFromConfig(t *testing.T) {
+	setupTestConfig(t)
+	vp := setupTestEncryption(t)
+	SetJWTSignKey("jwe-secret")
+	t.Cleanup(func() { SetJWTSignKey("") })
+
+	vp.Set(JWTEncryptionConfigKey, map[string]interface{}{
+		"enabled":  true,
+		"required": true,
+		"key-id":   "enc-config",
+		"secret":   strings.Repeat("c", 32),
+	})
+	j := NewJWT()
+	require.NotNil(t, j.Encryption)
+	assert.True(t, j.Encryption.Required)
+	assert.Equal(t, "enc-config", j.Encryption.Keys[0].KeyId)
+
+	token, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(token, "."))
+	claims, err := NewJWT().ResolveToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "1001", claims.UserId)
+
+	// SetJWTEncryption 优先于配置
+	SetJWTEncryption(nil)
+	assert.Nil(t, NewJWT().Encryption)
+}
+
+// TestEncryptionConfigInvalid 测试加密配置无效时拒绝签发，不退化为明文 JWS
+func TestEncryptionConfigInvalid(t *testing.T) {
+	setupTestConfig(t)
+	vp := setupTestEncryption(t)
+	vp.Set(JWTEncryptionConfigKey, map[string]interface{}{"enabled": true, "secret": "short"})
+
+	assert.Error(t, LoadJWTEncryption())
+
+	j := &JWT{SigningKey: []byte("jwe-secret"), Encryption: GetJWTEncryption()}
+	require.NotNil(t, j.Encryption)
+	_, err := j.CreateToken(testClaims("1001"))
+	assert.ErrorIs(t, err, EncryptionKeyNotFound)
+}
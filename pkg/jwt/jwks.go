@@ -0,0 +1,296 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 09:48:10
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 09:48:10
+ * @FilePath: \go-core\pkg\jwt\jwks.go
+ * @Description: JWKS 公钥集生成与基于远程 JWKS 的验签
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKS 远程公钥集默认配置
+const (
+	DefaultJWKSRefreshInterval = time.Hour        // 定期刷新间隔
+	DefaultJWKSMinRefreshGap   = 10 * time.Second // 遇到未知 kid 时两次刷新的最小间隔
+)
+
+// JWK 单个公钥，遵循 RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // 曲线名称
+	X   string `json:"x,omitempty"`   // EC / OKP 公钥 X 坐标
+	Y   string `json:"y,omitempty"`   // EC 公钥 Y 坐标
+}
+
+// JWKSet 公钥集
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK 将密钥转换为 JWK，HMAC 共享密钥不可公开
+func NewJWK(key *SigningKey) (JWK, error) {
+	if key == nil || key.IsSymmetric() {
+		return JWK{}, KeyUnsupported
+	}
+	jwk := JWK{Kid: key.KeyId, Use: "sig", Alg: key.Method.Alg()}
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(publicKey.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encodeSegment(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(publicKey)
+	default:
+		return JWK{}, KeyUnsupported
+	}
+	return jwk, nil
+}
+
+// NewJWKSet 生成公钥集，忽略无法公开的 HMAC 密钥
+func NewJWKSet(keys ...*SigningKey) *JWKSet {
+	set := &JWKSet{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		if jwk, err := NewJWK(key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKSHandler 返回输出密钥提供者公钥集的 http.HandlerFunc，Gin 中可使用 gin.WrapF 挂载
+func JWKSHandler(provider KeyProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_ = json.NewEncoder(w).Encode(NewJWKSet(provider.PublicKeys()...))
+	}
+}
+
+// SigningKey 将 JWK 转换为仅验签的密钥
+func (k JWK) SigningKey() (*SigningKey, error) {
+	var publicKey interface{}
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		curve, ok := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", KeyUnsupported, k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "OKP":
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: %s", KeyUnsupported, k.Crv)
+		}
+		publicKey = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("%w: %s", KeyUnsupported, k.Kty)
+	}
+	return newAsymmetricKey(k.Kid, k.Alg, nil, publicKey)
+}
+
+// encodeSegment base64url 编码（无填充）
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSegment base64url 解码（无填充）
+func decodeSegment(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
+
+// JWKSKeyProvider 基于远程 JWKS 的验签密钥提供者，只能验签不能签发
+type JWKSKeyProvider struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	minRefreshGap   time.Duration
+	mu              sync.RWMutex
+	keys            map[string]*SigningKey
+	fetchedAt       time.Time // 最近一次成功拉取时间
+	attemptedAt     time.Time // 最近一次尝试拉取时间
+}
+
+// JWKSOption JWKS 密钥提供者选项
+type JWKSOption func(*JWKSKeyProvider)
+
+// WithJWKSHTTPClient 设置获取 JWKS 使用的 HTTP 客户端
+func WithJWKSHTTPClient(client *http.Client) JWKSOption {
+	return func(p *JWKSKeyProvider) {
+		p.client = client
+	}
+}
+
+// WithJWKSRefreshInterval 设置 JWKS 定期刷新间隔
+func WithJWKSRefreshInterval(interval time.Duration) JWKSOption {
+	return func(p *JWKSKeyProvider) {
+		p.refreshInterval = interval
+	}
+}
+
+// WithJWKSMinRefreshGap 设置遇到未知 kid 时两次刷新的最小间隔
+func WithJWKSMinRefreshGap(gap time.Duration) JWKSOption {
+	return func(p *JWKSKeyProvider) {
+		p.minRefreshGap = gap
+	}
+}
+
+// NewJWKSKeyProvider 创建远程 JWKS 密钥提供者，首次验签时拉取公钥集
+func NewJWKSKeyProvider(url string, opts ...JWKSOption) *JWKSKeyProvider {
+	p := &JWKSKeyProvider{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: DefaultJWKSRefreshInterval,
+		minRefreshGap:   DefaultJWKSMinRefreshGap,
+		keys:            make(map[string]*SigningKey),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// SigningKey 远程公钥集不能用于签发
+func (p *JWKSKeyProvider) SigningKey() (*SigningKey, error) {
+	return nil, KeyVerifyOnly
+}
+
+// VerificationKey 按 kid 查找验签密钥，缓存过期或未找到 kid 时重新拉取
+func (p *JWKSKeyProvider) VerificationKey(kid, algorithm string) (*SigningKey, error) {
+	p.mu.RLock()
+	key, found := p.lookup(kid)
+	stale := time.Since(p.fetchedAt) > p.refreshInterval
+	canRefresh := time.Since(p.attemptedAt) > p.minRefreshGap
+	p.mu.RUnlock()
+
+	if canRefresh && (stale || !found) {
+		if err := p.Refresh(); err != nil && !found {
+			return nil, err
+		}
+		p.mu.RLock()
+		key, found = p.lookup(kid)
+		p.mu.RUnlock()
+	}
+	if !found {
+		return nil, KeyNotFound
+	}
+	if key.Method.Alg() != algorithm {
+		return nil, KeyAlgorithmMismatch
+	}
+	return key, nil
+}
+
+// lookup 查找缓存的密钥，kid 为空且只有一个密钥时返回该密钥
+func (p *JWKSKeyProvider) lookup(kid string) (*SigningKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// PublicKeys 返回缓存的公钥
+func (p *JWKSKeyProvider) PublicKeys() []*SigningKey {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make([]*SigningKey, 0, len(p.keys))
+	for _, key := range p.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Refresh 立即拉取远程公钥集
+func (p *JWKSKeyProvider) Refresh() error {
+	p.mu.Lock()
+	p.attemptedAt = time.Now()
+	p.mu.Unlock()
+
+	resp, err := p.client.Get(p.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取 JWKS 失败，状态码: %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+	keys := make(map[string]*SigningKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.SigningKey()
+		if err != nil {
+			continue
+		}
+		keys[key.KeyId] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// 确保实现 KeyProvider 接口
+var (
+	_ KeyProvider = (*StaticKeyProvider)(nil)
+	_ KeyProvider = (*JWKSKeyProvider)(nil)
+)
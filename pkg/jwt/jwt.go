@@ -12,30 +12,44 @@ package jwt
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
-	"github.com/redis/go-redis/v9"
-
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/kamalyes/go-core/pkg/global"
-	"gorm.io/gorm"
 )
 
 // 定义一些常量
 var (
-	TokenExpired     error = errors.New("Token 已经过期")
-	TokenNotValidYet error = errors.New("Token 尚未激活")
-	TokenMalformed   error = errors.New("Token 格式错误")
-	TokenInvalid     error = errors.New("Token 无法解析")
-	jwtSignKey             = "82011FC650590620FEFAC6500ADAB0F77" // 默认签名用的key
+	TokenExpired     error       = errors.New("Token 已经过期")
+	TokenNotValidYet error       = errors.New("Token 尚未激活")
+	TokenMalformed   error       = errors.New("Token 格式错误")
+	TokenInvalid     error       = errors.New("Token 无法解析")
+	TokenRevoked     error       = errors.New("Token 已被吊销")
+	TokenKicked      error       = errors.New("账号已在其他地方登录，您已被迫下线")
+	jwtSignKey       string      // 默认签名用的key，为空时使用配置中的 jwt.signing-key
+	jwtKeyProvider   KeyProvider // 默认密钥提供者，设置后 NewJWT 使用它代替 jwtSignKey
 )
 
+// jwtRandomKey 未设置签名密钥时使用的进程内随机密钥，重启或多实例部署时签发的 Token 互不认可
+var jwtRandomKey struct {
+	sync.Once
+	key string
+}
+
 // JWT jwt签名结构
 type JWT struct {
-	SigningKey []byte
+	SigningKey         []byte             // HMAC 共享密钥，未设置 Keys 时使用
+	Keys               KeyProvider        // 密钥提供者，设置后优先于 SigningKey
+	RefreshStore       RefreshTokenStore  // 刷新令牌存储，为空时有 Redis 用 Redis，否则用数据库
+	SessionStore       SessionStore       // 会话存储，为空时有 Redis 用 Redis，否则用数据库
+	Policy             *ValidationPolicy  // 校验策略，为空时使用 SetValidationPolicy 设置的默认策略
+	Encryption         *Encryption        // JWE 设置，为空时使用 SetJWTEncryption 设置的默认设置
+	APIKeyStore        APIKeyStore        // API Key 存储，为空时有 Redis 用 Redis，否则用数据库
+	ServiceClientStore ServiceClientStore // 客户端凭证存储，为空时有 Redis 用 Redis，否则用数据库
 }
 
 // SetJWTSignKey 动态设置JWT签名密钥
@@ -43,17 +57,91 @@ func SetJWTSignKey(key string) {
 	jwtSignKey = key
 }
 
-// GetJWTSignKey 获取JWT签名密钥
+// GetJWTSignKey 获取JWT签名密钥，依次使用 SetJWTSignKey 设置的密钥、配置中的 jwt.signing-key 与进程内随机密钥
 func GetJWTSignKey() string {
-	return jwtSignKey
+	if jwtSignKey != "" {
+		return jwtSignKey
+	}
+	if global.CONFIG != nil && global.CONFIG.JWT.SigningKey != "" {
+		return global.CONFIG.JWT.SigningKey
+	}
+	return randomSignKey()
+}
+
+// randomSignKey 生成进程内随机签名密钥并告警，只生成一次
+func randomSignKey() string {
+	jwtRandomKey.Do(func() {
+		key, err := randomToken(32)
+		if err != nil {
+			panic("生成JWT随机签名密钥失败：" + err.Error())
+		}
+		jwtRandomKey.key = key
+		message := "未配置JWT签名密钥（jwt.signing-key 或 SetJWTSignKey），已使用进程内随机密钥，重启后已签发的 Token 全部失效，多实例部署时互不认可"
+		if global.LOGGER != nil {
+			global.LOGGER.Warn(message)
+		} else {
+			log.Println(message)
+		}
+	})
+	return jwtRandomKey.key
+}
+
+// SetJWTKeyProvider 设置默认密钥提供者，如密钥环，设置后 NewJWT 创建的实例均使用它签发与验签
+func SetJWTKeyProvider(provider KeyProvider) {
+	jwtKeyProvider = provider
+}
+
+// GetJWTKeyProvider 获取默认密钥提供者
+func GetJWTKeyProvider() KeyProvider {
+	return jwtKeyProvider
 }
 
 // NewJWT 新建一个 jwt 实例
 func NewJWT() *JWT {
-	return &JWT{[]byte(GetJWTSignKey())}
+	return &JWT{SigningKey: []byte(GetJWTSignKey()), Keys: GetJWTKeyProvider(), Encryption: GetJWTEncryption()}
+}
+
+// NewJWTWithKeys 使用密钥提供者新建 jwt 实例，提供者仅持有公钥时只能验签
+func NewJWTWithKeys(keys KeyProvider) *JWT {
+	return &JWT{Keys: keys}
 }
 
-// RegisteredClaims expiresAt 过期时间单位秒
+// keyProvider 获取密钥提供者，未设置时使用 SigningKey 作为 HS256 共享密钥
+func (j *JWT) keyProvider() KeyProvider {
+	if j.Keys != nil {
+		return j.Keys
+	}
+	return NewStaticKeyProvider(&SigningKey{Method: jwt.SigningMethodHS256, PrivateKey: j.SigningKey, PublicKey: j.SigningKey})
+}
+
+// keyFunc 按 Token 头部的 kid 与 alg 查找验签密钥
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := j.keyProvider().VerificationKey(kid, token.Method.Alg())
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey, nil
+}
+
+// signToken 使用当前签名密钥签发 token，开启 JWE 时再加密
+func (j *JWT) signToken(claims jwt.Claims) (string, error) {
+	key, err := j.keyProvider().SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.KeyId != "" {
+		token.Header["kid"] = key.KeyId
+	}
+	signed, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return j.wrapToken(signed)
+}
+
+// RegisteredClaims expiresAt 过期时间单位秒，iat、nbf、jti、sub 在签发时自动补全
 func RegisteredClaims(issuer string, expiresAt int64) jwt.RegisteredClaims {
 	return jwt.RegisteredClaims{
 		Issuer:    issuer,
@@ -61,90 +149,137 @@ func RegisteredClaims(issuer string, expiresAt int64) jwt.RegisteredClaims {
 	}
 }
 
-// CreateToken 生成 token
+// CreateToken 生成 token，开启多点登录拦截时同时登记会话
 func (j *JWT) CreateToken(claims CustomClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// 判断多点登录拦截是否开启
-	if global.CONFIG.JWT.UseMultipoint {
-		// 拦截
-		if global.REDIS != nil {
-			// 优先存入到 redis
-			jsonData, _ := json.Marshal(claims)
-			toJson := string(jsonData)
-			// 此处过期时间等于jwt过期时间
-			timer := time.Duration(global.CONFIG.JWT.ExpiresTime) * time.Second
-			err := global.REDIS.Set(context.Background(), claims.UserId, toJson, timer).Err()
-			if err != nil {
-				return "", err
-			}
-			return token.SignedString(j.SigningKey)
-		}
-		// 没有redis存入到 数据库
-		err := global.DB.Save(&claims).Error
+	return j.createToken(&claims)
+}
+
+// createToken 校验并签发 token，开启多点登录拦截时同时登记会话，机器身份不登记
+func (j *JWT) createToken(claims Claims) (string, error) {
+	if j.isMultipointAuthEnabled() && !isServiceClaims(claims) {
+		token, _, err := j.createSessionToken(context.Background(), claims, SessionMeta{})
+		return token, err
+	}
+	if err := validateClaims(claims); err != nil {
+		return "", err
+	}
+	if err := j.prepareClaims(claims); err != nil {
+		return "", err
+	}
+	return j.signToken(claims)
+}
+
+// prepareClaims 补全签发时间 iat、生效时间 nbf、jti（即 tokenId，为空时生成）与 sub（即用户id）
+// 并按校验策略补全 iss 与 aud，签发时间用于按用户批量吊销
+func (j *JWT) prepareClaims(claims Claims) error {
+	if claims.GetTokenId() == "" {
+		tokenId, err := randomToken(16)
 		if err != nil {
-			return "", err
-		} else {
-			return token.SignedString(j.SigningKey)
+			return err
 		}
+		setTokenId(claims, tokenId)
+	}
+	registered := claims.GetRegisteredClaims()
+	if registered.IssuedAt == nil {
+		registered.IssuedAt = jwt.NewNumericDate(time.Now())
+	}
+	if registered.NotBefore == nil {
+		registered.NotBefore = registered.IssuedAt
+	}
+	if registered.ID == "" {
+		registered.ID = claims.GetTokenId()
 	}
-	// 不拦截
-	return token.SignedString(j.SigningKey)
+	if registered.Subject == "" {
+		registered.Subject = claims.GetUserId()
+	}
+	j.validationPolicy().applyDefaults(registered)
+	return nil
 }
 
-// DeleteToken 强制删除Token记录，用途--用户账号被盗后，强制下线
+// DeleteToken 强制删除Token记录，用途--用户账号被盗后，强制下线该用户的全部会话
+// 按用户吊销此前签发的全部 Token，未开启多点登录拦截、没有会话记录时同样生效
 func DeleteToken(userId string) (err error) {
-	if global.REDIS != nil {
-		err = global.REDIS.Del(context.Background(), userId).Err()
+	if err := RevokeUserTokensBefore(userId, time.Now()); err != nil {
 		return err
 	}
-	err = global.DB.Where("user_id = ?", userId).Delete(&CustomClaims{}).Error
-	return err
+	return NewJWT().TerminateAllSessions(context.Background(), userId)
 }
 
 // ResolveToken 解析token
 func (j *JWT) ResolveToken(tokenString string) (*CustomClaims, error) {
-	token, parseErr := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.SigningKey, nil
-	})
-	if parseErr != nil {
-		return handleTokenParseError(parseErr)
-	}
-
-	if token != nil && token.Valid {
-		claims, ok := token.Claims.(*CustomClaims)
-		if !ok {
-			return nil, TokenInvalid
-		}
+	return resolveToken[*CustomClaims](j, tokenString)
+}
 
-		if !j.isMultipointAuthEnabled() {
-			return claims, nil
-		}
+// ResolveClaims 实现 ClaimsResolver
+func (j *JWT) ResolveClaims(tokenString string) (Claims, error) {
+	return j.ResolveToken(tokenString)
+}
 
-		if err := j.checkMultipointAuth(claims); err != nil {
-			return nil, err
-		}
+// resolveToken 解析 token 到 T 类型的 claims，按校验策略校验后检查吊销记录与多点登录
+func resolveToken[T Claims](j *JWT, tokenString string) (T, error) {
+	var zero T
+	claims, err := newClaims[T]()
+	if err != nil {
+		return zero, err
+	}
+	tokenString, err = j.unwrapToken(tokenString)
+	if err != nil {
+		return zero, err
+	}
+	// 服务 Token 只能由 ResolveServiceToken 解析，否则会以空的用户id 通过用户 Token 的校验
+	if _, isUser := any(zero).(*CustomClaims); isUser && tokenUseOf(tokenString) != "" {
+		return zero, TokenInvalid
+	}
+	// 时间相关的 claims 由校验策略按时钟偏差校验
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, parseErr := parser.ParseWithClaims(tokenString, claims, j.keyFunc)
+	if parseErr != nil {
+		return zero, handleTokenParseError(parseErr)
+	}
+	if token == nil || !token.Valid {
+		return zero, TokenInvalid
+	}
+	if err := j.verifyClaims(token, claims); err != nil {
+		return zero, err
+	}
+	return claims, nil
+}
 
-		return claims, nil
+// verifyClaims 按校验策略校验，再检查吊销记录，开启多点登录拦截时检查会话
+func (j *JWT) verifyClaims(token *jwt.Token, claims Claims) error {
+	policy := j.validationPolicy()
+	registered := claims.GetRegisteredClaims()
+	if err := policy.verifyTime(registered, time.Now()); err != nil {
+		return err
+	}
+	if err := policy.verifyIdentity(token, registered); err != nil {
+		return err
 	}
 
-	return nil, TokenInvalid
+	if err := checkRevoked(claims); err != nil {
+		return err
+	}
+	if !j.isMultipointAuthEnabled() || isServiceClaims(claims) {
+		return nil
+	}
+	return j.checkMultipointAuth(claims)
 }
 
 // handleTokenParseError 处理token解析错误
-func handleTokenParseError(err error) (*CustomClaims, error) {
+func handleTokenParseError(err error) error {
 	if ve, ok := err.(*jwt.ValidationError); ok {
 		switch {
 		case ve.Errors&jwt.ValidationErrorMalformed != 0:
-			return nil, TokenMalformed
+			return TokenMalformed
 		case ve.Errors&jwt.ValidationErrorExpired != 0:
-			return nil, TokenExpired
+			return TokenExpired
 		case ve.Errors&jwt.ValidationErrorNotValidYet != 0:
-			return nil, TokenNotValidYet
+			return TokenNotValidYet
 		default:
-			return nil, TokenInvalid
+			return TokenInvalid
 		}
 	}
-	return nil, err
+	return err
 }
 
 // isMultipointAuthEnabled 检查是否启用多点登录拦截
@@ -152,80 +287,126 @@ func (j *JWT) isMultipointAuthEnabled() bool {
 	return global.CONFIG.JWT.UseMultipoint
 }
 
-// checkMultipointAuth 检查多点登录验证
-func (j *JWT) checkMultipointAuth(claims *CustomClaims) error {
-	if global.REDIS != nil {
-		if jsonStr, err := global.REDIS.Get(context.Background(), claims.UserId).Result(); err == redis.Nil || jsonStr == "" {
-			return nil
-		} else {
-			return j.checkRedisMultipointAuth(claims, jsonStr)
-		}
+// checkMultipointAuth 检查多点登录验证，会话被踢出或下线后 token 失效
+func (j *JWT) checkMultipointAuth(claims Claims) error {
+	tokenId := claims.GetTokenId()
+	if tokenId == "" {
+		return TokenInvalid
+	}
+	exists, err := j.sessionStore().Exists(context.Background(), tokenId)
+	if err != nil {
+		return errors.New("获取用户会话异常：" + err.Error())
 	}
-	return j.checkDBMultipointAuth(claims)
+	if !exists {
+		return TokenKicked
+	}
+	return nil
+}
+
+// RefreshToken 更新token，仅允许在过期后 BufferTime 秒内刷新，已吊销的 token 不能刷新，新 token 使用新的 tokenId
+// 开启多点登录拦截时原会话迁移到新 tokenId，原 token 随即吊销
+// 长期会话请使用 CreateTokenPair / RefreshTokenPair
+func (j *JWT) RefreshToken(tokenString string) (string, error) {
+	return refreshToken[*CustomClaims](j, tokenString)
 }
 
-// checkRedisMultipointAuth 检查Redis中的多点登录验证
-func (j *JWT) checkRedisMultipointAuth(claims *CustomClaims, jsonStr string) error {
-	var clis CustomClaims
-	if err := json.Unmarshal([]byte(jsonStr), &clis); err != nil {
-		return errors.New("解析Redis中的用户token时出错: " + err.Error())
+// refreshToken 解析已过期的 token 并以 T 类型的 claims 重新签发
+func refreshToken[T Claims](j *JWT, tokenString string) (string, error) {
+	claims, err := newClaims[T]()
+	if err != nil {
+		return "", err
+	}
+	tokenString, err = j.unwrapToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	// 跳过 claims 校验以允许解析已过期的 token，签名仍会校验
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, j.keyFunc)
+	if err != nil {
+		return handleTokenRefreshError(err)
+	}
+	if !token.Valid {
+		return "", TokenInvalid
 	}
 
-	if clis.TokenId != "" && claims.TokenId != clis.TokenId {
-		return errors.New("账号已在其他地方登录，您已被迫下线")
+	now := time.Now()
+	registered := claims.GetRegisteredClaims()
+	if registered.ExpiresAt != nil {
+		bufferTime := claimsBufferTime(claims)
+		if now.After(registered.ExpiresAt.Add(time.Duration(bufferTime) * time.Second)) {
+			return "", TokenExpired
+		}
+	}
+	if registered.NotBefore != nil && now.Before(registered.NotBefore.Time) {
+		return "", TokenNotValidYet
+	}
+	if err := j.validationPolicy().verifyIdentity(token, registered); err != nil {
+		return "", err
+	}
+	if err := checkRevoked(claims); err != nil {
+		return "", err
+	}
+	// 保留原签发时间，MaxAge 按首次签发时间计算
+	expiresAt, err := j.validationPolicy().renewedExpiresAt(registered, now, time.Duration(global.CONFIG.JWT.ExpiresTime)*time.Second)
+	if err != nil {
+		return "", err
+	}
+	previousTokenId := claims.GetTokenId()
+	if err := reissueTokenId(claims); err != nil {
+		return "", err
 	}
 
-	return nil
+	registered.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	return j.rotateSessionToken(context.Background(), claims, previousTokenId)
 }
 
-// checkDBMultipointAuth 检查数据库中的多点登录验证
-func (j *JWT) checkDBMultipointAuth(claims *CustomClaims) error {
-	var clis CustomClaims
-	if err := global.DB.Where("user_id = ?", claims.UserId).First(&clis).Error; err != nil && err != gorm.ErrRecordNotFound {
-		return errors.New("从数据库获取用户token异常：" + err.Error())
+// checkRevoked 已吊销时返回 TokenRevoked
+func checkRevoked(claims Claims) error {
+	revoked, err := IsTokenRevoked(claims)
+	if err != nil {
+		return err
 	}
-
-	if claims.TokenId != clis.TokenId {
-		return errors.New("账号已在其他地方登录，您已被迫下线")
+	if revoked {
+		return TokenRevoked
 	}
-
 	return nil
 }
 
-// RefreshToken 更新token
-func (j *JWT) RefreshToken(tokenString string) (string, error) {
-	jwt.TimeFunc = func() time.Time {
-		return time.Unix(0, 0)
-	}
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.SigningKey, nil
-	})
+// reissueTokenId 刷新与续签时更换 tokenId（即 jti）
+// 吊销记录只保留到原 token 过期，沿用原 tokenId 会使已吊销的 token 续签后重新生效
+func reissueTokenId(claims Claims) error {
+	tokenId, err := randomToken(16)
 	if err != nil {
-		return "", err
-	}
-	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
-		jwt.TimeFunc = time.Now
-		claims.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Unix(time.Now().Unix()+global.CONFIG.JWT.ExpiresTime, 0))
-		return j.CreateToken(*claims)
+		return err
 	}
-	return "", TokenInvalid
+	setTokenId(claims, tokenId)
+	claims.GetRegisteredClaims().ID = tokenId
+	return nil
+}
+
+// handleTokenRefreshError 处理刷新token时的解析错误
+func handleTokenRefreshError(err error) (string, error) {
+	return "", handleTokenParseError(err)
 }
 
 // GetClaims 获取Claims
 func GetClaims(c *gin.Context) (*CustomClaims, error) {
-	if claims, exists := c.Get("claims"); !exists {
-		global.LOGGER.Error("从Gin的Context中获取从jwt解析出来的用户claims失败, 请检查路由是否使用jwt中间件")
-		return nil, errors.New("获取用户用户claims失败")
-	} else {
-		token := claims.(*CustomClaims)
-		return token, nil
+	claims, err := GetTypedClaims[*CustomClaims](c)
+	if err != nil && global.LOGGER != nil {
+		global.LOGGER.WithError(err).ErrorMsg("从Gin的Context中获取从jwt解析出来的用户claims失败, 请检查路由是否使用jwt中间件")
 	}
+	return claims, err
 }
 
 // ClaimHandlerFunc 定义处理声明的函数
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 type ClaimHandlerFunc func(*CustomClaims) interface{}
 
 // ClaimHandlers 存储不同类型Claim的处理函数
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 var ClaimHandlers = map[string]ClaimHandlerFunc{
 	"TokenId":      func(claims *CustomClaims) interface{} { return claims.TokenId },
 	"UserId":       func(claims *CustomClaims) interface{} { return claims.UserId },
@@ -242,108 +423,106 @@ var ClaimHandlers = map[string]ClaimHandlerFunc{
 }
 
 // GetClaimValue 从Gin的Context中获取特定类型的Claim值，通过ClaimHandlers映射来获取
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 func GetClaimValue(c *gin.Context, key string) interface{} {
-	claims, exists := c.Get("claims")
-	if !exists {
-		return nil
-	}
-
-	customClaims, ok := claims.(*CustomClaims)
-	if !ok {
-		return nil
-	}
-
 	handler, found := ClaimHandlers[key]
 	if !found {
 		return nil
 	}
-
-	return handler(customClaims)
+	value, err := ClaimValue[*CustomClaims, interface{}](c, handler)
+	if err != nil {
+		return nil
+	}
+	return value
 }
 
 // GetStringClaimValue 从Gin的Context中获取字符串类型的Claim值
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 func GetStringClaimValue(c *gin.Context, key string) string {
-	value := GetClaimValue(c, key)
-	if strValue, ok := value.(string); ok {
-		return strValue
-	}
-	return ""
+	value, _ := GetClaimValue(c, key).(string)
+	return value
 }
 
 // GetInt32ClaimValue 从Gin的Context中获取Int32类型的Claim值
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 func GetInt32ClaimValue(c *gin.Context, key string) int32 {
-	value := GetClaimValue(c, key)
-	if intValue, ok := value.(int32); ok {
-		return intValue
-	}
-	return 0
+	value, _ := GetClaimValue(c, key).(int32)
+	return value
 }
 
 // GetInt64ClaimValue 从Gin的Context中获取Int64类型的Claim值
+//
+// Deprecated: 使用 ClaimValue 读取 claims 字段，下个版本移除
 func GetInt64ClaimValue(c *gin.Context, key string) int64 {
-	value := GetClaimValue(c, key)
-	if intValue, ok := value.(int64); ok {
-		return intValue
-	}
-	return 0
+	value, _ := GetClaimValue(c, key).(int64)
+	return value
+}
+
+// customClaimValue 从Gin的Context中获取 CustomClaims 的字段，claims 不存在时返回零值
+func customClaimValue[V any](c *gin.Context, field func(*CustomClaims) V) V {
+	value, _ := ClaimValue(c, field)
+	return value
 }
 
 // GetTokenId 获取Token Id
 func GetTokenId(c *gin.Context) string {
-	return GetStringClaimValue(c, "TokenId")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.TokenId })
 }
 
 // GetUserId 获取用户Id
 func GetUserId(c *gin.Context) string {
-	return GetStringClaimValue(c, "UserId")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.UserId })
 }
 
 // GetUserName 获取用户名
 func GetUserName(c *gin.Context) string {
-	return GetStringClaimValue(c, "UserName")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.UserName })
 }
 
 // GetUserType 获取用户类型
 func GetUserType(c *gin.Context) string {
-	return GetStringClaimValue(c, "UserType")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.UserType })
 }
 
 // GetNickName 获取用户昵称
 func GetNickName(c *gin.Context) string {
-	return GetStringClaimValue(c, "NickName")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.NickName })
 }
 
 // GetPhoneNumber 获取用户手机号
 func GetPhoneNumber(c *gin.Context) string {
-	return GetStringClaimValue(c, "PhoneNumber")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.PhoneNumber })
 }
 
 // GetMerchantNo 获取商户号
 func GetMerchantNo(c *gin.Context) string {
-	return GetStringClaimValue(c, "MerchantNo")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.MerchantNo })
 }
 
 // GetUserAuthorityId 获取用户角色Id
 func GetUserAuthorityId(c *gin.Context) string {
-	return GetStringClaimValue(c, "AuthorityId")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.AuthorityId })
 }
 
 // GetAppProductId 获取AppProduct Id
 func GetAppProductId(c *gin.Context) int32 {
-	return GetInt32ClaimValue(c, "AppProductId")
+	return customClaimValue(c, func(claims *CustomClaims) int32 { return claims.AppProductId })
 }
 
 // GetPlatformType 获取Platform Type
 func GetPlatformType(c *gin.Context) int32 {
-	return GetInt32ClaimValue(c, "PlatformType")
+	return customClaimValue(c, func(claims *CustomClaims) int32 { return claims.PlatformType })
 }
 
 // GetBufferTime 获取BufferTime
 func GetBufferTime(c *gin.Context) int64 {
-	return GetInt64ClaimValue(c, "BufferTime")
+	return customClaimValue(c, func(claims *CustomClaims) int64 { return claims.BufferTime })
 }
 
 // GetExtend 获取Extend
 func GetExtend(c *gin.Context) string {
-	return GetStringClaimValue(c, "Extend")
+	return customClaimValue(c, func(claims *CustomClaims) string { return claims.Extend })
 }
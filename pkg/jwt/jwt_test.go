@@ -0,0 +1,392 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 09:10:12
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 09:10:12
+ * @FilePath: \go-core\pkg\jwt\jwt_test.go
+ * @Description: 签名密钥与验签测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	goconfig "github.com/kamalyes/go-config"
+	jwtconfig "github.com/kamalyes/go-config/pkg/jwt"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestConfig 设置测试用的全局配置，测试结束后恢复
+func setupTestConfig(t *testing.T) {
+	originalConfig := global.CONFIG
+	global.CONFIG = &goconfig.SingleConfig{JWT: jwtconfig.JWT{ExpiresTime: 3600, BufferTime: 600}}
+	t.Cleanup(func() { global.CONFIG = originalConfig })
+}
+
+// setupTestRedis 使用 miniredis 作为 global.REDIS，测试结束后恢复
+func setupTestRedis(t *testing.T) *miniredis.Miniredis {
+	server := miniredis.RunT(t)
+	originalRedis := global.REDIS
+	global.REDIS = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		global.REDIS.Close()
+		global.REDIS = originalRedis
+	})
+	return server
+}
+
+// newRSAKey 生成测试用的 RSA 密钥
+func newRSAKey(t *testing.T, kid string) *SigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := newAsymmetricKey(kid, "", privateKey, &privateKey.PublicKey)
+	require.NoError(t, err)
+	return key
+}
+
+// testClaims 测试用的 claims
+func testClaims(userId string) CustomClaims {
+	return CustomClaims{
+		UserId:           userId,
+		UserName:         "tester",
+		RegisteredClaims: RegisteredClaims("test", time.Now().Add(time.Hour).Unix()),
+	}
+}
+
+// TestAlgorithmConfusion 测试篡改 Token 头部的 alg 无法绕过验签
+func TestAlgorithmConfusion(t *testing.T) {
+	setupTestConfig(t)
+	key := newRSAKey(t, "rsa-1")
+	j := NewJWTWithKeys(NewStaticKeyProvider(key))
+
+	token, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	resolved, err := j.ResolveToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "1001", resolved.UserId)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	claims := testClaims("1001")
+
+	// 以公钥作为 HMAC 密钥伪造 HS256 Token
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	forged.Header["kid"] = "rsa-1"
+	forgedToken, err := forged.SignedString(publicPEM)
+	require.NoError(t, err)
+	_, err = j.ResolveToken(forgedToken)
+	assert.Error(t, err)
+
+	// alg 为 none 的 Token
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, &claims)
+	unsigned.Header["kid"] = "rsa-1"
+	unsignedToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = j.ResolveToken(unsignedToken)
+	assert.Error(t, err)
+
+	// 同为 RS256 但使用其他私钥签名
+	other := NewJWTWithKeys(NewStaticKeyProvider(newRSAKey(t, "rsa-1")))
+	otherToken, err := other.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	_, err = j.ResolveToken(otherToken)
+	assert.Error(t, err)
+
+	// 刷新同样校验签名算法
+	_, err = j.RefreshToken(forgedToken)
+	assert.Error(t, err)
+}
+
+// TestDefaultSignKey 测试未配置签名密钥时不使用固定的默认密钥
+func TestDefaultSignKey(t *testing.T) {
+	setupTestConfig(t)
+	originalKey := jwtSignKey
+	SetJWTSignKey("")
+	defer SetJWTSignKey(originalKey)
+
+	// 未配置时使用进程内随机密钥，且保持不变
+	randomKey := GetJWTSignKey()
+	assert.Len(t, randomKey, 64)
+	assert.Equal(t, randomKey, GetJWTSignKey())
+
+	token, err := NewJWT().CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	_, err = NewJWT().ResolveToken(token)
+	assert.NoError(t, err)
+
+	// 使用旧版内置密钥伪造的 Token 无法通过验签
+	claims := testClaims("1001")
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString([]byte("82011FC650590620FEFAC6500ADAB0F77"))
+	require.NoError(t, err)
+	_, err = NewJWT().ResolveToken(legacy)
+	assert.Error(t, err)
+
+	// 配置中的签名密钥优先于随机密钥
+	global.CONFIG.JWT.SigningKey = "config-secret"
+	assert.Equal(t, "config-secret", GetJWTSignKey())
+	_, err = NewJWT().ResolveToken(token)
+	assert.Error(t, err)
+
+	// SetJWTSignKey 优先于配置
+	SetJWTSignKey("manual-secret")
+	assert.Equal(t, "manual-secret", GetJWTSignKey())
+}
+
+// TestKeyRingRotationGracePeriod 测试定时轮换后旧密钥在宽限期内继续验签
+func TestKeyRingRotationGracePeriod(t *testing.T) {
+	setupTestConfig(t)
+	key, err := GenerateKey("key-1", "HS256")
+	require.NoError(t, err)
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+	j := NewJWTWithKeys(ring)
+
+	oldToken, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+
+	// 未设置 GracePeriod 时使用 ExpiresTime + BufferTime，旧密钥不会立即退役
+	require.NoError(t, ring.rotateIfDue(context.Background(), RotationOptions{Interval: time.Nanosecond}))
+	assert.NotEqual(t, "key-1", ring.ActiveKeyId())
+	for _, entry := range ring.Entries() {
+		if entry.Key.KeyId == "key-1" {
+			assert.WithinDuration(t, time.Now().Add(4200*time.Second), entry.RetireAt, time.Minute)
+		}
+	}
+	_, err = j.ResolveToken(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &CustomClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, ring.ActiveKeyId(), parsed.Header["kid"])
+
+	// 再次轮换并设置较短的宽限期，到期后 key-1 之后的密钥也退役，只有活跃密钥签发的 Token 有效
+	require.NoError(t, ring.rotateIfDue(context.Background(), RotationOptions{Interval: time.Nanosecond, GracePeriod: 50 * time.Millisecond}))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, ring.Retire("key-1"))
+	_, err = j.ResolveToken(oldToken)
+	assert.Error(t, err)
+	_, err = j.ResolveToken(newToken)
+	assert.Error(t, err)
+
+	latestToken, err := j.CreateToken(testClaims("1001"))
+	require.NoError(t, err)
+	_, err = j.ResolveToken(latestToken)
+	assert.NoError(t, err)
+}
+
+// TestStartRotationGracePeriodInvalid 测试无法确定旧密钥保留时长时拒绝启动轮换
+func TestStartRotationGracePeriodInvalid(t *testing.T) {
+	originalConfig := global.CONFIG
+	global.CONFIG = nil
+	defer func() { global.CONFIG = originalConfig }()
+
+	key, err := GenerateKey("key-1", "HS256")
+	require.NoError(t, err)
+	ring, err := NewKeyRing(key)
+	require.NoError(t, err)
+
+	err = ring.StartRotation(context.Background(), RotationOptions{Interval: time.Hour})
+	assert.ErrorIs(t, err, RotationGracePeriodInvalid)
+	assert.ErrorIs(t, ring.rotateIfDue(context.Background(), RotationOptions{Interval: time.Nanosecond}), RotationGracePeriodInvalid)
+	assert.Equal(t, "key-1", ring.ActiveKeyId())
+}
+
+// TestRedisKeyRingStoreLock 测试轮换超过锁的 ttl 后，释放锁不会删除其他实例持有的锁
+func TestRedisKeyRingStoreLock(t *testing.T) {
+	server := setupTestRedis(t)
+	ctx := context.Background()
+	store := &RedisKeyRingStore{Key: "test_keyring"}
+
+	unlockA, ok, err := store.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, ok, err = store.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A 的锁已过期，B 获取到锁后 A 才完成轮换
+	server.FastForward(2 * time.Minute)
+	unlockB, ok, err := store.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	unlockA()
+	_, ok, err = store.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	unlockB()
+	_, ok, err = store.Lock(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// TestRefreshRevokedToken 测试已吊销的 Token 不能刷新或续签，刷新后的 Token 使用新的 tokenId
+func TestRefreshRevokedToken(t *testing.T) {
+	setupTestConfig(t)
+	server := setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("revoke-secret")}
+
+	// 已过期但仍在 BufferTime 内的 Token 可以刷新，并更换 tokenId
+	expiredClaims := testClaims("1001")
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired, err := j.createToken(&expiredClaims)
+	require.NoError(t, err)
+	refreshed, err := j.RefreshToken(expired)
+	require.NoError(t, err)
+	refreshedClaims, err := j.ResolveToken(refreshed)
+	require.NoError(t, err)
+	assert.NotEqual(t, expiredClaims.TokenId, refreshedClaims.TokenId)
+	assert.Equal(t, refreshedClaims.TokenId, refreshedClaims.ID)
+
+	// 吊销后刷新与续签均被拒绝
+	claims := testClaims("1001")
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(2 * time.Second))
+	token, err := j.createToken(&claims)
+	require.NoError(t, err)
+	require.NoError(t, RevokeToken(claims.TokenId, claims.ExpiresAt.Time))
+	_, err = j.RefreshToken(token)
+	assert.ErrorIs(t, err, TokenRevoked)
+	revokedClaims := claims
+	_, err = j.RenewToken(&revokedClaims)
+	assert.ErrorIs(t, err, TokenRevoked)
+
+	// 原 Token 过期后的 BufferTime 内吊销记录仍然有效
+	server.FastForward(time.Minute)
+	_, err = j.RefreshToken(token)
+	assert.ErrorIs(t, err, TokenRevoked)
+	server.FastForward(10 * time.Minute)
+	revoked, err := IsTokenRevoked(&claims)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+// TestDeleteTokenWithoutMultipoint 测试未开启多点登录拦截时强制下线仍使已签发的 Token 失效
+func TestDeleteTokenWithoutMultipoint(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("delete-secret")}
+
+	claims := testClaims("1001")
+	token, err := j.CreateToken(claims)
+	require.NoError(t, err)
+	other, err := j.CreateToken(testClaims("1002"))
+	require.NoError(t, err)
+
+	require.NoError(t, DeleteToken("1001"))
+	// 与吊销同一秒签发的 Token 同样失效
+	_, err = j.ResolveToken(token)
+	assert.ErrorIs(t, err, TokenRevoked)
+	_, err = j.ResolveToken(other)
+	assert.NoError(t, err)
+}
+
+// TestRevokeWithoutStore 测试未配置 Redis 与数据库时吊销返回错误
+func TestRevokeWithoutStore(t *testing.T) {
+	originalConfig, originalRedis, originalDB := global.CONFIG, global.REDIS, global.DB
+	global.CONFIG, global.REDIS, global.DB = nil, nil, nil
+	t.Cleanup(func() { global.CONFIG, global.REDIS, global.DB = originalConfig, originalRedis, originalDB })
+
+	assert.ErrorIs(t, RevokeToken("token", time.Now().Add(time.Hour)), RevocationStoreMissing)
+	assert.ErrorIs(t, RevokeUserTokensBefore("1001", time.Now()), RevocationStoreMissing)
+	assert.ErrorIs(t, RevokeServiceTokensBefore("billing", time.Now()), RevocationStoreMissing)
+}
+
+// TestDeprecatedClaimValue 测试弃用的按名称读取 claims 字段仍然可用
+func TestDeprecatedClaimValue(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Nil(t, GetClaimValue(c, "UserId"))
+
+	claims := testClaims("1001")
+	claims.PlatformType = 2
+	claims.BufferTime = 600
+	c.Set("claims", &claims)
+	assert.Equal(t, "1001", GetStringClaimValue(c, "UserId"))
+	assert.Equal(t, int32(2), GetInt32ClaimValue(c, "PlatformType"))
+	assert.Equal(t, int64(600), GetInt64ClaimValue(c, "BufferTime"))
+	assert.Nil(t, GetClaimValue(c, "Missing"))
+	assert.Empty(t, GetStringClaimValue(c, "PlatformType"))
+}
+
+// TestRenewTokenId 测试续签后吊销原 tokenId 不影响新 Token，吊销新 tokenId 后新 Token 失效
+func TestRenewTokenId(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("renew-secret")}
+
+	claims := testClaims("1001")
+	_, err := j.createToken(&claims)
+	require.NoError(t, err)
+	previousTokenId := claims.TokenId
+
+	renewed := claims
+	token, err := j.RenewToken(&renewed)
+	require.NoError(t, err)
+	assert.NotEqual(t, previousTokenId, renewed.TokenId)
+
+	require.NoError(t, RevokeToken(previousTokenId, claims.ExpiresAt.Time))
+	_, err = j.ResolveToken(token)
+	assert.NoError(t, err)
+
+	require.NoError(t, RevokeToken(renewed.TokenId, renewed.ExpiresAt.Time))
+	_, err = j.ResolveToken(token)
+	assert.ErrorIs(t, err, TokenRevoked)
+}
+
+// TestRenewTokenMaxAge 测试续签保留签发时间，有效期不超过 MaxAge
+func TestRenewTokenMaxAge(t *testing.T) {
+	setupTestConfig(t)
+	j := &JWT{SigningKey: []byte("max-age-secret"), Policy: &ValidationPolicy{MaxAge: 2 * time.Hour}}
+
+	issuedAt := time.Now().Add(-90 * time.Minute)
+	claims := testClaims("1001")
+	claims.IssuedAt = jwt.NewNumericDate(issuedAt)
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	_, err := j.createToken(&claims)
+	require.NoError(t, err)
+
+	// 续签后 iat 不变，exp 截止到 iat + MaxAge
+	token, err := j.RenewToken(&claims)
+	require.NoError(t, err)
+	assert.Equal(t, issuedAt.Unix(), claims.IssuedAt.Unix())
+	assert.Equal(t, issuedAt.Add(2*time.Hour).Unix(), claims.ExpiresAt.Unix())
+	_, err = j.ResolveToken(token)
+	assert.NoError(t, err)
+
+	// 已达到上限时不再续签
+	_, err = j.RenewToken(&claims)
+	assert.ErrorIs(t, err, TokenTooOld)
+
+	// 超过 MaxAge 的 Token 无法续签或刷新
+	tooOld := testClaims("1001")
+	tooOld.IssuedAt = jwt.NewNumericDate(time.Now().Add(-3 * time.Hour))
+	tooOld.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired, err := j.createToken(&tooOld)
+	require.NoError(t, err)
+	_, err = j.RefreshToken(expired)
+	assert.ErrorIs(t, err, TokenTooOld)
+	_, err = j.RenewToken(&tooOld)
+	assert.ErrorIs(t, err, TokenTooOld)
+
+	// 续签前重新校验 claims
+	invalid := testClaims("")
+	_, err = j.RenewToken(&invalid)
+	assert.ErrorIs(t, err, ClaimsInvalid)
+}
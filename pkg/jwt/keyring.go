@@ -0,0 +1,558 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 10:36:52
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 10:36:52
+ * @FilePath: \go-core\pkg\jwt\keyring.go
+ * @Description: 密钥环，按 kid 管理多把密钥，支持定时与运行时轮换
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyRingEntry 密钥环中的密钥
+type KeyRingEntry struct {
+	Key       *SigningKey // 密钥
+	CreatedAt time.Time   // 加入时间
+	RetireAt  time.Time   // 退役时间，为零表示不退役，退役后不再用于验签
+}
+
+// retired 是否已退役
+func (e *KeyRingEntry) retired(now time.Time) bool {
+	return !e.RetireAt.IsZero() && !now.Before(e.RetireAt)
+}
+
+// KeyRing 密钥环，新 Token 使用活跃密钥签发，旧密钥在退役前继续用于验签
+type KeyRing struct {
+	mu      sync.RWMutex
+	entries map[string]*KeyRingEntry
+	active  string
+}
+
+// NewKeyRing 创建密钥环，第一把可签名的密钥作为活跃密钥
+func NewKeyRing(keys ...*SigningKey) (*KeyRing, error) {
+	ring := &KeyRing{entries: make(map[string]*KeyRingEntry)}
+	for _, key := range keys {
+		if err := ring.Add(key); err != nil {
+			return nil, err
+		}
+		if ring.active == "" && key.CanSign() {
+			ring.active = key.KeyId
+		}
+	}
+	return ring, nil
+}
+
+// Add 加入密钥但不激活，可用于提前发布新公钥
+func (r *KeyRing) Add(key *SigningKey) error {
+	if key == nil || key.KeyId == "" {
+		return fmt.Errorf("%w: 密钥环中的密钥必须设置 kid", KeyNotFound)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.entries[key.KeyId]; exists {
+		return fmt.Errorf("密钥 %s 已存在", key.KeyId)
+	}
+	r.entries[key.KeyId] = &KeyRingEntry{Key: key, CreatedAt: time.Now()}
+	return nil
+}
+
+// Activate 将指定密钥设为活跃密钥
+func (r *KeyRing) Activate(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[kid]
+	if !ok || entry.retired(time.Now()) {
+		return KeyNotFound
+	}
+	if !entry.Key.CanSign() {
+		return KeyVerifyOnly
+	}
+	entry.RetireAt = time.Time{}
+	r.active = kid
+	return nil
+}
+
+// Rotate 加入并激活新密钥，原活跃密钥在 gracePeriod 后退役
+// gracePeriod 应不小于 Token 的最长有效期，否则尚未过期的 Token 会验签失败
+func (r *KeyRing) Rotate(key *SigningKey, gracePeriod time.Duration) error {
+	if !key.CanSign() {
+		return KeyVerifyOnly
+	}
+	if err := r.Add(key); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if previous, ok := r.entries[r.active]; ok {
+		previous.RetireAt = time.Now().Add(gracePeriod)
+	}
+	r.active = key.KeyId
+	return nil
+}
+
+// Retire 立即退役指定密钥，活跃密钥不能退役
+func (r *KeyRing) Retire(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if kid == r.active {
+		return errors.New("不能退役活跃密钥，请先轮换")
+	}
+	entry, ok := r.entries[kid]
+	if !ok {
+		return KeyNotFound
+	}
+	entry.RetireAt = time.Now()
+	return nil
+}
+
+// Prune 移除已退役的密钥
+func (r *KeyRing) Prune() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for kid, entry := range r.entries {
+		if entry.retired(now) {
+			delete(r.entries, kid)
+		}
+	}
+}
+
+// ActiveKeyId 获取活跃密钥 kid
+func (r *KeyRing) ActiveKeyId() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// Entries 获取密钥环中所有密钥
+func (r *KeyRing) Entries() []KeyRingEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := make([]KeyRingEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// SigningKey 返回活跃密钥
+func (r *KeyRing) SigningKey() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[r.active]
+	if !ok {
+		return nil, KeyNotFound
+	}
+	return entry.Key, nil
+}
+
+// VerificationKey 按 kid 查找未退役的验签密钥，kid 为空时使用活跃密钥
+func (r *KeyRing) VerificationKey(kid, algorithm string) (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if kid == "" {
+		kid = r.active
+	}
+	entry, ok := r.entries[kid]
+	if !ok || entry.retired(time.Now()) {
+		return nil, KeyNotFound
+	}
+	if entry.Key.Method.Alg() != algorithm {
+		return nil, KeyAlgorithmMismatch
+	}
+	return entry.Key, nil
+}
+
+// PublicKeys 返回所有未退役密钥的公钥
+func (r *KeyRing) PublicKeys() []*SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if public := entry.Key.Public(); public != nil && !entry.retired(now) {
+			keys = append(keys, public)
+		}
+	}
+	return keys
+}
+
+// GenerateKey 按算法生成新密钥，kid 为空时按时间生成
+func GenerateKey(kid, algorithm string) (*SigningKey, error) {
+	if kid == "" {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		kid = time.Now().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
+	}
+
+	method := jwt.GetSigningMethod(algorithm)
+	var privateKey interface{}
+	var err error
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		// 共享密钥以十六进制保存，便于写入配置与序列化
+		secret := make([]byte, m.Hash.Size())
+		if _, err = rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return NewHMACKey(kid, algorithm, []byte(hex.EncodeToString(secret)))
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case *jwt.SigningMethodECDSA:
+		curves := map[int]elliptic.Curve{256: elliptic.P256(), 384: elliptic.P384(), 521: elliptic.P521()}
+		privateKey, err = ecdsa.GenerateKey(curves[m.CurveBits], rand.Reader)
+	case *jwt.SigningMethodEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", KeyUnsupported, algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return newAsymmetricKey(kid, algorithm, key, key.Public())
+	case *ecdsa.PrivateKey:
+		return newAsymmetricKey(kid, algorithm, key, key.Public())
+	default:
+		edKey := privateKey.(ed25519.PrivateKey)
+		return newAsymmetricKey(kid, algorithm, edKey, edKey.Public())
+	}
+}
+
+// keyRingDocument 密钥环序列化格式
+type keyRingDocument struct {
+	Active string               `json:"active"`
+	Keys   []keyRingEntryRecord `json:"keys"`
+}
+
+// keyRingEntryRecord 密钥序列化格式
+type keyRingEntryRecord struct {
+	KeyConfig
+	CreatedAt time.Time `json:"createdAt"`
+	RetireAt  time.Time `json:"retireAt"`
+}
+
+// MarshalJSON 序列化密钥环，私钥以 PKCS8 PEM 保存，请妥善保管序列化结果
+func (r *KeyRing) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	document := keyRingDocument{Active: r.active, Keys: make([]keyRingEntryRecord, 0, len(r.entries))}
+	for _, entry := range r.entries {
+		config, err := keyToConfig(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		document.Keys = append(document.Keys, keyRingEntryRecord{KeyConfig: config, CreatedAt: entry.CreatedAt, RetireAt: entry.RetireAt})
+	}
+	return json.Marshal(document)
+}
+
+// UnmarshalJSON 反序列化密钥环，替换当前全部密钥
+func (r *KeyRing) UnmarshalJSON(data []byte) error {
+	var document keyRingDocument
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	entries := make(map[string]*KeyRingEntry, len(document.Keys))
+	for _, record := range document.Keys {
+		key, err := NewKeyFromConfig(record.KeyConfig)
+		if err != nil {
+			return fmt.Errorf("加载密钥 %s 失败: %w", record.KeyId, err)
+		}
+		entries[key.KeyId] = &KeyRingEntry{Key: key, CreatedAt: record.CreatedAt, RetireAt: record.RetireAt}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = entries
+	r.active = document.Active
+	return nil
+}
+
+// keyToConfig 将密钥转换为可序列化的配置
+func keyToConfig(key *SigningKey) (KeyConfig, error) {
+	config := KeyConfig{KeyId: key.KeyId, Algorithm: key.Method.Alg()}
+	if key.IsSymmetric() {
+		config.Secret = string(key.PrivateKey.([]byte))
+		return config, nil
+	}
+	if key.PrivateKey != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+		if err != nil {
+			return config, err
+		}
+		config.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		return config, nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return config, err
+	}
+	config.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return config, nil
+}
+
+// KeyRingStore 密钥环存储，用于多副本共享同一个密钥环
+type KeyRingStore interface {
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+}
+
+// KeyRingLocker 可选的分布式锁，存储实现该接口时轮换前会加锁，避免多副本同时轮换
+type KeyRingLocker interface {
+	Lock(ctx context.Context, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// LoadKeyRing 从存储创建密钥环
+func LoadKeyRing(ctx context.Context, store KeyRingStore) (*KeyRing, error) {
+	ring := &KeyRing{entries: make(map[string]*KeyRingEntry)}
+	if err := ring.LoadFrom(ctx, store); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// LoadFrom 从存储加载密钥环
+func (r *KeyRing) LoadFrom(ctx context.Context, store KeyRingStore) error {
+	data, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	return r.UnmarshalJSON(data)
+}
+
+// SaveTo 保存密钥环到存储
+func (r *KeyRing) SaveTo(ctx context.Context, store KeyRingStore) error {
+	data, err := r.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, data)
+}
+
+// RotationOptions 定时轮换配置
+type RotationOptions struct {
+	Algorithm    string        // 新密钥算法，默认沿用活跃密钥的算法
+	Interval     time.Duration // 活跃密钥使用时长，超过后轮换
+	GracePeriod  time.Duration // 旧密钥保留验签的时长，默认为配置的 ExpiresTime + BufferTime，即 Token 可被刷新的最长时间
+	SyncInterval time.Duration // 从存储同步的间隔，默认 1 分钟
+	Store        KeyRingStore  // 共享存储，为空时只在本地轮换
+}
+
+// RotationGracePeriodInvalid 未设置旧密钥保留时长且配置中没有 Token 有效期
+var RotationGracePeriodInvalid error = errors.New("密钥轮换的 GracePeriod 必须大于 0")
+
+// withDefaults 补全默认值，GracePeriod 为零时旧密钥会立即退役，尚未过期的 Token 全部验签失败
+func (o RotationOptions) withDefaults() (RotationOptions, error) {
+	if o.SyncInterval <= 0 {
+		o.SyncInterval = time.Minute
+	}
+	if o.GracePeriod <= 0 && global.CONFIG != nil {
+		o.GracePeriod = time.Duration(global.CONFIG.JWT.ExpiresTime+global.CONFIG.JWT.BufferTime) * time.Second
+	}
+	if o.GracePeriod <= 0 {
+		return o, RotationGracePeriodInvalid
+	}
+	return o, nil
+}
+
+// StartRotation 启动定时轮换，ctx 取消后停止，GracePeriod 无法确定时返回 RotationGracePeriodInvalid
+// 配置了共享存储时，每次同步先从存储加载，由抢到锁的副本轮换并写回，其余副本在下次同步时获取新密钥
+func (r *KeyRing) StartRotation(ctx context.Context, options RotationOptions) error {
+	options, err := options.withDefaults()
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(options.SyncInterval)
+		defer ticker.Stop()
+		for {
+			if err := r.rotateIfDue(ctx, options); err != nil && global.LOGGER != nil {
+				global.LOGGER.WithError(err).ErrorMsg("JWT 密钥环轮换失败")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// rotateIfDue 同步存储并在活跃密钥到期时轮换
+func (r *KeyRing) rotateIfDue(ctx context.Context, options RotationOptions) error {
+	options, err := options.withDefaults()
+	if err != nil {
+		return err
+	}
+	if options.Store != nil {
+		if err := r.LoadFrom(ctx, options.Store); err != nil && !errors.Is(err, KeyRingNotFound) {
+			return err
+		}
+	}
+	if !r.rotationDue(options.Interval) {
+		return nil
+	}
+
+	if locker, ok := options.Store.(KeyRingLocker); ok {
+		unlock, locked, err := locker.Lock(ctx, options.SyncInterval)
+		if err != nil || !locked {
+			return err
+		}
+		defer unlock()
+		// 加锁后重新加载，其他副本可能已完成轮换
+		if err := r.LoadFrom(ctx, options.Store); err != nil && !errors.Is(err, KeyRingNotFound) {
+			return err
+		}
+		if !r.rotationDue(options.Interval) {
+			return nil
+		}
+	}
+
+	algorithm := options.Algorithm
+	if current, err := r.SigningKey(); algorithm == "" && err == nil {
+		algorithm = current.Method.Alg()
+	}
+	key, err := GenerateKey("", algorithm)
+	if err != nil {
+		return err
+	}
+	if err := r.Rotate(key, options.GracePeriod); err != nil {
+		return err
+	}
+	r.Prune()
+	if options.Store != nil {
+		return r.SaveTo(ctx, options.Store)
+	}
+	return nil
+}
+
+// rotationDue 活跃密钥是否到期
+func (r *KeyRing) rotationDue(interval time.Duration) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[r.active]
+	if !ok {
+		return true
+	}
+	return interval > 0 && time.Since(entry.CreatedAt) >= interval
+}
+
+// KeyRingNotFound 存储中尚无密钥环
+var KeyRingNotFound error = errors.New("存储中未找到密钥环")
+
+// FileKeyRingStore 文件存储
+type FileKeyRingStore struct {
+	Path string
+}
+
+// Load 读取密钥环文件
+func (s *FileKeyRingStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, KeyRingNotFound
+	}
+	return data, err
+}
+
+// Save 写入密钥环文件，先写临时文件再替换，避免读到不完整的内容
+func (s *FileKeyRingStore) Save(ctx context.Context, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// RedisKeyRingStore Redis 存储，使用 global.REDIS
+type RedisKeyRingStore struct {
+	Key string
+}
+
+// NewRedisKeyRingStore 创建 Redis 存储，key 为空时使用默认键
+func NewRedisKeyRingStore(key string) *RedisKeyRingStore {
+	if key == "" {
+		key = global.GPerFix + "jwt_key_ring"
+	}
+	return &RedisKeyRingStore{Key: key}
+}
+
+// Load 读取密钥环
+func (s *RedisKeyRingStore) Load(ctx context.Context) ([]byte, error) {
+	if global.REDIS == nil {
+		return nil, errors.New("Redis 未初始化")
+	}
+	data, err := global.REDIS.Get(ctx, s.Key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, KeyRingNotFound
+	}
+	return data, err
+}
+
+// Save 写入密钥环
+func (s *RedisKeyRingStore) Save(ctx context.Context, data []byte) error {
+	if global.REDIS == nil {
+		return errors.New("Redis 未初始化")
+	}
+	return global.REDIS.Set(ctx, s.Key, data, 0).Err()
+}
+
+// releaseLockScript 锁的值仍为本实例写入的随机值时才删除，持有时间超过 ttl 后不会误删其他实例的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock 使用 SETNX 写入随机值获取轮换锁，释放时比较后删除
+func (s *RedisKeyRingStore) Lock(ctx context.Context, ttl time.Duration) (func(), bool, error) {
+	if global.REDIS == nil {
+		return nil, false, errors.New("Redis 未初始化")
+	}
+	owner, err := randomToken(16)
+	if err != nil {
+		return nil, false, err
+	}
+	lockKey := s.Key + ":lock"
+	ok, err := global.REDIS.SetNX(ctx, lockKey, owner, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return func() { releaseLockScript.Run(context.Background(), global.REDIS, []string{lockKey}, owner) }, true, nil
+}
@@ -0,0 +1,272 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 09:12:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 09:12:36
+ * @FilePath: \go-core\pkg\jwt\keys.go
+ * @Description: 签名密钥，支持 HMAC、RSA、ECDSA、Ed25519，可从 PEM 文件或配置加载
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 密钥相关错误
+var (
+	KeyNotFound          error = errors.New("未找到可用的签名密钥")
+	KeyUnsupported       error = errors.New("不支持的密钥类型或签名算法")
+	KeyVerifyOnly        error = errors.New("当前实例仅持有公钥，无法签发 Token")
+	KeyAlgorithmMismatch error = errors.New("Token 签名算法与密钥不匹配")
+)
+
+// SigningKey 签名密钥
+// HMAC 密钥的 PrivateKey 与 PublicKey 均为共享密钥；非对称密钥在仅验签的实例中 PrivateKey 为空
+type SigningKey struct {
+	KeyId      string            // 密钥标识，写入 Token 头部的 kid
+	Method     jwt.SigningMethod // 签名算法
+	PrivateKey interface{}       // 签名用密钥
+	PublicKey  interface{}       // 验签用密钥
+}
+
+// KeyConfig 密钥配置，可通过 global.VP 从配置文件读取
+// 密钥内容与密钥文件二选一，同时配置时优先使用密钥内容
+type KeyConfig struct {
+	KeyId          string `mapstructure:"key-id"           json:"keyId"          yaml:"key-id"`           // 密钥标识
+	Algorithm      string `mapstructure:"algorithm"        json:"algorithm"      yaml:"algorithm"`        // 签名算法，如 HS256、RS256、ES256、EdDSA，非对称密钥可留空按密钥类型推断
+	Secret         string `mapstructure:"secret"           json:"secret"         yaml:"secret"`           // HMAC 共享密钥
+	PrivateKey     string `mapstructure:"private-key"      json:"privateKey"     yaml:"private-key"`      // PEM 格式私钥
+	PublicKey      string `mapstructure:"public-key"       json:"publicKey"      yaml:"public-key"`       // PEM 格式公钥
+	PrivateKeyFile string `mapstructure:"private-key-file" json:"privateKeyFile" yaml:"private-key-file"` // PEM 私钥文件路径
+	PublicKeyFile  string `mapstructure:"public-key-file"  json:"publicKeyFile"  yaml:"public-key-file"`  // PEM 公钥文件路径
+}
+
+// NewHMACKey 创建 HMAC 密钥，algorithm 为空时使用 HS256
+func NewHMACKey(kid, algorithm string, secret []byte) (*SigningKey, error) {
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+	method, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", KeyUnsupported, algorithm)
+	}
+	if len(secret) == 0 {
+		return nil, KeyNotFound
+	}
+	return &SigningKey{KeyId: kid, Method: method, PrivateKey: secret, PublicKey: secret}, nil
+}
+
+// NewKeyFromPEM 从 PEM 内容创建非对称密钥，内容为私钥时同时推导公钥，为公钥时只能用于验签
+func NewKeyFromPEM(kid, algorithm string, pemBytes []byte) (*SigningKey, error) {
+	privateKey, err := parsePrivateKeyPEM(pemBytes)
+	if err == nil {
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, KeyUnsupported
+		}
+		return newAsymmetricKey(kid, algorithm, privateKey, signer.Public())
+	}
+	publicKey, pubErr := parsePublicKeyPEM(pemBytes)
+	if pubErr != nil {
+		return nil, fmt.Errorf("%w: 无法解析 PEM 密钥", KeyUnsupported)
+	}
+	return newAsymmetricKey(kid, algorithm, nil, publicKey)
+}
+
+// NewKeyFromFile 从 PEM 文件创建非对称密钥
+func NewKeyFromFile(kid, algorithm, path string) (*SigningKey, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyFromPEM(kid, algorithm, pemBytes)
+}
+
+// NewKeyFromConfig 根据配置创建密钥
+func NewKeyFromConfig(config KeyConfig) (*SigningKey, error) {
+	if config.Secret != "" {
+		return NewHMACKey(config.KeyId, config.Algorithm, []byte(config.Secret))
+	}
+
+	privatePEM, err := readPEM(config.PrivateKey, config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(privatePEM) > 0 {
+		return NewKeyFromPEM(config.KeyId, config.Algorithm, privatePEM)
+	}
+
+	publicPEM, err := readPEM(config.PublicKey, config.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicPEM) > 0 {
+		return NewKeyFromPEM(config.KeyId, config.Algorithm, publicPEM)
+	}
+	return nil, KeyNotFound
+}
+
+// readPEM 读取 PEM 内容，优先使用配置内容
+func readPEM(content, path string) ([]byte, error) {
+	if content != "" {
+		return []byte(content), nil
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+// parsePrivateKeyPEM 依次尝试解析 RSA、ECDSA、Ed25519 私钥
+func parsePrivateKeyPEM(pemBytes []byte) (interface{}, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	return jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+}
+
+// parsePublicKeyPEM 依次尝试解析 RSA、ECDSA、Ed25519 公钥
+func parsePublicKeyPEM(pemBytes []byte) (interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(pemBytes); err == nil {
+		return key, nil
+	}
+	return jwt.ParseEdPublicKeyFromPEM(pemBytes)
+}
+
+// newAsymmetricKey 创建非对称密钥并校验算法与密钥类型是否匹配
+func newAsymmetricKey(kid, algorithm string, privateKey, publicKey interface{}) (*SigningKey, error) {
+	if algorithm == "" {
+		algorithm = defaultAlgorithm(publicKey)
+	}
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || !methodMatchesKey(method, publicKey) {
+		return nil, fmt.Errorf("%w: %s", KeyUnsupported, algorithm)
+	}
+	return &SigningKey{KeyId: kid, Method: method, PrivateKey: privateKey, PublicKey: publicKey}, nil
+}
+
+// defaultAlgorithm 根据公钥类型推断签名算法
+func defaultAlgorithm(publicKey interface{}) string {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 384:
+			return jwt.SigningMethodES384.Alg()
+		case 521:
+			return jwt.SigningMethodES512.Alg()
+		default:
+			return jwt.SigningMethodES256.Alg()
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA.Alg()
+	}
+	return ""
+}
+
+// methodMatchesKey 校验签名算法与公钥类型是否匹配
+func methodMatchesKey(method jwt.SigningMethod, publicKey interface{}) bool {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		_, ok := method.(*jwt.SigningMethodECDSA)
+		return ok && method.Alg() == defaultAlgorithm(publicKey)
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+// CanSign 是否可用于签发 Token
+func (k *SigningKey) CanSign() bool {
+	return k != nil && k.PrivateKey != nil
+}
+
+// IsSymmetric 是否为 HMAC 共享密钥
+func (k *SigningKey) IsSymmetric() bool {
+	if k == nil {
+		return false
+	}
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// Public 返回只含公钥的副本，HMAC 密钥无法公开返回 nil
+func (k *SigningKey) Public() *SigningKey {
+	if k == nil || k.IsSymmetric() {
+		return nil
+	}
+	return &SigningKey{KeyId: k.KeyId, Method: k.Method, PublicKey: k.PublicKey}
+}
+
+// KeyProvider 密钥提供者，负责提供签名密钥与按 kid 查找验签密钥
+type KeyProvider interface {
+	// SigningKey 返回当前用于签发 Token 的密钥
+	SigningKey() (*SigningKey, error)
+	// VerificationKey 按 kid 与算法查找验签密钥，kid 为空时返回默认密钥
+	VerificationKey(kid, algorithm string) (*SigningKey, error)
+	// PublicKeys 返回可公开的验签密钥，用于生成 JWKS
+	PublicKeys() []*SigningKey
+}
+
+// StaticKeyProvider 单密钥提供者
+type StaticKeyProvider struct {
+	key *SigningKey
+}
+
+// NewStaticKeyProvider 创建单密钥提供者
+func NewStaticKeyProvider(key *SigningKey) *StaticKeyProvider {
+	return &StaticKeyProvider{key: key}
+}
+
+// SigningKey 返回签名密钥
+func (p *StaticKeyProvider) SigningKey() (*SigningKey, error) {
+	if p.key == nil {
+		return nil, KeyNotFound
+	}
+	if !p.key.CanSign() {
+		return nil, KeyVerifyOnly
+	}
+	return p.key, nil
+}
+
+// VerificationKey 返回验签密钥
+func (p *StaticKeyProvider) VerificationKey(kid, algorithm string) (*SigningKey, error) {
+	if p.key == nil || (kid != "" && p.key.KeyId != "" && kid != p.key.KeyId) {
+		return nil, KeyNotFound
+	}
+	if p.key.Method.Alg() != algorithm {
+		return nil, KeyAlgorithmMismatch
+	}
+	return p.key, nil
+}
+
+// PublicKeys 返回可公开的验签密钥
+func (p *StaticKeyProvider) PublicKeys() []*SigningKey {
+	if public := p.key.Public(); public != nil {
+		return []*SigningKey{public}
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 20:05:33
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 20:05:33
+ * @FilePath: \go-core\pkg\jwt\loginguard.go
+ * @Description: 登录防护，按用户与 IP 在滑动窗口内统计失败次数，超过阈值后要求验证码或临时锁定
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kamalyes/go-core/pkg/captcha"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/kamalyes/go-core/pkg/response"
+	"github.com/redis/go-redis/v9"
+)
+
+// 登录防护相关错误
+var (
+	LoginLocked     error = errors.New("登录失败次数过多，账号已被临时锁定")
+	CaptchaRequired error = errors.New("登录失败次数过多，请输入验证码")
+	CaptchaInvalid  error = errors.New("验证码错误")
+)
+
+// 登录防护默认配置，阈值为负数时关闭对应限制
+const (
+	DefaultLoginWindow         = 15 * time.Minute
+	DefaultLoginLockDuration   = 15 * time.Minute
+	DefaultLoginCaptchaAfter   = 3
+	DefaultLoginLockAfter      = 5
+	DefaultLoginIPCaptchaAfter = 10
+	DefaultLoginIPLockAfter    = 30
+)
+
+var loginGuardPerFixKey string // 为空时使用 global.GPerFix + "login_guard"
+
+func init() {
+	response.RegisterErrorCode(LoginLocked, response.StatusLocked, response.DisableAuth)
+	response.RegisterErrorCode(CaptchaRequired, response.StatusBadRequest, response.ValidateError)
+	response.RegisterErrorCode(CaptchaInvalid, response.StatusBadRequest, response.ValidateError)
+}
+
+// SetLoginGuardPerFixKey 设置登录防护 Redis 键前缀
+func SetLoginGuardPerFixKey(key string) {
+	loginGuardPerFixKey = key
+}
+
+// GetLoginGuardPerFixKey 获取登录防护 Redis 键前缀，未设置时按当前的 global.GPerFix 生成
+func GetLoginGuardPerFixKey() string {
+	if loginGuardPerFixKey != "" {
+		return loginGuardPerFixKey
+	}
+	return global.GPerFix + "login_guard"
+}
+
+// LoginGuardConfig 登录防护配置，零值使用默认值，阈值为负数时关闭
+type LoginGuardConfig struct {
+	Window         time.Duration `mapstructure:"window"           json:"window"           yaml:"window"`           // 统计失败次数的滑动窗口
+	LockDuration   time.Duration `mapstructure:"lock-duration"    json:"lockDuration"     yaml:"lock-duration"`    // 锁定时长
+	CaptchaAfter   int           `mapstructure:"captcha-after"    json:"captchaAfter"     yaml:"captcha-after"`    // 用户失败多少次后要求验证码
+	LockAfter      int           `mapstructure:"lock-after"       json:"lockAfter"        yaml:"lock-after"`       // 用户失败多少次后锁定账号
+	IPCaptchaAfter int           `mapstructure:"ip-captcha-after" json:"ipCaptchaAfter"   yaml:"ip-captcha-after"` // 同一 IP 失败多少次后要求验证码
+	IPLockAfter    int           `mapstructure:"ip-lock-after"    json:"ipLockAfter"      yaml:"ip-lock-after"`    // 同一 IP 失败多少次后锁定 IP
+}
+
+// CaptchaVerifier 校验验证码，captcha.RedisStoreInterface 已实现
+type CaptchaVerifier interface {
+	Verify(id, answer string, clear bool) bool
+}
+
+// LoginAttempt 一次登录尝试
+type LoginAttempt struct {
+	UserName      string // 登录账号，不区分大小写
+	IP            string // 客户端 IP，为空时只按用户统计
+	CaptchaId     string // 验证码id
+	CaptchaAnswer string // 验证码答案
+}
+
+// LoginStatus 登录防护状态
+type LoginStatus struct {
+	UserFailures    int64      `json:"userFailures"`    // 窗口内用户失败次数
+	IPFailures      int64      `json:"ipFailures"`      // 窗口内 IP 失败次数
+	CaptchaRequired bool       `json:"captchaRequired"` // 下次登录是否需要验证码
+	LockedUntil     *time.Time `json:"lockedUntil"`     // 锁定截止时间，未锁定时为空
+}
+
+// LoginLock 锁定记录
+type LoginLock struct {
+	Subject     string    `json:"subject"` // 用户名或 IP
+	IsIP        bool      `json:"isIp"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// LoginGuard 基于 global.REDIS 的登录防护
+//
+//	guard := jwt.NewLoginGuard()
+//	if err := guard.Check(ctx, attempt); err != nil { ... }   // 校验密码前调用
+//	guard.Fail(ctx, attempt) / guard.Succeed(ctx, attempt)    // 按校验结果记录
+//
+// Check 只读取状态，不预占次数：同一账号并发发起的请求在 Fail 记录前都能通过 Check，
+// 因此达到阈值前最多可多尝试并发数次，Fail 中计数与锁定是原子的，锁定后的请求均被拒绝。
+// 需要严格限制时在调用方按账号串行处理登录请求。
+type LoginGuard struct {
+	Config    LoginGuardConfig
+	Captcha   CaptchaVerifier // 校验验证码，默认 captcha.NewDefaultRedisStore()
+	PrefixKey string
+}
+
+// NewLoginGuard 新建登录防护，未传配置时使用默认配置
+func NewLoginGuard(configs ...*LoginGuardConfig) *LoginGuard {
+	config := LoginGuardConfig{}
+	if len(configs) > 0 && configs[0] != nil {
+		config = *configs[0]
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultLoginWindow
+	}
+	if config.LockDuration <= 0 {
+		config.LockDuration = DefaultLoginLockDuration
+	}
+	if config.CaptchaAfter == 0 {
+		config.CaptchaAfter = DefaultLoginCaptchaAfter
+	}
+	if config.LockAfter == 0 {
+		config.LockAfter = DefaultLoginLockAfter
+	}
+	if config.IPCaptchaAfter == 0 {
+		config.IPCaptchaAfter = DefaultLoginIPCaptchaAfter
+	}
+	if config.IPLockAfter == 0 {
+		config.IPLockAfter = DefaultLoginIPLockAfter
+	}
+	return &LoginGuard{Config: config, Captcha: captcha.NewDefaultRedisStore(), PrefixKey: GetLoginGuardPerFixKey()}
+}
+
+// normalizeUserName 用户名不区分大小写，避免变换大小写绕过限制
+func normalizeUserName(userName string) string {
+	return strings.ToLower(strings.TrimSpace(userName))
+}
+
+// failKey 失败记录键
+func (g *LoginGuard) failKey(kind, subject string) string {
+	return g.PrefixKey + ":fail:" + kind + ":" + subject
+}
+
+// lockKey 锁定记录键
+func (g *LoginGuard) lockKey(kind, subject string) string {
+	return g.PrefixKey + ":lock:" + kind + ":" + subject
+}
+
+// reached 是否达到阈值，阈值为负数时关闭
+func reached(count int64, threshold int) bool {
+	return threshold > 0 && count >= int64(threshold)
+}
+
+// Check 校验密码前调用，已锁定时返回 LoginLocked，需要验证码而未通过时返回 CaptchaRequired 或 CaptchaInvalid
+// 并发请求在 Fail 记录前都能通过，见 LoginGuard
+func (g *LoginGuard) Check(ctx context.Context, attempt LoginAttempt) error {
+	status, err := g.Status(ctx, attempt.UserName, attempt.IP)
+	if err != nil {
+		return err
+	}
+	if status.LockedUntil != nil {
+		return fmt.Errorf("%w，请于 %s 后重试", LoginLocked, status.LockedUntil.Format(time.DateTime))
+	}
+	if !status.CaptchaRequired {
+		return nil
+	}
+	if attempt.CaptchaId == "" || attempt.CaptchaAnswer == "" {
+		return CaptchaRequired
+	}
+	if g.Captcha == nil || !g.Captcha.Verify(attempt.CaptchaId, attempt.CaptchaAnswer, true) {
+		return CaptchaInvalid
+	}
+	return nil
+}
+
+// Status 获取用户与 IP 的失败次数与锁定状态
+func (g *LoginGuard) Status(ctx context.Context, userName, ip string) (*LoginStatus, error) {
+	status := &LoginStatus{}
+	userName = normalizeUserName(userName)
+	min := strconv.FormatInt(time.Now().Add(-g.Config.Window).UnixNano(), 10)
+
+	pipe := global.REDIS.Pipeline()
+	var userFailures, ipFailures *redis.IntCmd
+	var userLock, ipLock *redis.StringCmd
+	if userName != "" {
+		userFailures = pipe.ZCount(ctx, g.failKey("user", userName), min, "+inf")
+		userLock = pipe.Get(ctx, g.lockKey("user", userName))
+	}
+	if ip != "" {
+		ipFailures = pipe.ZCount(ctx, g.failKey("ip", ip), min, "+inf")
+		ipLock = pipe.Get(ctx, g.lockKey("ip", ip))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	for _, failures := range []*redis.IntCmd{userFailures, ipFailures} {
+		if failures != nil && failures.Err() != nil {
+			return nil, failures.Err()
+		}
+	}
+	if userFailures != nil {
+		status.UserFailures = userFailures.Val()
+	}
+	if ipFailures != nil {
+		status.IPFailures = ipFailures.Val()
+	}
+	for _, lock := range []*redis.StringCmd{userLock, ipLock} {
+		if lock == nil || lock.Err() != nil {
+			continue
+		}
+		until, err := lock.Int64()
+		if err != nil {
+			continue
+		}
+		lockedUntil := time.Unix(until, 0)
+		if status.LockedUntil == nil || lockedUntil.After(*status.LockedUntil) {
+			status.LockedUntil = &lockedUntil
+		}
+	}
+	status.CaptchaRequired = reached(status.UserFailures, g.Config.CaptchaAfter) ||
+		reached(status.IPFailures, g.Config.IPCaptchaAfter)
+	return status, nil
+}
+
+// Fail 记录一次失败，达到阈值时锁定用户或 IP，返回记录后的状态
+func (g *LoginGuard) Fail(ctx context.Context, attempt LoginAttempt) (*LoginStatus, error) {
+	userName := normalizeUserName(attempt.UserName)
+	if userName != "" {
+		count, err := g.addFailure(ctx, "user", userName)
+		if err != nil {
+			return nil, err
+		}
+		if reached(count, g.Config.LockAfter) {
+			if err := g.lock(ctx, "user", userName, g.Config.LockDuration); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if attempt.IP != "" {
+		count, err := g.addFailure(ctx, "ip", attempt.IP)
+		if err != nil {
+			return nil, err
+		}
+		if reached(count, g.Config.IPLockAfter) {
+			if err := g.lock(ctx, "ip", attempt.IP, g.Config.LockDuration); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g.Status(ctx, userName, attempt.IP)
+}
+
+// Succeed 登录成功后清除用户的失败记录，IP 的失败记录保留到窗口结束
+func (g *LoginGuard) Succeed(ctx context.Context, attempt LoginAttempt) error {
+	userName := normalizeUserName(attempt.UserName)
+	if userName == "" {
+		return nil
+	}
+	return global.REDIS.Del(ctx, g.failKey("user", userName)).Err()
+}
+
+// addFailure 在滑动窗口中记录一次失败并返回窗口内的失败次数
+func (g *LoginGuard) addFailure(ctx context.Context, kind, subject string) (int64, error) {
+	key := g.failKey(kind, subject)
+	now := time.Now()
+	member, err := randomToken(8)
+	if err != nil {
+		return 0, err
+	}
+	pipe := global.REDIS.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-g.Config.Window).UnixNano(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, g.Config.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// lock 锁定用户或 IP，同时清空失败记录，解锁后重新计数
+func (g *LoginGuard) lock(ctx context.Context, kind, subject string, duration time.Duration) error {
+	until := time.Now().Add(duration)
+	pipe := global.REDIS.TxPipeline()
+	pipe.Set(ctx, g.lockKey(kind, subject), until.Unix(), duration)
+	pipe.Del(ctx, g.failKey(kind, subject))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// unlock 解除锁定并清空失败记录
+func (g *LoginGuard) unlock(ctx context.Context, kind, subject string) error {
+	return global.REDIS.Del(ctx, g.lockKey(kind, subject), g.failKey(kind, subject)).Err()
+}
+
+// Lock 管理员锁定用户，duration 为 0 时使用配置的锁定时长
+func (g *LoginGuard) Lock(ctx context.Context, userName string, duration time.Duration) error {
+	if duration <= 0 {
+		duration = g.Config.LockDuration
+	}
+	return g.lock(ctx, "user", normalizeUserName(userName), duration)
+}
+
+// Unlock 管理员解锁用户
+func (g *LoginGuard) Unlock(ctx context.Context, userName string) error {
+	return g.unlock(ctx, "user", normalizeUserName(userName))
+}
+
+// LockIP 管理员锁定 IP，duration 为 0 时使用配置的锁定时长
+func (g *LoginGuard) LockIP(ctx context.Context, ip string, duration time.Duration) error {
+	if duration <= 0 {
+		duration = g.Config.LockDuration
+	}
+	return g.lock(ctx, "ip", ip, duration)
+}
+
+// UnlockIP 管理员解锁 IP
+func (g *LoginGuard) UnlockIP(ctx context.Context, ip string) error {
+	return g.unlock(ctx, "ip", ip)
+}
+
+// ListLocks 列出当前全部锁定的用户与 IP
+func (g *LoginGuard) ListLocks(ctx context.Context) ([]*LoginLock, error) {
+	prefix := g.PrefixKey + ":lock:"
+	var locks []*LoginLock
+	iter := global.REDIS.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		until, err := global.REDIS.Get(ctx, key).Int64()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		kind, subject, _ := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		locks = append(locks, &LoginLock{Subject: subject, IsIP: kind == "ip", LockedUntil: time.Unix(until, 0)})
+	}
+	return locks, iter.Err()
+}
@@ -0,0 +1,129 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 11:36:52
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 11:36:52
+ * @FilePath: \go-core\pkg\jwt\loginguard_test.go
+ * @Description: 登录防护的验证码与锁定阈值测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCaptcha 答案为 ok 时通过
+type fakeCaptcha struct{}
+
+func (fakeCaptcha) Verify(id, answer string, clear bool) bool {
+	return answer == "ok"
+}
+
+// newTestLoginGuard 使用 miniredis 的登录防护
+func newTestLoginGuard(t *testing.T, config LoginGuardConfig) *LoginGuard {
+	setupTestRedis(t)
+	guard := NewLoginGuard(&config)
+	guard.Captcha = fakeCaptcha{}
+	return guard
+}
+
+// TestLoginGuardUserThresholds 测试用户失败次数达到阈值后要求验证码与锁定
+func TestLoginGuardUserThresholds(t *testing.T) {
+	guard := newTestLoginGuard(t, LoginGuardConfig{CaptchaAfter: 2, LockAfter: 3, IPCaptchaAfter: -1, IPLockAfter: -1})
+	ctx := context.Background()
+	attempt := LoginAttempt{UserName: "Alice", IP: "10.0.0.1"}
+
+	require.NoError(t, guard.Check(ctx, attempt))
+	status, err := guard.Fail(ctx, attempt)
+	require.NoError(t, err)
+	assert.False(t, status.CaptchaRequired)
+
+	// 用户名不区分大小写
+	status, err = guard.Fail(ctx, LoginAttempt{UserName: " alice ", IP: "10.0.0.2"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.UserFailures)
+	assert.True(t, status.CaptchaRequired)
+
+	assert.ErrorIs(t, guard.Check(ctx, attempt), CaptchaRequired)
+	assert.ErrorIs(t, guard.Check(ctx, LoginAttempt{UserName: "alice", CaptchaId: "id", CaptchaAnswer: "bad"}), CaptchaInvalid)
+	assert.NoError(t, guard.Check(ctx, LoginAttempt{UserName: "alice", CaptchaId: "id", CaptchaAnswer: "ok"}))
+
+	status, err = guard.Fail(ctx, attempt)
+	require.NoError(t, err)
+	require.NotNil(t, status.LockedUntil)
+	assert.ErrorIs(t, guard.Check(ctx, LoginAttempt{UserName: "alice", CaptchaId: "id", CaptchaAnswer: "ok"}), LoginLocked)
+
+	locks, err := guard.ListLocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	assert.Equal(t, "alice", locks[0].Subject)
+	assert.False(t, locks[0].IsIP)
+
+	// 解锁后重新计数
+	require.NoError(t, guard.Unlock(ctx, "ALICE"))
+	assert.NoError(t, guard.Check(ctx, attempt))
+}
+
+// TestLoginGuardIPThresholds 测试同一 IP 对不同账号的失败按 IP 阈值锁定
+func TestLoginGuardIPThresholds(t *testing.T) {
+	guard := newTestLoginGuard(t, LoginGuardConfig{CaptchaAfter: -1, LockAfter: -1, IPCaptchaAfter: 2, IPLockAfter: 3})
+	ctx := context.Background()
+
+	for i, userName := range []string{"u1", "u2"} {
+		status, err := guard.Fail(ctx, LoginAttempt{UserName: userName, IP: "10.0.0.9"})
+		require.NoError(t, err)
+		assert.Equal(t, i == 1, status.CaptchaRequired)
+	}
+	assert.ErrorIs(t, guard.Check(ctx, LoginAttempt{UserName: "u3", IP: "10.0.0.9"}), CaptchaRequired)
+	assert.NoError(t, guard.Check(ctx, LoginAttempt{UserName: "u3", IP: "10.0.0.10"}))
+
+	_, err := guard.Fail(ctx, LoginAttempt{UserName: "u3", IP: "10.0.0.9"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, guard.Check(ctx, LoginAttempt{UserName: "u4", IP: "10.0.0.9"}), LoginLocked)
+
+	require.NoError(t, guard.UnlockIP(ctx, "10.0.0.9"))
+	assert.NoError(t, guard.Check(ctx, LoginAttempt{UserName: "u4", IP: "10.0.0.9"}))
+}
+
+// TestLoginGuardWindow 测试失败记录在滑动窗口外不再计数，成功后清除用户失败记录
+func TestLoginGuardWindow(t *testing.T) {
+	guard := newTestLoginGuard(t, LoginGuardConfig{Window: time.Minute, CaptchaAfter: 2, LockAfter: -1, IPCaptchaAfter: -1, IPLockAfter: -1})
+	ctx := context.Background()
+	attempt := LoginAttempt{UserName: "bob"}
+
+	_, err := guard.Fail(ctx, attempt)
+	require.NoError(t, err)
+	guard.Config.Window = time.Nanosecond // 等同于窗口已过去
+	status, err := guard.Status(ctx, "bob", "")
+	require.NoError(t, err)
+	assert.Zero(t, status.UserFailures)
+
+	guard.Config.Window = time.Minute
+	_, err = guard.Fail(ctx, attempt)
+	require.NoError(t, err)
+	require.NoError(t, guard.Succeed(ctx, attempt))
+	status, err = guard.Status(ctx, "bob", "")
+	require.NoError(t, err)
+	assert.Zero(t, status.UserFailures)
+}
+
+// TestLoginGuardPrefix 测试键前缀在创建时按当前的 global.GPerFix 生成
+func TestLoginGuardPrefix(t *testing.T) {
+	original := global.GPerFix
+	t.Cleanup(func() { global.GPerFix = original })
+
+	global.GPerFix = "app_"
+	assert.Equal(t, "app_login_guard", NewLoginGuard().PrefixKey)
+
+	SetLoginGuardPerFixKey("custom")
+	t.Cleanup(func() { SetLoginGuardPerFixKey("") })
+	assert.Equal(t, "custom", NewLoginGuard().PrefixKey)
+}
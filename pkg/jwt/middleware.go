@@ -0,0 +1,292 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:55:08
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:55:08
+ * @FilePath: \go-core\pkg\jwt\middleware.go
+ * @Description: JWT 中间件公共逻辑，各框架的中间件见 ginx.go、echox.go、fiberx.go、httpx.go
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/kamalyes/go-core/pkg/response"
+)
+
+// TokenMissing 请求未携带 Token
+var TokenMissing error = errors.New("请求未携带 Token，请先登录")
+
+// 中间件默认配置
+const (
+	DefaultHeaderName          = "Authorization"  // 默认读取 Token 的请求头
+	DefaultRefreshHeaderName   = "New-Token"      // 默认返回新 Token 的响应头
+	DefaultRefreshExpireHeader = "New-Expires-At" // 默认返回新 Token 过期时间（Unix 秒）的响应头
+	DefaultClaimsKey           = "claims"         // 上下文中保存 claims 的键，与 GetClaims 一致
+	bearerPrefix               = "Bearer "
+)
+
+func init() {
+	// 未登录与认证失败分别使用 WithoutLogin 与 Unauthorized 业务码
+	response.RegisterErrorCode(TokenMissing, response.StatusUnauthorized, response.WithoutLogin)
+	for _, err := range []error{
+		TokenExpired, TokenNotValidYet, TokenMalformed, TokenInvalid, TokenRevoked, TokenKicked,
+		TokenIssuerInvalid, TokenAudienceInvalid, TokenClaimMissing, TokenTooOld,
+		TokenEncryptionRequired, EncryptionKeyNotFound, KeyNotFound, KeyAlgorithmMismatch,
+		APIKeyInvalid, APIKeyExpired, APIKeyRevoked,
+	} {
+		response.RegisterErrorCode(err, response.StatusUnauthorized, response.Unauthorized)
+	}
+	response.RegisterErrorCode(ClientCredentialsInvalid, response.StatusUnauthorized, response.Unauthorized)
+	response.RegisterErrorCode(ClientDisabled, response.StatusUnauthorized, response.DisableAuth)
+	response.RegisterErrorCode(ScopeNotAllowed, response.StatusForbidden, response.AuthError)
+}
+
+// ClaimsResolver 中间件解析与续签 Token 使用的接口，*JWT 与 *TypedJWT 均已实现
+type ClaimsResolver interface {
+	ResolveClaims(tokenString string) (Claims, error)
+	RenewClaims(claims Claims) (string, error)
+}
+
+// MiddlewareConfig 中间件配置
+type MiddlewareConfig struct {
+	JWT                 ClaimsResolver // 解析 Token 使用的实例，默认 NewJWT()，自定义 claims 使用 NewTypedJWT
+	HeaderName          string         // 读取 Token 的请求头，默认 Authorization，支持 Bearer 前缀
+	CookieName          string         // 读取 Token 的 Cookie 名称，为空时不读取
+	QueryName           string         // 读取 Token 的查询参数名称，为空时不读取
+	RefreshHeaderName   string         // 返回新 Token 的响应头，默认 New-Token
+	RefreshExpireHeader string         // 返回新 Token 过期时间的响应头，默认 New-Expires-At
+	DisableAutoRefresh  bool           // 关闭在 BufferTime 内自动刷新
+	ClaimsKey           string         // 上下文中保存 claims 的键，默认 claims
+	APIKeyHeaderName    string         // 读取 API Key 的请求头，如 X-API-Key，为空时不接受 API Key
+	AllowServiceTokens  bool           // 是否接受客户端凭证签发的服务 Token
+	ServiceJWT          *JWT           // 校验 API Key 与服务 Token 使用的实例，默认 NewJWT()
+}
+
+// tokenSource 从请求中读取 Token 的来源，由各框架适配
+type tokenSource interface {
+	header(name string) string
+	cookie(name string) string
+	query(name string) string
+}
+
+// middleware 中间件公共实现
+type middleware struct {
+	config MiddlewareConfig
+}
+
+// newMiddleware 合并默认配置
+func newMiddleware(configs ...*MiddlewareConfig) *middleware {
+	config := MiddlewareConfig{}
+	if len(configs) > 0 && configs[0] != nil {
+		config = *configs[0]
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = DefaultHeaderName
+	}
+	if config.RefreshHeaderName == "" {
+		config.RefreshHeaderName = DefaultRefreshHeaderName
+	}
+	if config.RefreshExpireHeader == "" {
+		config.RefreshExpireHeader = DefaultRefreshExpireHeader
+	}
+	if config.ClaimsKey == "" {
+		config.ClaimsKey = DefaultClaimsKey
+	}
+	return &middleware{config: config}
+}
+
+// jwt 获取解析 Token 使用的实例，未配置时每次创建以读取最新的密钥设置
+func (m *middleware) jwt() ClaimsResolver {
+	if m.config.JWT != nil {
+		return m.config.JWT
+	}
+	return NewJWT()
+}
+
+// serviceJWT 获取校验机器身份使用的实例
+func (m *middleware) serviceJWT() *JWT {
+	if m.config.ServiceJWT != nil {
+		return m.config.ServiceJWT
+	}
+	return NewJWT()
+}
+
+// extractToken 依次从请求头、Cookie、查询参数中读取 Token
+func (m *middleware) extractToken(source tokenSource) string {
+	if token := strings.TrimSpace(source.header(m.config.HeaderName)); token != "" {
+		if len(token) > len(bearerPrefix) && strings.EqualFold(token[:len(bearerPrefix)], bearerPrefix) {
+			token = strings.TrimSpace(token[len(bearerPrefix):])
+		}
+		return token
+	}
+	if m.config.CookieName != "" {
+		if token := source.cookie(m.config.CookieName); token != "" {
+			return token
+		}
+	}
+	if m.config.QueryName != "" {
+		return source.query(m.config.QueryName)
+	}
+	return ""
+}
+
+// refreshedToken 刷新后的 Token
+type refreshedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// authentication 认证结果
+type authentication struct {
+	claims    Claims
+	token     string // 请求携带的 Token
+	refreshed *refreshedToken
+}
+
+// context 返回写入当前用户的 context，自动刷新后转发新 Token
+func (a *authentication) context(ctx context.Context) context.Context {
+	if a.refreshed != nil {
+		return withPrincipal(ctx, a.claims, a.refreshed.token)
+	}
+	return withPrincipal(ctx, a.claims, a.token)
+}
+
+// headers 返回需要写入响应的头部
+func (m *middleware) headers(refreshed *refreshedToken) map[string]string {
+	if refreshed == nil {
+		return nil
+	}
+	return map[string]string{
+		m.config.RefreshHeaderName:      refreshed.token,
+		m.config.RefreshExpireHeader:    strconv.FormatInt(refreshed.expiresAt.Unix(), 10),
+		"Access-Control-Expose-Headers": m.config.RefreshHeaderName + ", " + m.config.RefreshExpireHeader,
+	}
+}
+
+// authenticate 读取并解析 Token，剩余有效期小于 BufferTime 时签发新 Token
+// 配置了 APIKeyHeaderName 或 AllowServiceTokens 时同时接受机器身份，机器身份不自动刷新
+func (m *middleware) authenticate(source tokenSource) (*authentication, error) {
+	if m.config.APIKeyHeaderName != "" {
+		if key := strings.TrimSpace(source.header(m.config.APIKeyHeaderName)); key != "" {
+			claims, err := m.serviceJWT().VerifyAPIKey(context.Background(), key)
+			if err != nil {
+				return nil, err
+			}
+			// API Key 不向下游转发
+			return &authentication{claims: claims}, nil
+		}
+	}
+	token := m.extractToken(source)
+	if token == "" {
+		return nil, TokenMissing
+	}
+	if m.config.AllowServiceTokens {
+		if service := m.serviceJWT(); service.isServiceToken(token) {
+			claims, err := service.ResolveServiceToken(token)
+			if err != nil {
+				return nil, err
+			}
+			return &authentication{claims: claims, token: token}, nil
+		}
+	}
+	j := m.jwt()
+	claims, err := j.ResolveClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	auth := &authentication{claims: claims, token: token}
+	if m.config.DisableAutoRefresh || !needsRenewal(claims) {
+		return auth, nil
+	}
+
+	newToken, err := j.RenewClaims(claims)
+	if err != nil {
+		// 刷新失败不影响本次请求，达到 MaxAge 上限属于正常情况
+		if global.LOGGER != nil && !errors.Is(err, TokenTooOld) {
+			global.LOGGER.WithError(err).ErrorMsg("自动刷新 Token 失败")
+		}
+		return auth, nil
+	}
+	auth.refreshed = &refreshedToken{token: newToken, expiresAt: claims.GetRegisteredClaims().ExpiresAt.Time}
+	return auth, nil
+}
+
+// needsRenewal 剩余有效期是否小于 BufferTime
+func needsRenewal(claims Claims) bool {
+	expiresAt := claims.GetRegisteredClaims().ExpiresAt
+	if expiresAt == nil {
+		return false
+	}
+	bufferTime := claimsBufferTime(claims)
+	return bufferTime > 0 && time.Until(expiresAt.Time) < time.Duration(bufferTime)*time.Second
+}
+
+// RenewToken 延长 Token 有效期并重新签发，新 Token 使用新的 tokenId，claims 的 tokenId 与过期时间会被更新
+// 已吊销的 Token 不能续签，开启多点登录拦截时会话随之迁移到新的 tokenId
+func (j *JWT) RenewToken(claims *CustomClaims) (string, error) {
+	return j.renewToken(claims)
+}
+
+// RenewClaims 实现 ClaimsResolver
+func (j *JWT) RenewClaims(claims Claims) (string, error) {
+	return j.renewToken(claims)
+}
+
+// renewToken 延长 Token 有效期并更换 tokenId，开启多点登录拦截时为新 tokenId 登记会话
+// 签发时间 iat 保持不变，校验策略的 MaxAge 按首次签发时间计算，续签不会超过该期限
+// 原会话保留到原 Token 过期，避免同时发出的其他请求因会话被移除而失败
+func (j *JWT) renewToken(claims Claims) (string, error) {
+	if err := validateClaims(claims); err != nil {
+		return "", err
+	}
+	if err := checkRevoked(claims); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	registered := claims.GetRegisteredClaims()
+	if registered.IssuedAt == nil {
+		registered.IssuedAt = jwt.NewNumericDate(now)
+	}
+	expiresAt, err := j.validationPolicy().renewedExpiresAt(registered, now, time.Duration(global.CONFIG.JWT.ExpiresTime)*time.Second)
+	if err != nil {
+		return "", err
+	}
+	if registered.ExpiresAt != nil && !expiresAt.After(registered.ExpiresAt.Time) {
+		// 已达到 MaxAge 上限，续签无法延长有效期
+		return "", TokenTooOld
+	}
+	previousTokenId := claims.GetTokenId()
+	if err := reissueTokenId(claims); err != nil {
+		return "", err
+	}
+	registered.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	if j.isMultipointAuthEnabled() && !isServiceClaims(claims) {
+		store := j.sessionStore()
+		sessions, err := store.List(context.Background(), claims.GetUserId())
+		if err != nil {
+			return "", err
+		}
+		for _, session := range sessions {
+			if session.TokenId == previousTokenId {
+				renewed := *session
+				renewed.TokenId = claims.GetTokenId()
+				renewed.ExpiresAt = registered.ExpiresAt.Time
+				if err := store.Save(context.Background(), &renewed); err != nil {
+					return "", err
+				}
+				break
+			}
+		}
+	}
+	return j.signToken(claims)
+}
@@ -23,6 +23,12 @@ func AutoCreateTables() {
 	if global.DB != nil {
 		err := global.DB.AutoMigrate(
 			CustomClaims{},
+			RefreshTokenRecord{},
+			RevokedToken{},
+			UserTokenRevocation{},
+			Session{},
+			APIKey{},
+			ServiceClient{},
 		)
 		if err != nil {
 			errMsgs := fmt.Sprintf("自动创建%s表失败", string(CustomClaims{}.TableName()))
@@ -75,6 +81,6 @@ type CustomClaims struct {
 	/** 有效时间 */
 	BufferTime int64 `json:"bufferTime"`
 
-	/** 系统标准Claims */
-	jwt.RegisteredClaims `json:"-"        gorm:"-"`
+	/** 系统标准Claims，exp、iat、jti 等需要写入 Token */
+	jwt.RegisteredClaims `gorm:"-"`
 }
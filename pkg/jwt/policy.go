@@ -0,0 +1,194 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 16:10:24
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 16:10:24
+ * @FilePath: \go-core\pkg\jwt\policy.go
+ * @Description: Token 校验策略：签发者、受众、必填 claims、时钟偏差与最长有效期
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 校验策略相关错误
+var (
+	TokenIssuerInvalid   error = errors.New("Token 签发者不受信任")
+	TokenAudienceInvalid error = errors.New("Token 受众不匹配")
+	TokenClaimMissing    error = errors.New("Token 缺少必填 claims")
+	TokenTooOld          error = errors.New("Token 签发时间过早")
+)
+
+// ValidationPolicy Token 校验策略，可通过 global.VP.UnmarshalKey 从配置加载
+type ValidationPolicy struct {
+	Issuers        []string      `mapstructure:"issuers"         json:"issuers"        yaml:"issuers"`         // 受信任的签发者，为空时不校验；签发时 iss 为空则写入第一个
+	Audiences      []string      `mapstructure:"audiences"       json:"audiences"      yaml:"audiences"`       // 可接受的受众，Token 的 aud 命中任意一个即可，为空时不校验；签发时 aud 为空则全部写入
+	RequiredClaims []string      `mapstructure:"required-claims" json:"requiredClaims" yaml:"required-claims"` // 必须存在且不为空的 claims，如 sub、jti、tenantId
+	Leeway         time.Duration `mapstructure:"leeway"          json:"leeway"         yaml:"leeway"`          // 校验 exp、nbf、iat 时允许的时钟偏差
+	MaxAge         time.Duration `mapstructure:"max-age"         json:"maxAge"         yaml:"max-age"`         // 距签发时间的最长有效期，为 0 时不限制，设置后 iat 必填
+}
+
+var validationPolicy = struct {
+	sync.RWMutex
+	policy *ValidationPolicy
+}{}
+
+// SetValidationPolicy 设置默认校验策略，NewJWT 创建的实例未设置 Policy 时使用
+func SetValidationPolicy(policy *ValidationPolicy) {
+	validationPolicy.Lock()
+	defer validationPolicy.Unlock()
+	validationPolicy.policy = policy
+}
+
+// GetValidationPolicy 获取默认校验策略
+func GetValidationPolicy() *ValidationPolicy {
+	validationPolicy.RLock()
+	defer validationPolicy.RUnlock()
+	return validationPolicy.policy
+}
+
+// validationPolicy 获取实例的校验策略，未设置时使用默认策略
+func (j *JWT) validationPolicy() *ValidationPolicy {
+	if j.Policy != nil {
+		return j.Policy
+	}
+	if policy := GetValidationPolicy(); policy != nil {
+		return policy
+	}
+	return &ValidationPolicy{}
+}
+
+// verifyTime 按时钟偏差校验 exp、nbf、iat 与最长有效期
+func (p *ValidationPolicy) verifyTime(registered *jwt.RegisteredClaims, now time.Time) error {
+	if registered.ExpiresAt != nil && !now.Before(registered.ExpiresAt.Add(p.Leeway)) {
+		return TokenExpired
+	}
+	if registered.NotBefore != nil && now.Add(p.Leeway).Before(registered.NotBefore.Time) {
+		return TokenNotValidYet
+	}
+	if registered.IssuedAt != nil && now.Add(p.Leeway).Before(registered.IssuedAt.Time) {
+		return TokenNotValidYet
+	}
+	if p.MaxAge > 0 {
+		if registered.IssuedAt == nil {
+			return fmt.Errorf("%w: iat", TokenClaimMissing)
+		}
+		if now.After(registered.IssuedAt.Add(p.MaxAge + p.Leeway)) {
+			return TokenTooOld
+		}
+	}
+	return nil
+}
+
+// renewedExpiresAt 刷新与续签后的过期时间，保留原签发时间，设置了 MaxAge 时不超过签发时间加 MaxAge
+func (p *ValidationPolicy) renewedExpiresAt(registered *jwt.RegisteredClaims, now time.Time, lifetime time.Duration) (time.Time, error) {
+	expiresAt := now.Add(lifetime)
+	if p.MaxAge <= 0 {
+		return expiresAt, nil
+	}
+	if registered.IssuedAt == nil {
+		return time.Time{}, fmt.Errorf("%w: iat", TokenClaimMissing)
+	}
+	if deadline := registered.IssuedAt.Add(p.MaxAge); deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+	if !expiresAt.After(now) {
+		return time.Time{}, TokenTooOld
+	}
+	return expiresAt, nil
+}
+
+// verifyIdentity 校验签发者、受众与必填 claims
+func (p *ValidationPolicy) verifyIdentity(token *jwt.Token, registered *jwt.RegisteredClaims) error {
+	if len(p.Issuers) > 0 && !containsString(p.Issuers, registered.Issuer) {
+		return TokenIssuerInvalid
+	}
+	if len(p.Audiences) > 0 && !containsAny(p.Audiences, registered.Audience) {
+		return TokenAudienceInvalid
+	}
+	if len(p.RequiredClaims) == 0 {
+		return nil
+	}
+	payload, err := tokenPayload(token)
+	if err != nil {
+		return err
+	}
+	for _, name := range p.RequiredClaims {
+		if isEmptyClaim(payload[name]) {
+			return fmt.Errorf("%w: %s", TokenClaimMissing, name)
+		}
+	}
+	return nil
+}
+
+// applyDefaults 签发时补全 iss 与 aud，使 Token 能通过本实例的校验
+func (p *ValidationPolicy) applyDefaults(registered *jwt.RegisteredClaims) {
+	if registered.Issuer == "" && len(p.Issuers) > 0 {
+		registered.Issuer = p.Issuers[0]
+	}
+	if len(registered.Audience) == 0 && len(p.Audiences) > 0 {
+		registered.Audience = append(jwt.ClaimStrings{}, p.Audiences...)
+	}
+}
+
+// tokenPayload 解码 Token 的载荷，用于按名称检查 claims
+func tokenPayload(token *jwt.Token) (map[string]interface{}, error) {
+	parts := strings.Split(token.Raw, ".")
+	if len(parts) != 3 {
+		return nil, TokenMalformed
+	}
+	data, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return nil, TokenMalformed
+	}
+	payload := map[string]interface{}{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, TokenMalformed
+	}
+	return payload, nil
+}
+
+// isEmptyClaim claims 不存在或为空值
+func isEmptyClaim(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// containsString values 中是否包含 target
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny expected 与 actual 是否有交集
+func containsAny(expected []string, actual []string) bool {
+	for _, value := range actual {
+		if containsString(expected, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,506 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 11:28:14
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 11:28:14
+ * @FilePath: \go-core\pkg\jwt\refresh.go
+ * @Description: 访问令牌与刷新令牌，刷新令牌每次使用后轮换，重复使用时吊销整个令牌族
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 刷新令牌相关错误
+var (
+	RefreshTokenInvalid error = errors.New("刷新令牌无效")
+	RefreshTokenExpired error = errors.New("刷新令牌已过期")
+	RefreshTokenReused  error = errors.New("刷新令牌被重复使用，已吊销该登录下的全部令牌")
+	RefreshTokenRevoked error = errors.New("刷新令牌已被吊销")
+)
+
+var (
+	refreshExpiresTime = 7 * 24 * time.Hour  // 刷新令牌默认有效期
+	refreshMaxLifetime = 30 * 24 * time.Hour // 令牌族默认最长有效期，从登录时算起
+	refreshPerFixKey   = global.GPerFix + "jwt_refresh"
+)
+
+// SetRefreshExpiresTime 设置刷新令牌有效期
+func SetRefreshExpiresTime(duration time.Duration) {
+	refreshExpiresTime = duration
+}
+
+// GetRefreshExpiresTime 获取刷新令牌有效期
+func GetRefreshExpiresTime() time.Duration {
+	return refreshExpiresTime
+}
+
+// SetRefreshMaxLifetime 设置令牌族从登录时算起的最长有效期，到期后必须重新登录，小于等于 0 表示不限制
+func SetRefreshMaxLifetime(duration time.Duration) {
+	refreshMaxLifetime = duration
+}
+
+// GetRefreshMaxLifetime 获取令牌族最长有效期
+func GetRefreshMaxLifetime() time.Duration {
+	return refreshMaxLifetime
+}
+
+// SetRefreshPerFixKey 设置刷新令牌 Redis 键前缀
+func SetRefreshPerFixKey(key string) {
+	refreshPerFixKey = key
+}
+
+// GetRefreshPerFixKey 获取刷新令牌 Redis 键前缀
+func GetRefreshPerFixKey() string {
+	return refreshPerFixKey
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	AccessExpiresAt  time.Time `json:"accessExpiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// RefreshTokenRecord 刷新令牌记录，只保存令牌的哈希值
+type RefreshTokenRecord struct {
+
+	/** 刷新令牌 SHA-256 哈希 */
+	TokenHash string `json:"tokenHash"  gorm:"column:token_hash;primary_key;comment:刷新令牌哈希;type:varchar(64);"`
+
+	/** 令牌族id，同一次登录轮换出的刷新令牌属于同一族 */
+	FamilyId string `json:"familyId"   gorm:"column:family_id;index;comment:令牌族id;type:varchar(36);"`
+
+	/** 用户账号id */
+	UserId string `json:"userId"     gorm:"column:user_id;index;comment:用户账号id;type:varchar(36);"`
+
+	/** 签发访问令牌使用的 claims */
+	Claims string `json:"claims"     gorm:"column:claims;comment:claims;type:text;"`
+
+	/** 同时签发的访问令牌 tokenId，轮换时据此迁移多端会话，吊销令牌族时一并吊销 */
+	AccessTokenId string `json:"accessTokenId"  gorm:"column:access_token_id;comment:访问令牌tokenId;type:varchar(36);"`
+
+	/** 访问令牌过期时间 */
+	AccessExpiresAt time.Time `json:"accessExpiresAt"  gorm:"column:access_expires_at;comment:访问令牌过期时间;"`
+
+	/** 令牌族创建时间，即登录时间，令牌族的有效期不超过此时间加 GetRefreshMaxLifetime */
+	FamilyCreatedAt time.Time `json:"familyCreatedAt"  gorm:"column:family_created_at;comment:令牌族创建时间;"`
+
+	/** 过期时间 */
+	ExpiresAt time.Time `json:"expiresAt"  gorm:"column:expires_at;index;comment:过期时间;"`
+
+	/** 使用时间，使用后即失效 */
+	UsedAt *time.Time `json:"usedAt"     gorm:"column:used_at;comment:使用时间;"`
+
+	/** 是否已吊销 */
+	Revoked bool `json:"revoked"    gorm:"column:revoked;comment:是否已吊销;"`
+
+	/** 创建时间 */
+	CreatedAt time.Time `json:"createdAt"  gorm:"column:created_at;comment:创建时间;"`
+}
+
+// TableName 自定义表名
+func (RefreshTokenRecord) TableName() string {
+	return global.GPerFix + "jwt_refresh_tokens"
+}
+
+// RefreshTokenStore 刷新令牌存储
+type RefreshTokenStore interface {
+	// Save 保存刷新令牌
+	Save(ctx context.Context, record *RefreshTokenRecord) error
+	// Get 按哈希获取刷新令牌，不存在时返回 RefreshTokenInvalid
+	Get(ctx context.Context, tokenHash string) (*RefreshTokenRecord, error)
+	// MarkUsed 原子地标记为已使用，已被使用过时返回 false
+	MarkUsed(ctx context.Context, record *RefreshTokenRecord) (bool, error)
+	// RevokeFamily 吊销整个令牌族
+	RevokeFamily(ctx context.Context, familyId string, until time.Time) error
+	// ListFamily 列出令牌族中未过期的刷新令牌，用于吊销其签发的访问令牌
+	ListFamily(ctx context.Context, familyId string) ([]*RefreshTokenRecord, error)
+	// FamilyRevoked 令牌族是否已吊销
+	FamilyRevoked(ctx context.Context, familyId string) (bool, error)
+}
+
+// NewDefaultRefreshTokenStore 有 Redis 时使用 Redis，否则使用数据库
+func NewDefaultRefreshTokenStore() RefreshTokenStore {
+	if global.REDIS != nil {
+		return &RedisRefreshTokenStore{PrefixKey: GetRefreshPerFixKey()}
+	}
+	return &DBRefreshTokenStore{}
+}
+
+// RedisRefreshTokenStore 基于 global.REDIS 的刷新令牌存储
+type RedisRefreshTokenStore struct {
+	PrefixKey string
+}
+
+// tokenKey 刷新令牌键
+func (s *RedisRefreshTokenStore) tokenKey(tokenHash string) string {
+	return s.PrefixKey + ":token:" + tokenHash
+}
+
+// usedKey 刷新令牌使用标记键
+func (s *RedisRefreshTokenStore) usedKey(tokenHash string) string {
+	return s.PrefixKey + ":used:" + tokenHash
+}
+
+// familyKey 令牌族吊销标记键
+func (s *RedisRefreshTokenStore) familyKey(familyId string) string {
+	return s.PrefixKey + ":revoked:" + familyId
+}
+
+// membersKey 令牌族成员索引键，集合中为刷新令牌哈希
+func (s *RedisRefreshTokenStore) membersKey(familyId string) string {
+	return s.PrefixKey + ":family:" + familyId
+}
+
+// Save 保存刷新令牌并加入令牌族索引，过期时间与令牌一致
+func (s *RedisRefreshTokenStore) Save(ctx context.Context, record *RefreshTokenRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(record.ExpiresAt)
+	membersKey := s.membersKey(record.FamilyId)
+	current, err := global.REDIS.TTL(ctx, membersKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := global.REDIS.TxPipeline()
+	pipe.Set(ctx, s.tokenKey(record.TokenHash), data, ttl)
+	pipe.SAdd(ctx, membersKey, record.TokenHash)
+	// 索引保留到令牌族中最晚的刷新令牌过期
+	if current < ttl {
+		pipe.Expire(ctx, membersKey, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get 获取刷新令牌
+func (s *RedisRefreshTokenStore) Get(ctx context.Context, tokenHash string) (*RefreshTokenRecord, error) {
+	data, err := global.REDIS.Get(ctx, s.tokenKey(tokenHash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, RefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record RefreshTokenRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// MarkUsed 使用 SETNX 标记，保证并发下只有一次使用成功
+func (s *RedisRefreshTokenStore) MarkUsed(ctx context.Context, record *RefreshTokenRecord) (bool, error) {
+	return global.REDIS.SetNX(ctx, s.usedKey(record.TokenHash), time.Now().Unix(), time.Until(record.ExpiresAt)).Result()
+}
+
+// RevokeFamily 写入令牌族吊销标记，保留到令牌族中最晚的过期时间
+func (s *RedisRefreshTokenStore) RevokeFamily(ctx context.Context, familyId string, until time.Time) error {
+	return global.REDIS.Set(ctx, s.familyKey(familyId), time.Now().Unix(), time.Until(until)).Err()
+}
+
+// FamilyRevoked 令牌族是否已吊销
+func (s *RedisRefreshTokenStore) FamilyRevoked(ctx context.Context, familyId string) (bool, error) {
+	count, err := global.REDIS.Exists(ctx, s.familyKey(familyId)).Result()
+	return count > 0, err
+}
+
+// ListFamily 按令牌族索引列出未过期的刷新令牌
+func (s *RedisRefreshTokenStore) ListFamily(ctx context.Context, familyId string) ([]*RefreshTokenRecord, error) {
+	hashes, err := global.REDIS.SMembers(ctx, s.membersKey(familyId)).Result()
+	if err != nil || len(hashes) == 0 {
+		return nil, err
+	}
+	keys := make([]string, 0, len(hashes))
+	for _, tokenHash := range hashes {
+		keys = append(keys, s.tokenKey(tokenHash))
+	}
+	values, err := global.REDIS.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*RefreshTokenRecord, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var record RefreshTokenRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+// DBRefreshTokenStore 基于 global.DB 的刷新令牌存储，表需通过 AutoCreateTables 创建
+type DBRefreshTokenStore struct{}
+
+// Save 保存刷新令牌
+func (s *DBRefreshTokenStore) Save(ctx context.Context, record *RefreshTokenRecord) error {
+	return global.DB.WithContext(ctx).Create(record).Error
+}
+
+// Get 获取刷新令牌
+func (s *DBRefreshTokenStore) Get(ctx context.Context, tokenHash string) (*RefreshTokenRecord, error) {
+	var record RefreshTokenRecord
+	err := global.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, RefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// MarkUsed 以条件更新标记，保证并发下只有一次使用成功
+func (s *DBRefreshTokenStore) MarkUsed(ctx context.Context, record *RefreshTokenRecord) (bool, error) {
+	result := global.DB.WithContext(ctx).Model(&RefreshTokenRecord{}).
+		Where("token_hash = ? AND used_at IS NULL", record.TokenHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeFamily 吊销令牌族中的全部刷新令牌
+func (s *DBRefreshTokenStore) RevokeFamily(ctx context.Context, familyId string, until time.Time) error {
+	return global.DB.WithContext(ctx).Model(&RefreshTokenRecord{}).
+		Where("family_id = ?", familyId).
+		Update("revoked", true).Error
+}
+
+// FamilyRevoked 令牌族是否已吊销
+func (s *DBRefreshTokenStore) FamilyRevoked(ctx context.Context, familyId string) (bool, error) {
+	var count int64
+	err := global.DB.WithContext(ctx).Model(&RefreshTokenRecord{}).
+		Where("family_id = ? AND revoked = ?", familyId, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListFamily 列出令牌族中未过期的刷新令牌
+func (s *DBRefreshTokenStore) ListFamily(ctx context.Context, familyId string) ([]*RefreshTokenRecord, error) {
+	var records []*RefreshTokenRecord
+	err := global.DB.WithContext(ctx).Where("family_id = ? AND expires_at > ?", familyId, time.Now()).Find(&records).Error
+	return records, err
+}
+
+// refreshStore 获取刷新令牌存储
+func (j *JWT) refreshStore() RefreshTokenStore {
+	if j.RefreshStore != nil {
+		return j.RefreshStore
+	}
+	return NewDefaultRefreshTokenStore()
+}
+
+// CreateTokenPair 签发访问令牌与刷新令牌，开启新的令牌族
+func (j *JWT) CreateTokenPair(ctx context.Context, claims CustomClaims) (*TokenPair, error) {
+	return j.createTokenPair(ctx, &claims)
+}
+
+// createTokenPair 开启新的令牌族并签发令牌对
+func (j *JWT) createTokenPair(ctx context.Context, claims Claims) (*TokenPair, error) {
+	return j.issueTokenPair(ctx, claims, nil)
+}
+
+// RefreshTokenPair 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+// 已使用过的刷新令牌再次出现时视为泄露，吊销整个令牌族并返回 RefreshTokenReused
+func (j *JWT) RefreshTokenPair(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return j.refreshTokenPair(ctx, refreshToken, decodeClaims[*CustomClaims])
+}
+
+// decodeClaims 将刷新令牌记录中的 claims 还原为 T 类型
+func decodeClaims[T Claims](data string) (Claims, error) {
+	claims, err := newClaims[T]()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// refreshTokenPair 校验并轮换刷新令牌，decode 用于还原签发时的 claims
+func (j *JWT) refreshTokenPair(ctx context.Context, refreshToken string, decode func(string) (Claims, error)) (*TokenPair, error) {
+	store := j.refreshStore()
+	record, err := store.Get(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(record.ExpiresAt) {
+		return nil, RefreshTokenExpired
+	}
+
+	revoked, err := store.FamilyRevoked(ctx, record.FamilyId)
+	if err != nil {
+		return nil, err
+	}
+	if revoked || record.Revoked {
+		return nil, RefreshTokenRevoked
+	}
+	before, found, err := userRevokedBefore(record.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if found && issuedNotAfter(jwt.NewNumericDate(record.CreatedAt), before) {
+		return nil, RefreshTokenRevoked
+	}
+
+	first, err := store.MarkUsed(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		// 令牌族中的刷新令牌最晚在本次轮换后 GetRefreshExpiresTime 过期
+		if err := j.revokeFamily(ctx, store, record.FamilyId, time.Now().Add(GetRefreshExpiresTime())); err != nil {
+			return nil, err
+		}
+		return nil, RefreshTokenReused
+	}
+
+	claims, err := decode(record.Claims)
+	if err != nil {
+		return nil, err
+	}
+	return j.issueTokenPair(ctx, claims, record)
+}
+
+// RevokeTokenFamily 吊销令牌族及其签发的访问令牌，用于用户退出登录
+func (j *JWT) RevokeTokenFamily(ctx context.Context, refreshToken string) error {
+	store := j.refreshStore()
+	record, err := store.Get(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	return j.revokeFamily(ctx, store, record.FamilyId, record.ExpiresAt)
+}
+
+// revokeFamily 吊销令牌族中的刷新令牌，并吊销其签发的仍未过期的访问令牌，开启多点登录拦截时同时下线对应会话
+func (j *JWT) revokeFamily(ctx context.Context, store RefreshTokenStore, familyId string, until time.Time) error {
+	if err := store.RevokeFamily(ctx, familyId, until); err != nil {
+		return err
+	}
+	records, err := store.ListFamily(ctx, familyId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, record := range records {
+		if record.AccessTokenId == "" || !record.AccessExpiresAt.After(now) {
+			continue
+		}
+		if j.isMultipointAuthEnabled() {
+			if err := j.sessionStore().Remove(ctx, record.UserId, record.AccessTokenId); err != nil {
+				return err
+			}
+		}
+		if err := RevokeToken(record.AccessTokenId, record.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issueTokenPair 签发令牌对并保存刷新令牌，previous 为本次轮换使用的刷新令牌，为空时开启新的令牌族
+// 轮换时访问令牌的会话从 previous 签发的访问令牌迁移而来，不占用新的会话名额
+func (j *JWT) issueTokenPair(ctx context.Context, claims Claims, previous *RefreshTokenRecord) (*TokenPair, error) {
+	now := time.Now()
+	familyId, familyCreatedAt, previousTokenId := "", now, ""
+	if previous != nil {
+		familyId, familyCreatedAt, previousTokenId = previous.FamilyId, previous.FamilyCreatedAt, previous.AccessTokenId
+		if familyCreatedAt.IsZero() {
+			familyCreatedAt = previous.CreatedAt
+		}
+	} else {
+		id, err := randomToken(16)
+		if err != nil {
+			return nil, err
+		}
+		familyId = id
+	}
+	// 每次轮换都会顺延刷新令牌的有效期，令牌族的有效期不超过登录时间加 GetRefreshMaxLifetime
+	refreshExpiresAt := now.Add(GetRefreshExpiresTime())
+	if maxLifetime := GetRefreshMaxLifetime(); maxLifetime > 0 {
+		if deadline := familyCreatedAt.Add(maxLifetime); deadline.Before(refreshExpiresAt) {
+			refreshExpiresAt = deadline
+		}
+		if !refreshExpiresAt.After(now) {
+			return nil, RefreshTokenExpired
+		}
+	}
+	accessExpiresAt := now.Add(time.Duration(global.CONFIG.JWT.ExpiresTime) * time.Second)
+	registered := claims.GetRegisteredClaims()
+	registered.ExpiresAt = jwt.NewNumericDate(accessExpiresAt)
+	registered.IssuedAt = jwt.NewNumericDate(now)
+
+	// 签发前保存 claims，多端会话生成的 tokenId 不随刷新令牌复用
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := j.rotateSessionToken(ctx, claims, previousTokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	record := &RefreshTokenRecord{
+		TokenHash:       hashToken(refreshToken),
+		FamilyId:        familyId,
+		UserId:          claims.GetUserId(),
+		Claims:          string(claimsJson),
+		AccessTokenId:   claims.GetTokenId(),
+		AccessExpiresAt: registered.ExpiresAt.Time,
+		ExpiresAt:       refreshExpiresAt,
+		FamilyCreatedAt: familyCreatedAt,
+		CreatedAt:       now,
+	}
+	if err := j.refreshStore().Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// randomToken 生成随机的不透明令牌
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken 计算令牌哈希，存储中只保存哈希值
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
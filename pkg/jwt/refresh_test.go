@@ -0,0 +1,115 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 09:42:25
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 09:42:25
+ * @FilePath: \go-core\pkg\jwt\refresh_test.go
+ * @Description: 刷新令牌轮换与重复使用检测测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRefreshTokenReuse 测试已使用的刷新令牌再次出现时吊销整个令牌族
+func TestRefreshTokenReuse(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("refresh-secret")}
+
+	claims := testClaims("1001")
+	claims.Issuer = "go-core"
+	pair, err := j.CreateTokenPair(ctx, claims)
+	require.NoError(t, err)
+
+	rotated, err := j.RefreshTokenPair(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, rotated.RefreshToken)
+
+	// 标准 claims 随刷新令牌保存，轮换后仍然保留
+	resolved, err := j.ResolveToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1001", resolved.UserId)
+	assert.Equal(t, "go-core", resolved.Issuer)
+	assert.NotEmpty(t, resolved.TokenId)
+
+	// 旧刷新令牌被重复使用，视为泄露
+	_, err = j.RefreshTokenPair(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, RefreshTokenReused)
+
+	// 令牌族已吊销，攻击者与合法用户手中的最新刷新令牌均失效
+	_, err = j.RefreshTokenPair(ctx, rotated.RefreshToken)
+	assert.ErrorIs(t, err, RefreshTokenRevoked)
+
+	// 令牌族签发的访问令牌同时失效
+	for _, accessToken := range []string{pair.AccessToken, rotated.AccessToken} {
+		_, err = j.ResolveToken(accessToken)
+		assert.ErrorIs(t, err, TokenRevoked)
+	}
+
+	// 其他登录开启的令牌族不受影响
+	other, err := j.CreateTokenPair(ctx, testClaims("1001"))
+	require.NoError(t, err)
+	_, err = j.RefreshTokenPair(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+// TestRevokeTokenFamily 测试退出登录后刷新令牌与访问令牌均失效
+func TestRevokeTokenFamily(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("refresh-secret")}
+
+	pair, err := j.CreateTokenPair(ctx, testClaims("1001"))
+	require.NoError(t, err)
+	require.NoError(t, j.RevokeTokenFamily(ctx, pair.RefreshToken))
+
+	_, err = j.ResolveToken(pair.AccessToken)
+	assert.ErrorIs(t, err, TokenRevoked)
+	_, err = j.RefreshTokenPair(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, RefreshTokenRevoked)
+}
+
+// TestRefreshTokenMaxLifetime 测试轮换不会把令牌族的有效期顺延到登录时间加最长有效期之后
+func TestRefreshTokenMaxLifetime(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	original := GetRefreshMaxLifetime()
+	t.Cleanup(func() { SetRefreshMaxLifetime(original) })
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("refresh-secret")}
+
+	SetRefreshMaxLifetime(time.Hour)
+	pair, err := j.CreateTokenPair(ctx, testClaims("1001"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), pair.RefreshExpiresAt, time.Second)
+
+	rotated, err := j.RefreshTokenPair(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	assert.False(t, rotated.RefreshExpiresAt.After(pair.RefreshExpiresAt))
+
+	// 等同于已超过最长有效期
+	SetRefreshMaxLifetime(time.Nanosecond)
+	_, err = j.RefreshTokenPair(ctx, rotated.RefreshToken)
+	assert.ErrorIs(t, err, RefreshTokenExpired)
+}
+
+// TestRefreshTokenInvalid 测试未知的刷新令牌
+func TestRefreshTokenInvalid(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("refresh-secret")}
+
+	_, err := j.RefreshTokenPair(context.Background(), "unknown")
+	assert.ErrorIs(t, err, RefreshTokenInvalid)
+}
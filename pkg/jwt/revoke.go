@@ -0,0 +1,239 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 12:16:40
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 12:16:40
+ * @FilePath: \go-core\pkg\jwt\revoke.go
+ * @Description: 按 tokenId 吊销单个 Token，或吊销用户在某时间之前签发的全部 Token
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+)
+
+var revokePerFixKey = global.GPerFix + "jwt_revoked"
+
+// RevocationStoreMissing 未配置 Redis 与数据库，无法保存吊销记录
+var RevocationStoreMissing error = errors.New("未配置 Redis 或数据库，无法保存吊销记录")
+
+// SetRevokePerFixKey 设置吊销记录 Redis 键前缀
+func SetRevokePerFixKey(key string) {
+	revokePerFixKey = key
+}
+
+// GetRevokePerFixKey 获取吊销记录 Redis 键前缀
+func GetRevokePerFixKey() string {
+	return revokePerFixKey
+}
+
+// RevokedToken 已吊销的 Token，无 Redis 时存入数据库
+type RevokedToken struct {
+
+	/** 被吊销的tokenId */
+	TokenId string `json:"tokenId"    gorm:"column:token_id;primary_key;comment:tokenId;type:varchar(36);"`
+
+	/** 吊销记录过期时间，等于 Token 的过期时间 */
+	ExpiresAt time.Time `json:"expiresAt"  gorm:"column:expires_at;index;comment:过期时间;"`
+
+	/** 创建时间 */
+	CreatedAt time.Time `json:"createdAt"  gorm:"column:created_at;comment:创建时间;"`
+}
+
+// TableName 自定义表名
+func (RevokedToken) TableName() string {
+	return global.GPerFix + "jwt_revoked_tokens"
+}
+
+// UserTokenRevocation 用户级吊销记录，签发时间早于 RevokedBefore 的 Token 均失效
+type UserTokenRevocation struct {
+
+	/** 用户账号id，机器身份为 svc: 加客户端id */
+	UserId string `json:"userId"         gorm:"column:user_id;primary_key;comment:用户账号id或svc:客户端id;type:varchar(72);"`
+
+	/** 吊销此时间之前签发的 Token */
+	RevokedBefore time.Time `json:"revokedBefore"  gorm:"column:revoked_before;comment:吊销此时间之前签发的Token;"`
+
+	/** 记录过期时间，此后被吊销的 Token 均已过期 */
+	ExpiresAt time.Time `json:"expiresAt"      gorm:"column:expires_at;index;comment:过期时间;"`
+}
+
+// TableName 自定义表名
+func (UserTokenRevocation) TableName() string {
+	return global.GPerFix + "jwt_user_revocations"
+}
+
+// revokedTokenKey 单个 Token 吊销键
+func revokedTokenKey(tokenId string) string {
+	return GetRevokePerFixKey() + ":token:" + tokenId
+}
+
+// serviceRevocationPrefix 机器身份吊销记录的主体前缀，客户端id 与用户id 相同时互不影响
+const serviceRevocationPrefix = "svc:"
+
+// revokedUserKey 用户级吊销键，机器身份为 :svc:客户端id
+func revokedUserKey(subject string) string {
+	if clientId, ok := strings.CutPrefix(subject, serviceRevocationPrefix); ok {
+		return GetRevokePerFixKey() + ":svc:" + clientId
+	}
+	return GetRevokePerFixKey() + ":user:" + subject
+}
+
+// revocationSubject 吊销记录的主体，机器身份为 svc: 加客户端id，其余为用户id
+func revocationSubject(claims Claims) string {
+	if isServiceClaims(claims) {
+		return serviceRevocationPrefix + claims.GetUserId()
+	}
+	return claims.GetUserId()
+}
+
+// RevokeToken 吊销单个 Token，until 为 Token 的过期时间
+// 过期后 BufferTime 内仍可调用 RefreshToken，吊销记录保留到 until 之后 BufferTime 为止
+func RevokeToken(tokenId string, until time.Time) error {
+	if tokenId == "" {
+		return TokenInvalid
+	}
+	if global.CONFIG != nil {
+		until = until.Add(time.Duration(global.CONFIG.JWT.BufferTime) * time.Second)
+	}
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		// Token 已过期，无需记录
+		return nil
+	}
+	if global.REDIS != nil {
+		return global.REDIS.Set(context.Background(), revokedTokenKey(tokenId), until.Unix(), ttl).Err()
+	}
+	if global.DB == nil {
+		return RevocationStoreMissing
+	}
+	record := RevokedToken{TokenId: tokenId, ExpiresAt: until, CreatedAt: time.Now()}
+	return global.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// RevokeUserTokensBefore 吊销用户在 before 之前签发的全部 Token，包括 before 之前创建的刷新令牌
+// iat 精确到秒，与 before 同一秒签发的 Token 同样失效，吊销后立即重新登录需等到下一秒签发
+// 记录保留到 before 之后一个刷新令牌有效期，届时这些 Token 均已过期
+func RevokeUserTokensBefore(userId string, before time.Time) error {
+	if userId == "" {
+		return errors.New("userId 不能为空")
+	}
+	return revokeSubjectBefore(userId, before)
+}
+
+// RevokeServiceTokensBefore 吊销客户端在 before 之前签发的全部服务 Token，与同名用户的记录互不影响
+func RevokeServiceTokensBefore(clientId string, before time.Time) error {
+	if clientId == "" {
+		return errors.New("clientId 不能为空")
+	}
+	return revokeSubjectBefore(serviceRevocationPrefix+clientId, before)
+}
+
+// revokeSubjectBefore 写入用户级吊销记录，subject 见 revocationSubject
+func revokeSubjectBefore(subject string, before time.Time) error {
+	var lifetime time.Duration
+	if global.CONFIG != nil {
+		lifetime = time.Duration(global.CONFIG.JWT.ExpiresTime+global.CONFIG.JWT.BufferTime) * time.Second
+	}
+	if GetRefreshExpiresTime() > lifetime {
+		lifetime = GetRefreshExpiresTime()
+	}
+	until := before.Add(lifetime)
+	if global.REDIS != nil {
+		ttl := time.Until(until)
+		if ttl <= 0 {
+			return nil
+		}
+		return global.REDIS.Set(context.Background(), revokedUserKey(subject), before.Unix(), ttl).Err()
+	}
+	if global.DB == nil {
+		return RevocationStoreMissing
+	}
+	record := UserTokenRevocation{UserId: subject, RevokedBefore: before, ExpiresAt: until}
+	return global.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// PurgeRevokedTokens 清理数据库中已过期的吊销记录，Redis 记录会自动过期
+func PurgeRevokedTokens() error {
+	if global.DB == nil {
+		return nil
+	}
+	now := time.Now()
+	if err := global.DB.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return global.DB.Where("expires_at < ?", now).Delete(&UserTokenRevocation{}).Error
+}
+
+// IsTokenRevoked 检查 Token 是否已被吊销
+func IsTokenRevoked(claims Claims) (bool, error) {
+	revoked, err := isTokenIdRevoked(claims.GetTokenId())
+	if err != nil || revoked {
+		return revoked, err
+	}
+	before, found, err := userRevokedBefore(revocationSubject(claims))
+	if err != nil || !found {
+		return false, err
+	}
+	return issuedNotAfter(claims.GetRegisteredClaims().IssuedAt, before), nil
+}
+
+// issuedNotAfter 签发时间不晚于吊销时间所在的秒，没有签发时间的 Token 视为更早签发
+// iat 只精确到秒，无法区分同一秒内吊销前后签发的 Token，按已吊销处理
+func issuedNotAfter(issuedAt *jwt.NumericDate, before time.Time) bool {
+	return issuedAt == nil || issuedAt.Unix() <= before.Unix()
+}
+
+// isTokenIdRevoked 检查单个 Token 的吊销记录
+func isTokenIdRevoked(tokenId string) (bool, error) {
+	if tokenId == "" {
+		return false, nil
+	}
+	if global.REDIS != nil {
+		count, err := global.REDIS.Exists(context.Background(), revokedTokenKey(tokenId)).Result()
+		return count > 0, err
+	}
+	if global.DB == nil {
+		return false, nil
+	}
+	var count int64
+	err := global.DB.Model(&RevokedToken{}).Where("token_id = ? AND expires_at > ?", tokenId, time.Now()).Count(&count).Error
+	return count > 0, err
+}
+
+// userRevokedBefore 获取用户级吊销时间，subject 见 revocationSubject
+func userRevokedBefore(subject string) (time.Time, bool, error) {
+	if subject == "" || subject == serviceRevocationPrefix {
+		return time.Time{}, false, nil
+	}
+	if global.REDIS != nil {
+		before, err := global.REDIS.Get(context.Background(), revokedUserKey(subject)).Int64()
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, false, nil
+		}
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return time.Unix(before, 0), true, nil
+	}
+	if global.DB == nil {
+		return time.Time{}, false, nil
+	}
+	// 每次验签都会查询，使用 Find 避免未找到记录时打印错误日志
+	var revocation UserTokenRevocation
+	result := global.DB.Where("user_id = ? AND expires_at > ?", subject, time.Now()).Limit(1).Find(&revocation)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return time.Time{}, false, result.Error
+	}
+	return revocation.RevokedBefore, true, nil
+}
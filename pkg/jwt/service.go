@@ -0,0 +1,339 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 19:20:42
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 19:20:42
+ * @FilePath: \go-core\pkg\jwt\service.go
+ * @Description: 机器身份，服务间调用使用客户端凭证换取服务 Token，与 API Key 一样以 ServiceClaims 作为当前身份
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 机器身份相关错误
+var (
+	ClientCredentialsInvalid error = errors.New("客户端凭证无效")
+	ClientDisabled           error = errors.New("客户端已停用")
+	ScopeNotAllowed          error = errors.New("申请的授权范围超出客户端允许的范围")
+)
+
+const (
+	ServiceUserType = "service" // 机器身份的用户类型，与 CustomClaims.UserType 含义一致
+	TokenUseService = "service" // 客户端凭证签发的服务 Token
+	TokenUseAPIKey  = "api_key" // API Key 校验得到的身份
+	ScopeAll        = "*"       // 全部授权范围
+)
+
+var (
+	serviceTokenExpiresTime = time.Hour // 服务 Token 默认有效期
+	servicePerFixKey        = global.GPerFix + "jwt_service_client"
+)
+
+// SetServiceTokenExpiresTime 设置服务 Token 有效期
+func SetServiceTokenExpiresTime(duration time.Duration) {
+	serviceTokenExpiresTime = duration
+}
+
+// GetServiceTokenExpiresTime 获取服务 Token 有效期
+func GetServiceTokenExpiresTime() time.Duration {
+	return serviceTokenExpiresTime
+}
+
+// SetServicePerFixKey 设置服务客户端 Redis 键前缀
+func SetServicePerFixKey(key string) {
+	servicePerFixKey = key
+}
+
+// GetServicePerFixKey 获取服务客户端 Redis 键前缀
+func GetServicePerFixKey() string {
+	return servicePerFixKey
+}
+
+// ServiceClaims 机器身份的 claims，用户id 为客户端id，casbin 按客户端id 授权
+type ServiceClaims struct {
+	BaseClaims
+	ClientId   string   `json:"clientId"`
+	Name       string   `json:"name,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+	MerchantNo string   `json:"merchantNo,omitempty"`
+	TokenUse   string   `json:"tokenUse"` // service 或 api_key
+}
+
+// GetUserId 返回客户端id
+func (c *ServiceClaims) GetUserId() string {
+	return c.ClientId
+}
+
+// GetUserType 返回 ServiceUserType
+func (c *ServiceClaims) GetUserType() string {
+	return ServiceUserType
+}
+
+// GetTenantId 返回租户id，即商户号
+func (c *ServiceClaims) GetTenantId() string {
+	return c.MerchantNo
+}
+
+// HasScope 是否拥有授权范围，* 表示全部
+func (c *ServiceClaims) HasScope(scope string) bool {
+	return containsString(c.Scopes, ScopeAll) || containsString(c.Scopes, scope)
+}
+
+// Validate 校验客户端id
+func (c *ServiceClaims) Validate() error {
+	if c.ClientId == "" {
+		return errors.New("clientId 不能为空")
+	}
+	return nil
+}
+
+// isServiceClaims 机器身份不登记多端会话
+func isServiceClaims(claims Claims) bool {
+	_, ok := claims.(*ServiceClaims)
+	return ok
+}
+
+// ServiceClient 客户端凭证，只保存密钥的哈希值
+type ServiceClient struct {
+
+	/** 客户端id，即服务名 */
+	ClientId string `json:"clientId"    gorm:"column:client_id;primary_key;comment:客户端id;type:varchar(64);"`
+
+	/** 客户端密钥 SHA-256 哈希 */
+	SecretHash string `json:"secretHash"  gorm:"column:secret_hash;comment:客户端密钥哈希;type:varchar(64);"`
+
+	/** 客户端名称 */
+	Name string `json:"name"        gorm:"column:name;comment:客户端名称;type:varchar(128);"`
+
+	/** 允许申请的授权范围 */
+	Scopes []string `json:"scopes"      gorm:"column:scopes;serializer:json;comment:授权范围;type:text;"`
+
+	/** 所属商户的商户号 */
+	MerchantNo string `json:"merchantNo"  gorm:"column:merchant_no;comment:商户号;type:varchar(32);"`
+
+	/** 是否已停用 */
+	Disabled bool `json:"disabled"    gorm:"column:disabled;comment:是否已停用;"`
+
+	/** 创建时间 */
+	CreatedAt time.Time `json:"createdAt"   gorm:"column:created_at;comment:创建时间;"`
+}
+
+// TableName 自定义表名
+func (ServiceClient) TableName() string {
+	return global.GPerFix + "jwt_service_clients"
+}
+
+// ServiceClientStore 客户端凭证存储
+type ServiceClientStore interface {
+	// Save 保存客户端，已存在时覆盖
+	Save(ctx context.Context, client *ServiceClient) error
+	// Get 获取客户端，不存在时返回 ClientCredentialsInvalid
+	Get(ctx context.Context, clientId string) (*ServiceClient, error)
+	// Delete 删除客户端
+	Delete(ctx context.Context, clientId string) error
+}
+
+// NewDefaultServiceClientStore 有 Redis 时使用 Redis，否则使用数据库
+func NewDefaultServiceClientStore() ServiceClientStore {
+	if global.REDIS != nil {
+		return &RedisServiceClientStore{PrefixKey: GetServicePerFixKey()}
+	}
+	return &DBServiceClientStore{}
+}
+
+// RedisServiceClientStore 基于 global.REDIS 的客户端凭证存储
+type RedisServiceClientStore struct {
+	PrefixKey string
+}
+
+// clientKey 客户端键
+func (s *RedisServiceClientStore) clientKey(clientId string) string {
+	return s.PrefixKey + ":client:" + clientId
+}
+
+// Save 保存客户端
+func (s *RedisServiceClientStore) Save(ctx context.Context, client *ServiceClient) error {
+	data, err := json.Marshal(client)
+	if err != nil {
+		return err
+	}
+	return global.REDIS.Set(ctx, s.clientKey(client.ClientId), data, 0).Err()
+}
+
+// Get 获取客户端
+func (s *RedisServiceClientStore) Get(ctx context.Context, clientId string) (*ServiceClient, error) {
+	data, err := global.REDIS.Get(ctx, s.clientKey(clientId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ClientCredentialsInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	var client ServiceClient
+	if err := json.Unmarshal(data, &client); err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// Delete 删除客户端
+func (s *RedisServiceClientStore) Delete(ctx context.Context, clientId string) error {
+	return global.REDIS.Del(ctx, s.clientKey(clientId)).Err()
+}
+
+// DBServiceClientStore 基于 global.DB 的客户端凭证存储，表需通过 AutoCreateTables 创建
+type DBServiceClientStore struct{}
+
+// Save 保存客户端
+func (s *DBServiceClientStore) Save(ctx context.Context, client *ServiceClient) error {
+	return global.DB.WithContext(ctx).Save(client).Error
+}
+
+// Get 获取客户端
+func (s *DBServiceClientStore) Get(ctx context.Context, clientId string) (*ServiceClient, error) {
+	var client ServiceClient
+	err := global.DB.WithContext(ctx).Where("client_id = ?", clientId).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ClientCredentialsInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// Delete 删除客户端
+func (s *DBServiceClientStore) Delete(ctx context.Context, clientId string) error {
+	return global.DB.WithContext(ctx).Where("client_id = ?", clientId).Delete(&ServiceClient{}).Error
+}
+
+// serviceClientStore 获取客户端凭证存储
+func (j *JWT) serviceClientStore() ServiceClientStore {
+	if j.ServiceClientStore != nil {
+		return j.ServiceClientStore
+	}
+	return NewDefaultServiceClientStore()
+}
+
+// ServiceToken 客户端凭证换取的服务 Token
+type ServiceToken struct {
+	AccessToken string    `json:"accessToken"`
+	TokenType   string    `json:"tokenType"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Scopes      []string  `json:"scopes"`
+}
+
+// RegisterServiceClient 登记客户端并生成密钥，密钥只在此时返回一次，重复登记会轮换密钥
+func (j *JWT) RegisterServiceClient(ctx context.Context, client *ServiceClient) (string, error) {
+	if client.ClientId == "" {
+		return "", errors.New("clientId 不能为空")
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	client.SecretHash = hashToken(secret)
+	if client.CreatedAt.IsZero() {
+		client.CreatedAt = time.Now()
+	}
+	if err := j.serviceClientStore().Save(ctx, client); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DisableServiceClient 停用客户端并吊销已签发的服务 Token
+func (j *JWT) DisableServiceClient(ctx context.Context, clientId string) error {
+	store := j.serviceClientStore()
+	client, err := store.Get(ctx, clientId)
+	if err != nil {
+		return err
+	}
+	client.Disabled = true
+	if err := store.Save(ctx, client); err != nil {
+		return err
+	}
+	return RevokeServiceTokensBefore(clientId, time.Now())
+}
+
+// IssueServiceToken 校验客户端凭证并签发服务 Token，scopes 为空时授予客户端允许的全部范围
+func (j *JWT) IssueServiceToken(ctx context.Context, clientId, clientSecret string, scopes ...string) (*ServiceToken, error) {
+	client, err := j.serviceClientStore().Get(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashToken(clientSecret))) != 1 {
+		return nil, ClientCredentialsInvalid
+	}
+	if client.Disabled {
+		return nil, ClientDisabled
+	}
+	granted := client.Scopes
+	if len(scopes) > 0 {
+		for _, scope := range scopes {
+			if !containsString(client.Scopes, ScopeAll) && !containsString(client.Scopes, scope) {
+				return nil, fmt.Errorf("%w: %s", ScopeNotAllowed, scope)
+			}
+		}
+		granted = scopes
+	}
+
+	expiresAt := time.Now().Add(GetServiceTokenExpiresTime())
+	claims := &ServiceClaims{
+		ClientId:   client.ClientId,
+		Name:       client.Name,
+		Scopes:     granted,
+		MerchantNo: client.MerchantNo,
+		TokenUse:   TokenUseService,
+	}
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	token, err := j.createToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceToken{AccessToken: token, TokenType: "Bearer", ExpiresAt: expiresAt, Scopes: granted}, nil
+}
+
+// ResolveServiceToken 解析服务 Token，用户 Token 返回 TokenInvalid
+func (j *JWT) ResolveServiceToken(tokenString string) (*ServiceClaims, error) {
+	claims, err := resolveToken[*ServiceClaims](j, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != TokenUseService || claims.ClientId == "" {
+		return nil, TokenInvalid
+	}
+	return claims, nil
+}
+
+// isServiceToken 不验签读取 tokenUse，判断是否为服务 Token，验签由 ResolveServiceToken 完成
+func (j *JWT) isServiceToken(tokenString string) bool {
+	tokenString, err := j.unwrapToken(tokenString)
+	if err != nil {
+		return false
+	}
+	return tokenUseOf(tokenString) == TokenUseService
+}
+
+// tokenUseOf 不验签读取 JWS 的 tokenUse，用户 Token 为空
+func tokenUseOf(jws string) string {
+	var claims ServiceClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(jws, &claims); err != nil {
+		return ""
+	}
+	return claims.TokenUse
+}
@@ -0,0 +1,78 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 11:08:37
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 11:08:37
+ * @FilePath: \go-core\pkg\jwt\service_test.go
+ * @Description: 机器身份与用户身份的隔离测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueTestServiceToken 登记客户端并签发服务 Token
+func issueTestServiceToken(t *testing.T, j *JWT, clientId string) string {
+	ctx := context.Background()
+	secret, err := j.RegisterServiceClient(ctx, &ServiceClient{ClientId: clientId, Scopes: []string{ScopeAll}})
+	require.NoError(t, err)
+	token, err := j.IssueServiceToken(ctx, clientId, secret)
+	require.NoError(t, err)
+	return token.AccessToken
+}
+
+// TestServiceTokenAsUserToken 测试服务 Token 不能按用户 Token 解析
+func TestServiceTokenAsUserToken(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("service-secret")}
+	token := issueTestServiceToken(t, j, "billing")
+
+	_, err := j.ResolveToken(token)
+	assert.ErrorIs(t, err, TokenInvalid)
+	_, err = j.ResolveClaims(token)
+	assert.ErrorIs(t, err, TokenInvalid)
+
+	claims, err := j.ResolveServiceToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", claims.ClientId)
+}
+
+// TestServiceRevocationNamespace 测试客户端id 与用户id 相同时吊销记录互不影响
+func TestServiceRevocationNamespace(t *testing.T) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	j := &JWT{SigningKey: []byte("service-secret")}
+
+	userClaims := testClaims("billing")
+	userClaims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	userToken, err := j.CreateToken(userClaims)
+	require.NoError(t, err)
+	serviceToken := issueTestServiceToken(t, j, "billing")
+
+	// 停用客户端不影响同名用户
+	require.NoError(t, j.DisableServiceClient(context.Background(), "billing"))
+	_, err = j.ResolveToken(userToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, RevokeServiceTokensBefore("billing", time.Now().Add(time.Second)))
+	_, err = j.ResolveServiceToken(serviceToken)
+	assert.ErrorIs(t, err, TokenRevoked)
+	_, err = j.ResolveToken(userToken)
+	assert.NoError(t, err)
+
+	// 吊销用户不影响同名客户端
+	otherService := issueTestServiceToken(t, j, "orders")
+	require.NoError(t, RevokeUserTokensBefore("orders", time.Now().Add(time.Second)))
+	_, err = j.ResolveServiceToken(otherService)
+	assert.NoError(t, err)
+}
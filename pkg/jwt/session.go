@@ -0,0 +1,510 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 13:02:27
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 13:02:27
+ * @FilePath: \go-core\pkg\jwt\session.go
+ * @Description: 多端登录会话，按 tokenId 记录会话，支持按平台限制并发数并踢出最早的会话
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+)
+
+// 会话相关错误
+var (
+	SessionNotFound    error = errors.New("会话不存在或已下线")
+	SessionLockTimeout error = errors.New("登录请求过于频繁，请稍后重试")
+)
+
+const (
+	sessionLockTTL   = 5 * time.Second       // 用户会话锁的过期时间，也是等待锁的最长时间
+	sessionLockRetry = 20 * time.Millisecond // 等待用户会话锁的重试间隔
+)
+
+// Session 登录会话
+type Session struct {
+
+	/** 单次登陆产生的tokenId */
+	TokenId string `json:"tokenId"       gorm:"column:token_id;primary_key;comment:tokenId;type:varchar(36);"`
+
+	/** 用户账号id */
+	UserId string `json:"userId"        gorm:"column:user_id;index;comment:用户账号id;type:varchar(36);"`
+
+	/** 平台类型 */
+	PlatformType int32 `json:"platformType"  gorm:"column:platform_type;comment:平台类型;"`
+
+	/** 设备名称或标识 */
+	Device string `json:"device"        gorm:"column:device;comment:设备;type:varchar(128);"`
+
+	/** 登录IP */
+	IP string `json:"ip"            gorm:"column:ip;comment:登录IP;type:varchar(64);"`
+
+	/** 客户端 User-Agent */
+	UserAgent string `json:"userAgent"     gorm:"column:user_agent;comment:User-Agent;type:varchar(512);"`
+
+	/** 登录时间 */
+	CreatedAt time.Time `json:"createdAt"     gorm:"column:created_at;comment:登录时间;"`
+
+	/** 过期时间 */
+	ExpiresAt time.Time `json:"expiresAt"     gorm:"column:expires_at;index;comment:过期时间;"`
+}
+
+// TableName 自定义表名
+func (Session) TableName() string {
+	return global.GPerFix + "jwt_sessions"
+}
+
+// SessionMeta 登录设备信息
+type SessionMeta struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// sessionSettings 会话并发限制设置
+type sessionSettings struct {
+	mu          sync.RWMutex
+	defaultMax  int
+	platformMax map[int32]int
+	prefixKey   string
+}
+
+var sessionLimits = &sessionSettings{
+	defaultMax:  1,
+	platformMax: make(map[int32]int),
+	prefixKey:   global.GPerFix + "jwt_session",
+}
+
+// SetDefaultMaxSessions 设置每个平台默认的最大并发会话数，小于等于 0 表示不限制
+func SetDefaultMaxSessions(max int) {
+	sessionLimits.mu.Lock()
+	defer sessionLimits.mu.Unlock()
+	sessionLimits.defaultMax = max
+}
+
+// SetMaxSessions 设置指定平台的最大并发会话数，小于等于 0 表示不限制
+func SetMaxSessions(platformType int32, max int) {
+	sessionLimits.mu.Lock()
+	defer sessionLimits.mu.Unlock()
+	sessionLimits.platformMax[platformType] = max
+}
+
+// GetMaxSessions 获取指定平台的最大并发会话数
+func GetMaxSessions(platformType int32) int {
+	sessionLimits.mu.RLock()
+	defer sessionLimits.mu.RUnlock()
+	if max, ok := sessionLimits.platformMax[platformType]; ok {
+		return max
+	}
+	return sessionLimits.defaultMax
+}
+
+// SetSessionPerFixKey 设置会话 Redis 键前缀
+func SetSessionPerFixKey(key string) {
+	sessionLimits.mu.Lock()
+	defer sessionLimits.mu.Unlock()
+	sessionLimits.prefixKey = key
+}
+
+// GetSessionPerFixKey 获取会话 Redis 键前缀
+func GetSessionPerFixKey() string {
+	sessionLimits.mu.RLock()
+	defer sessionLimits.mu.RUnlock()
+	return sessionLimits.prefixKey
+}
+
+// SessionStore 会话存储
+type SessionStore interface {
+	// Save 保存会话
+	Save(ctx context.Context, session *Session) error
+	// Exists 会话是否存在且未过期
+	Exists(ctx context.Context, tokenId string) (bool, error)
+	// List 按登录时间升序列出用户的有效会话
+	List(ctx context.Context, userId string) ([]*Session, error)
+	// Remove 删除会话
+	Remove(ctx context.Context, userId, tokenId string) error
+}
+
+// SessionLocker 可选的按用户加锁，存储实现该接口时登记会话前加锁，并发登录不会超过会话数上限
+// 未实现时会话数上限为尽力而为，同一用户并发登录时可能短暂超出
+type SessionLocker interface {
+	// LockUser 获取用户的会话锁，已被持有时 ok 为 false，ttl 后自动释放
+	LockUser(ctx context.Context, userId string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// NewDefaultSessionStore 有 Redis 时使用 Redis，否则使用数据库
+func NewDefaultSessionStore() SessionStore {
+	if global.REDIS != nil {
+		return &RedisSessionStore{PrefixKey: GetSessionPerFixKey()}
+	}
+	return &DBSessionStore{}
+}
+
+// RedisSessionStore 基于 global.REDIS 的会话存储
+// 会话内容保存在 {prefix}:token:{tokenId}，用户的会话索引为有序集合 {prefix}:user:{userId}
+type RedisSessionStore struct {
+	PrefixKey string
+}
+
+// tokenKey 会话键
+func (s *RedisSessionStore) tokenKey(tokenId string) string {
+	return s.PrefixKey + ":token:" + tokenId
+}
+
+// userKey 用户会话索引键
+func (s *RedisSessionStore) userKey(userId string) string {
+	return s.PrefixKey + ":user:" + userId
+}
+
+// Save 保存会话
+func (s *RedisSessionStore) Save(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(session.ExpiresAt)
+	userKey := s.userKey(session.UserId)
+	current, err := global.REDIS.TTL(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := global.REDIS.TxPipeline()
+	pipe.Set(ctx, s.tokenKey(session.TokenId), data, ttl)
+	pipe.ZAdd(ctx, userKey, redis.Z{Score: float64(session.CreatedAt.UnixNano()), Member: session.TokenId})
+	// 索引保留到最晚的会话过期
+	if current < ttl {
+		pipe.Expire(ctx, userKey, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Exists 会话是否存在
+func (s *RedisSessionStore) Exists(ctx context.Context, tokenId string) (bool, error) {
+	count, err := global.REDIS.Exists(ctx, s.tokenKey(tokenId)).Result()
+	return count > 0, err
+}
+
+// List 列出用户的有效会话，顺带清理索引中已过期的会话
+func (s *RedisSessionStore) List(ctx context.Context, userId string) ([]*Session, error) {
+	tokenIds, err := global.REDIS.ZRange(ctx, s.userKey(userId), 0, -1).Result()
+	if err != nil || len(tokenIds) == 0 {
+		return nil, err
+	}
+	keys := make([]string, 0, len(tokenIds))
+	for _, tokenId := range tokenIds {
+		keys = append(keys, s.tokenKey(tokenId))
+	}
+	values, err := global.REDIS.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(values))
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			expired = append(expired, tokenIds[i])
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	if len(expired) > 0 {
+		global.REDIS.ZRem(ctx, s.userKey(userId), expired...)
+	}
+	return sessions, nil
+}
+
+// LockUser 使用 SETNX 写入随机值获取用户的会话锁，释放时比较后删除
+func (s *RedisSessionStore) LockUser(ctx context.Context, userId string, ttl time.Duration) (func(), bool, error) {
+	owner, err := randomToken(16)
+	if err != nil {
+		return nil, false, err
+	}
+	lockKey := s.PrefixKey + ":lock:" + userId
+	ok, err := global.REDIS.SetNX(ctx, lockKey, owner, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return func() { releaseLockScript.Run(context.Background(), global.REDIS, []string{lockKey}, owner) }, true, nil
+}
+
+// Remove 删除会话
+func (s *RedisSessionStore) Remove(ctx context.Context, userId, tokenId string) error {
+	pipe := global.REDIS.TxPipeline()
+	pipe.Del(ctx, s.tokenKey(tokenId))
+	pipe.ZRem(ctx, s.userKey(userId), tokenId)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DBSessionStore 基于 global.DB 的会话存储，表需通过 AutoCreateTables 创建
+type DBSessionStore struct{}
+
+// Save 保存会话
+func (s *DBSessionStore) Save(ctx context.Context, session *Session) error {
+	return global.DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(session).Error
+}
+
+// Exists 会话是否存在
+func (s *DBSessionStore) Exists(ctx context.Context, tokenId string) (bool, error) {
+	var count int64
+	err := global.DB.WithContext(ctx).Model(&Session{}).Where("token_id = ? AND expires_at > ?", tokenId, time.Now()).Count(&count).Error
+	return count > 0, err
+}
+
+// List 列出用户的有效会话
+func (s *DBSessionStore) List(ctx context.Context, userId string) ([]*Session, error) {
+	var sessions []*Session
+	err := global.DB.WithContext(ctx).Where("user_id = ? AND expires_at > ?", userId, time.Now()).Order("created_at").Find(&sessions).Error
+	return sessions, err
+}
+
+// Remove 删除会话
+func (s *DBSessionStore) Remove(ctx context.Context, userId, tokenId string) error {
+	return global.DB.WithContext(ctx).Where("user_id = ? AND token_id = ?", userId, tokenId).Delete(&Session{}).Error
+}
+
+// sessionStore 获取会话存储
+func (j *JWT) sessionStore() SessionStore {
+	if j.SessionStore != nil {
+		return j.SessionStore
+	}
+	return NewDefaultSessionStore()
+}
+
+// CreateSessionToken 签发 token 并登记会话，同一平台会话数超过上限时踢出最早的会话
+// 返回被踢出的会话，被踢出会话的 token 同时被吊销
+func (j *JWT) CreateSessionToken(ctx context.Context, claims CustomClaims, meta SessionMeta) (string, []*Session, error) {
+	return j.createSessionToken(ctx, &claims, meta)
+}
+
+// createSessionToken 校验 claims，补全 tokenId 与过期时间后登记会话并签发 token
+func (j *JWT) createSessionToken(ctx context.Context, claims Claims, meta SessionMeta) (string, []*Session, error) {
+	if err := validateClaims(claims); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	if claims.GetTokenId() == "" {
+		tokenId, err := randomToken(16)
+		if err != nil {
+			return "", nil, err
+		}
+		setTokenId(claims, tokenId)
+	}
+	registered := claims.GetRegisteredClaims()
+	if registered.ExpiresAt == nil {
+		registered.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(global.CONFIG.JWT.ExpiresTime) * time.Second))
+	}
+
+	session := &Session{
+		TokenId:      claims.GetTokenId(),
+		UserId:       claims.GetUserId(),
+		PlatformType: claimsPlatformType(claims),
+		Device:       meta.Device,
+		IP:           meta.IP,
+		UserAgent:    meta.UserAgent,
+		CreatedAt:    now,
+		ExpiresAt:    registered.ExpiresAt.Time,
+	}
+	kicked, err := j.addSession(ctx, session)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := j.prepareClaims(claims); err != nil {
+		return "", kicked, err
+	}
+	token, err := j.signToken(claims)
+	return token, kicked, err
+}
+
+// addSession 登记会话并按平台上限踢出最早的会话，存储实现 SessionLocker 时按用户串行执行
+func (j *JWT) addSession(ctx context.Context, session *Session) ([]*Session, error) {
+	store := j.sessionStore()
+	unlock, err := lockUserSessions(ctx, store, session.UserId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	sessions, err := store.List(ctx, session.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	var samePlatform []*Session
+	for _, existing := range sessions {
+		if existing.PlatformType == session.PlatformType && existing.TokenId != session.TokenId {
+			samePlatform = append(samePlatform, existing)
+		}
+	}
+	sort.Slice(samePlatform, func(a, b int) bool {
+		return samePlatform[a].CreatedAt.Before(samePlatform[b].CreatedAt)
+	})
+
+	var kicked []*Session
+	if max := GetMaxSessions(session.PlatformType); max > 0 && len(samePlatform) >= max {
+		kicked = samePlatform[:len(samePlatform)-max+1]
+	}
+	if err := store.Save(ctx, session); err != nil {
+		return nil, err
+	}
+	for _, old := range kicked {
+		if err := j.terminate(ctx, store, old); err != nil {
+			return kicked, err
+		}
+	}
+	return kicked, nil
+}
+
+// rotateSessionToken 签发更换了 tokenId 的 token，用于刷新，previousTokenId 为刷新前的 tokenId
+// 开启多点登录拦截时原会话迁移到新 tokenId，保留登录时间与设备信息，再下线原会话并吊销原 token，不占用新的会话名额
+// 原会话已随原 token 过期时按新登录登记会话，原 token 已被吊销（被踢出或下线）时返回 TokenKicked
+func (j *JWT) rotateSessionToken(ctx context.Context, claims Claims, previousTokenId string) (string, error) {
+	if previousTokenId != "" && claims.GetTokenId() == previousTokenId {
+		if err := reissueTokenId(claims); err != nil {
+			return "", err
+		}
+	}
+	if previousTokenId == "" || !j.isMultipointAuthEnabled() || isServiceClaims(claims) {
+		return j.createToken(claims)
+	}
+	if err := validateClaims(claims); err != nil {
+		return "", err
+	}
+	if claims.GetTokenId() == "" {
+		if err := reissueTokenId(claims); err != nil {
+			return "", err
+		}
+	}
+	registered := claims.GetRegisteredClaims()
+	if registered.ExpiresAt == nil {
+		registered.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Duration(global.CONFIG.JWT.ExpiresTime) * time.Second))
+	}
+
+	store := j.sessionStore()
+	sessions, err := store.List(ctx, claims.GetUserId())
+	if err != nil {
+		return "", err
+	}
+	var previous *Session
+	for _, session := range sessions {
+		if session.TokenId == previousTokenId {
+			previous = session
+			break
+		}
+	}
+	if previous == nil {
+		revoked, err := isTokenIdRevoked(previousTokenId)
+		if err != nil {
+			return "", err
+		}
+		if revoked {
+			return "", TokenKicked
+		}
+		token, _, err := j.createSessionToken(ctx, claims, SessionMeta{})
+		return token, err
+	}
+
+	renewed := *previous
+	renewed.TokenId = claims.GetTokenId()
+	renewed.ExpiresAt = registered.ExpiresAt.Time
+	if err := store.Save(ctx, &renewed); err != nil {
+		return "", err
+	}
+	if err := j.terminate(ctx, store, previous); err != nil {
+		return "", err
+	}
+	if err := j.prepareClaims(claims); err != nil {
+		return "", err
+	}
+	return j.signToken(claims)
+}
+
+// lockUserSessions 存储实现 SessionLocker 时获取用户的会话锁，超过 sessionLockTTL 仍未获取到时返回 SessionLockTimeout
+func lockUserSessions(ctx context.Context, store SessionStore, userId string) (func(), error) {
+	locker, ok := store.(SessionLocker)
+	if !ok {
+		return func() {}, nil
+	}
+	deadline := time.Now().Add(sessionLockTTL)
+	for {
+		unlock, locked, err := locker.LockUser(ctx, userId, sessionLockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, SessionLockTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sessionLockRetry):
+		}
+	}
+}
+
+// ListSessions 列出用户的有效会话
+func (j *JWT) ListSessions(ctx context.Context, userId string) ([]*Session, error) {
+	return j.sessionStore().List(ctx, userId)
+}
+
+// TerminateSession 强制下线用户的某个会话并吊销其 token
+func (j *JWT) TerminateSession(ctx context.Context, userId, tokenId string) error {
+	store := j.sessionStore()
+	sessions, err := store.List(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.TokenId == tokenId {
+			return j.terminate(ctx, store, session)
+		}
+	}
+	return SessionNotFound
+}
+
+// TerminateAllSessions 强制下线用户的全部会话
+func (j *JWT) TerminateAllSessions(ctx context.Context, userId string) error {
+	store := j.sessionStore()
+	sessions, err := store.List(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := j.terminate(ctx, store, session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terminate 删除会话并吊销 token，未开启多点登录拦截时也能让 token 失效
+func (j *JWT) terminate(ctx context.Context, store SessionStore, session *Session) error {
+	if err := store.Remove(ctx, session.UserId, session.TokenId); err != nil {
+		return err
+	}
+	return RevokeToken(session.TokenId, session.ExpiresAt)
+}
@@ -0,0 +1,127 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-20 14:12:48
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-20 14:12:48
+ * @FilePath: \go-core\pkg\jwt\session_test.go
+ * @Description: 多端会话在刷新时的迁移与并发数限制测试
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package jwt
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kamalyes/go-core/pkg/global"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSessionPlatform 测试使用的平台类型
+const testSessionPlatform int32 = 7
+
+// setupTestSessions 开启多点登录拦截并限制测试平台的会话数，测试结束后恢复
+func setupTestSessions(t *testing.T, max int) {
+	setupTestConfig(t)
+	setupTestRedis(t)
+	global.CONFIG.JWT.UseMultipoint = true
+	SetMaxSessions(testSessionPlatform, max)
+	t.Cleanup(func() {
+		sessionLimits.mu.Lock()
+		delete(sessionLimits.platformMax, testSessionPlatform)
+		sessionLimits.mu.Unlock()
+	})
+}
+
+// testSessionClaims 测试平台的 claims
+func testSessionClaims(userId string) CustomClaims {
+	claims := testClaims(userId)
+	claims.PlatformType = testSessionPlatform
+	return claims
+}
+
+// TestRefreshMigratesSession 测试刷新时迁移原会话，不占用新的会话名额，也不踢出其他设备
+func TestRefreshMigratesSession(t *testing.T) {
+	setupTestSessions(t, 2)
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("session-secret")}
+
+	// 设备 B 先登录，会话更早
+	deviceB, err := j.CreateTokenPair(ctx, testSessionClaims("1001"))
+	require.NoError(t, err)
+	deviceA, err := j.CreateTokenPair(ctx, testSessionClaims("1001"))
+	require.NoError(t, err)
+
+	// 设备 A 轮换刷新令牌，原会话迁移到新 tokenId，原访问令牌失效
+	rotated, err := j.RefreshTokenPair(ctx, deviceA.RefreshToken)
+	require.NoError(t, err)
+	_, err = j.ResolveToken(deviceB.AccessToken)
+	assert.NoError(t, err)
+	_, err = j.ResolveToken(deviceA.AccessToken)
+	assert.ErrorIs(t, err, TokenRevoked)
+
+	// 设备 A 再用 RefreshToken 刷新访问令牌
+	refreshed, err := j.RefreshToken(rotated.AccessToken)
+	require.NoError(t, err)
+	_, err = j.ResolveToken(rotated.AccessToken)
+	assert.ErrorIs(t, err, TokenRevoked)
+	claims, err := j.ResolveToken(refreshed)
+	require.NoError(t, err)
+	_, err = j.ResolveToken(deviceB.AccessToken)
+	assert.NoError(t, err)
+
+	sessions, err := j.ListSessions(ctx, "1001")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	tokenIds := []string{sessions[0].TokenId, sessions[1].TokenId}
+	assert.Contains(t, tokenIds, claims.TokenId)
+}
+
+// TestRefreshKickedSession 测试被踢出的设备不能通过刷新重新登记会话
+func TestRefreshKickedSession(t *testing.T) {
+	setupTestSessions(t, 1)
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("session-secret")}
+
+	deviceA, err := j.CreateTokenPair(ctx, testSessionClaims("1001"))
+	require.NoError(t, err)
+	deviceB, err := j.CreateTokenPair(ctx, testSessionClaims("1001"))
+	require.NoError(t, err)
+
+	_, err = j.RefreshTokenPair(ctx, deviceA.RefreshToken)
+	assert.ErrorIs(t, err, TokenKicked)
+	_, err = j.RefreshToken(deviceA.AccessToken)
+	assert.ErrorIs(t, err, TokenRevoked)
+	_, err = j.ResolveToken(deviceB.AccessToken)
+	assert.NoError(t, err)
+}
+
+// TestConcurrentLoginLimit 测试同一用户并发登录时会话数不超过上限
+func TestConcurrentLoginLimit(t *testing.T) {
+	setupTestSessions(t, 2)
+	ctx := context.Background()
+	j := &JWT{SigningKey: []byte("session-secret")}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := j.CreateSessionToken(ctx, testSessionClaims("1001"), SessionMeta{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	sessions, err := j.ListSessions(ctx, "1001")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+}
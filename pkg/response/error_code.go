@@ -0,0 +1,79 @@
+/*
+ * @Author: kamalyes 501893067@qq.com
+ * @Date: 2026-10-19 10:12:36
+ * @LastEditors: kamalyes 501893067@qq.com
+ * @LastEditTime: 2026-10-19 10:12:36
+ * @FilePath: \go-core\pkg\response\error_code.go
+ * @Description: 业务错误与响应码的映射
+ *
+ * Copyright (c) 2026 by kamalyes, All Rights Reserved.
+ */
+package response
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorCode 错误对应的响应码
+type ErrorCode struct {
+	HttpCode StatusCode
+	Code     SceneCode
+}
+
+// errorCodeMap 用于存储错误与响应码的映射关系，按注册顺序匹配
+var errorCodeMap = struct {
+	sync.RWMutex
+	targets []error
+	mapping map[error]ErrorCode
+}{
+	mapping: map[error]ErrorCode{},
+}
+
+// RegisterErrorCode 注册错误与响应码的映射，匹配时使用 errors.Is 判断
+func RegisterErrorCode(target error, httpCode StatusCode, code SceneCode) {
+	if target == nil {
+		return
+	}
+	errorCodeMap.Lock()
+	defer errorCodeMap.Unlock()
+	if _, exists := errorCodeMap.mapping[target]; !exists {
+		errorCodeMap.targets = append(errorCodeMap.targets, target)
+	}
+	errorCodeMap.mapping[target] = ErrorCode{HttpCode: httpCode, Code: code}
+}
+
+// GetErrorCode 根据错误获取已注册的响应码
+func GetErrorCode(err error) (ErrorCode, bool) {
+	if err == nil {
+		return ErrorCode{}, false
+	}
+	errorCodeMap.RLock()
+	defer errorCodeMap.RUnlock()
+	for _, target := range errorCodeMap.targets {
+		if errors.Is(err, target) {
+			return errorCodeMap.mapping[target], true
+		}
+	}
+	return ErrorCode{}, false
+}
+
+// NewErrorResponseOption 根据错误生成 ResponseOption，未注册的错误按服务器错误处理
+func NewErrorResponseOption(err error, data ...interface{}) *ResponseOption {
+	respOption := &ResponseOption{
+		Code:     Fail,
+		HttpCode: StatusInternalServerError,
+	}
+	if len(data) > 0 {
+		respOption.Data = data[0]
+	}
+	if err == nil {
+		return respOption
+	}
+	if errorCode, ok := GetErrorCode(err); ok {
+		respOption.Code = errorCode.Code
+		respOption.HttpCode = errorCode.HttpCode
+	}
+	respOption.Message = err.Error()
+	return respOption
+}
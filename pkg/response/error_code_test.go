@@ -0,0 +1,41 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterErrorCode(t *testing.T) {
+	errConflict := errors.New("conflict")
+	RegisterErrorCode(errConflict, StatusConflict, DataConflict)
+
+	code, ok := GetErrorCode(fmt.Errorf("wrapped: %w", errConflict))
+	assert.True(t, ok)
+	assert.Equal(t, StatusConflict, code.HttpCode)
+	assert.Equal(t, SceneCode(DataConflict), code.Code)
+
+	_, ok = GetErrorCode(errors.New("unknown"))
+	assert.False(t, ok)
+
+	_, ok = GetErrorCode(nil)
+	assert.False(t, ok)
+}
+
+func TestNewErrorResponseOption(t *testing.T) {
+	errLocked := errors.New("locked")
+	RegisterErrorCode(errLocked, StatusLocked, DisableAuth)
+
+	respOption := NewErrorResponseOption(errLocked, "data")
+	assert.Equal(t, StatusLocked, respOption.HttpCode)
+	assert.Equal(t, SceneCode(DisableAuth), respOption.Code)
+	assert.Equal(t, "locked", respOption.Message)
+	assert.Equal(t, "data", respOption.Data)
+
+	respOption = NewErrorResponseOption(errors.New("boom"))
+	assert.Equal(t, StatusInternalServerError, respOption.HttpCode)
+	assert.Equal(t, SceneCode(Fail), respOption.Code)
+	assert.Equal(t, "boom", respOption.Message)
+}
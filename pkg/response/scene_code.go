@@ -39,6 +39,7 @@ const (
 	Unauthorized  = 1010 // JWT认证失败
 	WithoutLogin  = 1011 // 用户未登录
 	DisableAuth   = 1012 // 当前用户已被禁用
+	DataConflict  = 1013 // 数据已被修改
 )
 
 // sceneCodeMsgMap 用于存储状态码和消息的映射关系
@@ -63,6 +64,7 @@ var sceneCodeMsgMap = struct {
 		Unauthorized:  "Unauthorized",
 		WithoutLogin:  "User Not Logged In",
 		DisableAuth:   "User Authentication Disabled",
+		DataConflict:  "Data Has Been Modified",
 	},
 }
 